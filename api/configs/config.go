@@ -18,6 +18,15 @@ func GetAPIURL() string {
 	return os.Getenv("API_URL")
 }
 
+// GetFrontendURL is where links in emails lead. Without FRONTEND_URL they
+// lead to the pages the API serves itself.
+func GetFrontendURL() string {
+	if url := os.Getenv("FRONTEND_URL"); url != "" {
+		return url
+	}
+	return GetAPIURL()
+}
+
 func GetDBSource() string {
 	return os.Getenv("DB_SOURCE")
 }
@@ -33,3 +42,146 @@ func GetRazorpayKeySecret() string {
 func GetRazorpayWebhookSecret() string {
 	return os.Getenv("RAZORPAY_WEBHOOK_SECRET")
 }
+
+func GetExportDir() string {
+	if dir := os.Getenv("EXPORT_DIR"); dir != "" {
+		return dir
+	}
+	return "exports"
+}
+
+// GetSafetyBlocklistFile is a file of blocked domains, one per line.
+func GetSafetyBlocklistFile() string {
+	if path := os.Getenv("SAFETY_BLOCKLIST_FILE"); path != "" {
+		return path
+	}
+	return "safety/blocklist.txt"
+}
+
+// GetSafetyRulesFile is a file of "<verdict> <regexp>" URL rules.
+func GetSafetyRulesFile() string {
+	if path := os.Getenv("SAFETY_RULES_FILE"); path != "" {
+		return path
+	}
+	return "safety/rules.txt"
+}
+
+// GetSafeBrowsingMode is "api" to query the Safe Browsing API, "mock" to
+// use the built in test entries, or empty to skip it.
+func GetSafeBrowsingMode() string {
+	return os.Getenv("SAFE_BROWSING_MODE")
+}
+
+func GetSafeBrowsingAPIKey() string {
+	return os.Getenv("SAFE_BROWSING_API_KEY")
+}
+
+func GetSafeBrowsingURL() string {
+	if url := os.Getenv("SAFE_BROWSING_URL"); url != "" {
+		return url
+	}
+	return "https://safebrowsing.googleapis.com/v4/threatMatches:find"
+}
+
+// GetMediaDir is where uploaded images are stored; it is served under /media.
+func GetMediaDir() string {
+	if dir := os.Getenv("MEDIA_DIR"); dir != "" {
+		return dir
+	}
+	return "media"
+}
+
+// GetAllowPrivateFetch lets outbound fetches of user URLs reach private and
+// loopback addresses. Only for local development.
+func GetAllowPrivateFetch() bool {
+	return os.Getenv("ALLOW_PRIVATE_FETCH") == "true"
+}
+
+// GetStorageDriver selects where generated files such as thumbnails are
+// kept: "local" (the default) or "s3" for any S3 compatible service.
+func GetStorageDriver() string {
+	if driver := os.Getenv("STORAGE_DRIVER"); driver != "" {
+		return driver
+	}
+	return "local"
+}
+
+// GetStorageDir is the root directory of the local storage driver.
+func GetStorageDir() string {
+	if dir := os.Getenv("STORAGE_DIR"); dir != "" {
+		return dir
+	}
+	return "storage"
+}
+
+// GetS3Endpoint is the base URL of the S3 service, such as
+// https://s3.us-east-1.amazonaws.com or http://localhost:9000 for MinIO.
+func GetS3Endpoint() string {
+	return os.Getenv("S3_ENDPOINT")
+}
+
+func GetS3Region() string {
+	if region := os.Getenv("S3_REGION"); region != "" {
+		return region
+	}
+	return "us-east-1"
+}
+
+func GetS3Bucket() string {
+	return os.Getenv("S3_BUCKET")
+}
+
+func GetS3AccessKey() string {
+	return os.Getenv("S3_ACCESS_KEY")
+}
+
+func GetS3SecretKey() string {
+	return os.Getenv("S3_SECRET_KEY")
+}
+
+// GetMailDriver selects how emails are sent: "log" (the default) prints
+// them, "smtp" sends them through SMTP_HOST.
+func GetMailDriver() string {
+	if driver := os.Getenv("MAIL_DRIVER"); driver != "" {
+		return driver
+	}
+	return "log"
+}
+
+func GetMailFrom() string {
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		return from
+	}
+	return "URL Shortener <no-reply@localhost>"
+}
+
+func GetSMTPHost() string {
+	return os.Getenv("SMTP_HOST")
+}
+
+// GetSMTPPort is 587 for STARTTLS, 465 for TLS from the start, or the port
+// of a local capture server such as Mailpit's 1025.
+func GetSMTPPort() string {
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		return port
+	}
+	return "587"
+}
+
+func GetSMTPUsername() string {
+	return os.Getenv("SMTP_USERNAME")
+}
+
+func GetSMTPPassword() string {
+	return os.Getenv("SMTP_PASSWORD")
+}
+
+// GetUnverifiedEmailPolicy is what accounts with an unverified email may
+// do: "allow" everything, "restrict" (the default) everything but creating
+// links, QR codes and webhooks, or "block" to refuse them at login.
+func GetUnverifiedEmailPolicy() string {
+	if policy := os.Getenv("UNVERIFIED_EMAIL_POLICY"); policy != "" {
+		return policy
+	}
+	return "restrict"
+}
@@ -25,7 +25,7 @@ WHERE razorpay_order_id = $1;
 
 -- name: GetUserAccountDetails :one
 SELECT 
-  id, username, email, ip_address, provider, provider_id, image, created_at, updated_at
+  id, username, email, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at
 FROM users
 WHERE id = $1
 LIMIT 1;
@@ -46,8 +46,8 @@ VALUES ($1, $2, $3, $4)
 RETURNING *;
 
 -- name: CreateOAuthUser :one
-INSERT INTO users (username, email,password_hash, ip_address, provider, provider_id, image)
-VALUES ($1, $2, $3, $4, $5, $6,$7)
+INSERT INTO users (username, email,password_hash, ip_address, provider, provider_id, image, email_verified_at)
+VALUES ($1, $2, $3, $4, $5, $6,$7, now())
 RETURNING *;
 
 -- name: GetUserByEmail :one
@@ -55,6 +55,12 @@ SELECT * FROM users
 WHERE email = $1
 LIMIT 1;
 
+-- name: GetUserByEmailFold :one
+SELECT * FROM users
+WHERE lower(email) = lower($1)
+ORDER BY id
+LIMIT 1;
+
 -- name: GetUserByUsername :one
 SELECT * FROM users
 WHERE username = $1
@@ -76,7 +82,22 @@ SELECT
   password_hash,
   created_at,
   expire_at,
-  user_id
+  user_id,
+  health_status,
+  health_checked_at,
+  backup_url,
+  safety_status,
+  safety_reason,
+  safety_checked_at,
+  always_preview,
+  preview_count,
+  og_title,
+  og_description,
+  og_image,
+  thumbnail_checked_at,
+  payload_type,
+  payload,
+  health_streak
 FROM urls
 WHERE user_id = $1
 ORDER BY created_at DESC;
@@ -87,7 +108,19 @@ SET
   original_url = $1,
   title = $2,
   expire_at = $3,
-  password_hash = $4
+  password_hash = $4,
+  backup_url = $6,
+  safety_status = $7,
+  safety_reason = $8,
+  safety_checked_at = now(),
+  always_preview = $9,
+  og_title = $10,
+  og_description = $11,
+  og_image = $12,
+  health_status = CASE WHEN original_url = $1 THEN health_status END,
+  health_checked_at = CASE WHEN original_url = $1 THEN health_checked_at END,
+  health_streak = CASE WHEN original_url = $1 THEN health_streak ELSE 0 END,
+  thumbnail_checked_at = CASE WHEN original_url = $1 THEN thumbnail_checked_at END
 WHERE short_code = $5
 RETURNING *;
 
@@ -96,6 +129,75 @@ SELECT * FROM users
 WHERE provider = $1 AND provider_id = $2
 LIMIT 1;
 
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1
+LIMIT 1;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now()),
+    updated_at = now()
+WHERE id = $1 AND email = $2
+RETURNING *;
+
+-- name: GetUserSessionVersion :one
+SELECT session_version FROM users
+WHERE id = $1;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET password_hash = $2,
+    session_version = session_version + 1,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: CountPasswordResetRequests :one
+SELECT
+  COUNT(*) FILTER (WHERE email = sqlc.arg(email)) AS by_email,
+  COUNT(*) FILTER (WHERE ip_address = sqlc.arg(ip_address)) AS by_ip
+FROM password_reset_requests
+WHERE requested_at > sqlc.arg(since)
+  AND (email = sqlc.arg(email) OR ip_address = sqlc.arg(ip_address));
+
+-- name: CreatePasswordResetRequest :exec
+INSERT INTO password_reset_requests (email, ip_address)
+VALUES ($1, $2);
+
+-- name: DeletePasswordResetRequestsBefore :exec
+DELETE FROM password_reset_requests
+WHERE requested_at < $1;
+
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: ConsumePasswordResetToken :one
+DELETE FROM password_reset_tokens
+WHERE token_hash = $1 AND expires_at > now()
+RETURNING user_id;
+
+-- name: DeletePasswordResetTokensByUser :exec
+DELETE FROM password_reset_tokens
+WHERE user_id = $1;
+
+-- name: DeleteExpiredPasswordResetTokens :exec
+DELETE FROM password_reset_tokens
+WHERE expires_at < now();
+
+-- name: IsUserEmailVerified :one
+SELECT (email_verified_at IS NOT NULL)::bool AS verified
+FROM users
+WHERE id = $1;
+
+-- name: ClaimVerificationEmail :execrows
+UPDATE users
+SET verification_sent_at = now()
+WHERE id = $1
+  AND email_verified_at IS NULL
+  AND (verification_sent_at IS NULL OR verification_sent_at < now() - INTERVAL '1 minute');
+
 -- name: CreateShortURL :one
 INSERT INTO urls (
   original_url,
@@ -105,13 +207,42 @@ INSERT INTO urls (
   expire_at,
   user_id,
   thumbnail,
+  backup_url,
+  safety_status,
+  safety_reason,
+  safety_checked_at,
+  always_preview,
+  og_title,
+  og_description,
+  og_image,
+  thumbnail_checked_at,
   click_count
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, 0
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, now(), $11, $12, $13, $14,
+  CASE WHEN $7 IS NULL THEN NULL ELSE now() END, 0
 )
 RETURNING *;
 
 
+-- name: CreatePayloadLink :one
+INSERT INTO urls (
+  original_url,
+  short_code,
+  user_id,
+  payload_type,
+  payload,
+  click_count
+) VALUES (
+  $1, $2, $3, $4, $5, 0
+)
+RETURNING *;
+
+-- name: UpdateLinkPayload :one
+UPDATE urls
+SET original_url = $2, payload_type = $3, payload = $4
+WHERE id = $1
+RETURNING *;
+
 -- name: LogURLVisit :exec
 INSERT INTO url_visits (
   url_id,
@@ -122,9 +253,13 @@ INSERT INTO url_visits (
   referrer,
   country,
   region,
-  city
+  city,
+  referrer_domain,
+  referrer_source,
+  destination,
+  source
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
 );
 
 -- name: GetURLVisits :many
@@ -162,39 +297,16 @@ GROUP BY device_type;
 
 -- name: GetDeviceTypeStatsByUser :many
 SELECT
-  COALESCE(uv.device_type, 'unknown') AS device_type,
-  COUNT(*) AS count
-FROM url_visits uv
-INNER JOIN urls u ON uv.url_id = u.id
-WHERE u.user_id = $1
-GROUP BY uv.device_type;
-
+  COALESCE(NULLIF(d.device_type, ''), 'unknown')::text AS device_type,
+  SUM(d.clicks)::BIGINT AS count
+FROM url_visit_rollup_dims d
+INNER JOIN urls u ON d.url_id = u.id
+WHERE u.user_id = sqlc.arg(user_id)
+  AND d.bucket >= sqlc.arg(from_time)::timestamptz
+  AND d.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY 1;
 
 
--- name: GetDailyClicksAndLinksByUser :many
-SELECT
-  d::date AS date,
-  COALESCE(clicks.count, 0) AS clicks,
-  COALESCE(links.count, 0) AS links
-FROM generate_series(
-         CURRENT_DATE - INTERVAL '6 days',
-         CURRENT_DATE,
-         INTERVAL '1 day'
-     ) AS d
-LEFT JOIN (
-    SELECT uv.clicked_at::date AS date, COUNT(*) AS count
-    FROM url_visits uv
-    INNER JOIN urls u ON uv.url_id = u.id
-    WHERE u.user_id = $1
-    GROUP BY uv.clicked_at::date
-) AS clicks ON clicks.date = d
-LEFT JOIN (
-    SELECT created_at::date AS date, COUNT(*) AS count
-    FROM urls
-    WHERE user_id = $1
-    GROUP BY created_at::date
-) AS links ON links.date = d
-ORDER BY d;
 
 -- name: GetTitleAndUrlByUser :many
 SELECT 
@@ -204,25 +316,17 @@ SELECT
 FROM urls
 WHERE user_id = $1;
 
--- name: GetMonthlyClicksByUser :many
-SELECT DATE_TRUNC('month', url_visits.clicked_at)::date AS month,
-      COUNT(*) AS click_count
-      FROM url_visits
-LEFT JOIN urls ON url_visits.url_id = urls.id
-WHERE urls.user_id = $1
-  AND url_visits.clicked_at >= NOW() - INTERVAL '1 year'
-GROUP BY month;
-
 -- name: GetCountryVisitCountsByUser :many
 SELECT
-  COALESCE(uv.country, '') AS country,
-  COUNT(*) AS clicks
-FROM url_visits uv
-JOIN urls u ON uv.url_id = u.id
-WHERE u.user_id = $1
-  AND uv.clicked_at >= NOW() - (sqlc.arg(days)::int * INTERVAL '1 day')
-  AND uv.country IS NOT NULL
-GROUP BY country
+  d.country,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON d.url_id = u.id
+WHERE u.user_id = sqlc.arg(user_id)
+  AND d.bucket >= sqlc.arg(from_time)::timestamptz
+  AND d.bucket < sqlc.arg(to_time)::timestamptz
+  AND d.country <> ''
+GROUP BY d.country
 ORDER BY clicks DESC;
 
 -- name: GetDashboardSummaryByUser :one
@@ -234,61 +338,661 @@ SELECT
   ) AS active_links,
   COUNT(*) FILTER (
     WHERE expire_at IS NOT NULL AND expire_at <= now()
-  ) AS expired_links
+  ) AS expired_links,
+  COUNT(*) FILTER (
+    WHERE created_at >= sqlc.arg(from_time)::timestamptz AND created_at < sqlc.arg(to_time)::timestamptz
+  ) AS period_links,
+  (
+    SELECT COALESCE(SUM(h.clicks), 0)
+    FROM url_visit_rollups h
+    JOIN urls pu ON pu.id = h.url_id
+    WHERE pu.user_id = sqlc.arg(user_id)
+      AND h.bucket >= sqlc.arg(from_time)::timestamptz
+      AND h.bucket < sqlc.arg(to_time)::timestamptz
+  )::BIGINT AS period_clicks
 FROM urls
-WHERE user_id = $1;
+WHERE user_id = sqlc.arg(user_id);
 
 
 
--- name: GetDailyClicksScoped :many
+-- name: GetDeviceStatsScoped :many
 SELECT
-  DATE(uv.clicked_at) AS date,
-  COUNT(*) AS clicks
-FROM url_visits uv
-JOIN urls u ON u.id = uv.url_id
-WHERE u.short_code = $1 AND u.user_id = $2
-GROUP BY date
-ORDER BY date DESC
-LIMIT 7;
+  COALESCE(NULLIF(d.device_type, ''), 'unknown')::text AS device_type,
+  SUM(d.clicks)::BIGINT AS count
+FROM url_visit_rollup_dims d
+JOIN urls u ON u.id = d.url_id
+WHERE u.short_code = sqlc.arg(short_code) AND u.user_id = sqlc.arg(user_id)
+  AND d.bucket >= sqlc.arg(from_time)::timestamptz
+  AND d.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY 1;
 
--- name: GetMonthlyClicksScoped :many
+-- name: GetCountryStatsScoped :many
 SELECT
-  DATE_TRUNC('month', uv.clicked_at)::date AS month,
-  COUNT(*) AS click_count
-FROM url_visits uv
-JOIN urls u ON u.id = uv.url_id
-WHERE u.short_code = $1 AND u.user_id = $2
-  AND uv.clicked_at >= NOW() - INTERVAL '1 year'
-GROUP BY month
-ORDER BY month;
+  COALESCE(NULLIF(d.country, ''), 'unknown')::text AS country,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON u.id = d.url_id
+WHERE u.short_code = sqlc.arg(short_code) AND u.user_id = sqlc.arg(user_id)
+  AND d.bucket >= sqlc.arg(from_time)::timestamptz
+  AND d.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY 1
+ORDER BY clicks DESC
+LIMIT 10;
 
--- name: GetDeviceStatsScoped :many
+
+-- name: GetTopReferrersByUser :many
 SELECT
-  COALESCE(uv.device_type, 'unknown') AS device_type,
-  COUNT(*) AS count
+  d.referrer_domain AS domain,
+  d.referrer_source AS source,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON d.url_id = u.id
+WHERE u.user_id = sqlc.arg(user_id)
+  AND d.bucket >= sqlc.arg(from_time)::timestamptz
+  AND d.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY d.referrer_domain, d.referrer_source
+ORDER BY clicks DESC
+LIMIT sqlc.arg(row_limit)::int;
+
+-- name: GetReferrerSourcesByUser :many
+SELECT
+  d.referrer_source AS source,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON d.url_id = u.id
+WHERE u.user_id = sqlc.arg(user_id)
+  AND d.bucket >= sqlc.arg(from_time)::timestamptz
+  AND d.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY d.referrer_source
+ORDER BY clicks DESC;
+
+-- name: GetTopReferrersScoped :many
+SELECT
+  d.referrer_domain AS domain,
+  d.referrer_source AS source,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON u.id = d.url_id
+WHERE u.short_code = sqlc.arg(short_code) AND u.user_id = sqlc.arg(user_id)
+  AND d.bucket >= sqlc.arg(from_time)::timestamptz
+  AND d.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY d.referrer_domain, d.referrer_source
+ORDER BY clicks DESC
+LIMIT sqlc.arg(row_limit)::int;
+
+-- name: GetReferrerSourcesScoped :many
+SELECT
+  d.referrer_source AS source,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON u.id = d.url_id
+WHERE u.short_code = sqlc.arg(short_code) AND u.user_id = sqlc.arg(user_id)
+  AND d.bucket >= sqlc.arg(from_time)::timestamptz
+  AND d.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY d.referrer_source
+ORDER BY clicks DESC;
+
+-- name: GetVisitSourcesByUser :many
+SELECT
+  s.source,
+  SUM(s.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_sources s
+JOIN urls u ON s.url_id = u.id
+WHERE u.user_id = sqlc.arg(user_id)
+  AND s.bucket >= sqlc.arg(from_time)::timestamptz
+  AND s.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY s.source;
+
+-- name: GetVisitSourcesScoped :many
+SELECT
+  s.source,
+  SUM(s.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_sources s
+JOIN urls u ON u.id = s.url_id
+WHERE u.short_code = sqlc.arg(short_code) AND u.user_id = sqlc.arg(user_id)
+  AND s.bucket >= sqlc.arg(from_time)::timestamptz
+  AND s.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY s.source;
+
+-- name: GetClickSeriesByUser :many
+SELECT
+  date_trunc(sqlc.arg(granularity)::text, h.bucket AT TIME ZONE sqlc.arg(tz)::text)::timestamp AS bucket,
+  SUM(h.clicks)::BIGINT AS clicks
+FROM url_visit_rollups h
+INNER JOIN urls u ON h.url_id = u.id
+WHERE u.user_id = sqlc.arg(user_id)
+  AND h.bucket >= sqlc.arg(from_time)::timestamptz
+  AND h.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY bucket
+ORDER BY bucket;
+
+-- name: GetLinkSeriesByUser :many
+SELECT
+  date_trunc(sqlc.arg(granularity)::text, created_at AT TIME ZONE sqlc.arg(tz)::text)::timestamp AS bucket,
+  COUNT(*) AS links
+FROM urls
+WHERE user_id = sqlc.arg(user_id)
+  AND created_at >= sqlc.arg(from_time)::timestamptz
+  AND created_at < sqlc.arg(to_time)::timestamptz
+GROUP BY bucket
+ORDER BY bucket;
+
+-- name: GetClickSeriesScoped :many
+SELECT
+  date_trunc(sqlc.arg(granularity)::text, h.bucket AT TIME ZONE sqlc.arg(tz)::text)::timestamp AS bucket,
+  SUM(h.clicks)::BIGINT AS clicks
+FROM url_visit_rollups h
+JOIN urls u ON u.id = h.url_id
+WHERE u.short_code = sqlc.arg(short_code) AND u.user_id = sqlc.arg(user_id)
+  AND h.bucket >= sqlc.arg(from_time)::timestamptz
+  AND h.bucket < sqlc.arg(to_time)::timestamptz
+GROUP BY bucket
+ORDER BY bucket;
+
+-- name: GetClickHeatmapByUser :many
+SELECT
+  EXTRACT(ISODOW FROM v.clicked_at AT TIME ZONE sqlc.arg(tz)::text)::int AS weekday,
+  EXTRACT(HOUR FROM v.clicked_at AT TIME ZONE sqlc.arg(tz)::text)::int AS hour,
+  COUNT(*)::BIGINT AS clicks,
+  COUNT(DISTINCT v.ip_address)::BIGINT AS uniques
+FROM url_visits v
+JOIN urls u ON v.url_id = u.id
+WHERE u.user_id = sqlc.arg(user_id)
+  AND v.clicked_at >= sqlc.arg(from_time)::timestamptz
+  AND v.clicked_at < sqlc.arg(to_time)::timestamptz
+GROUP BY 1, 2;
+
+-- name: GetClickHeatmapScoped :many
+SELECT
+  EXTRACT(ISODOW FROM v.clicked_at AT TIME ZONE sqlc.arg(tz)::text)::int AS weekday,
+  EXTRACT(HOUR FROM v.clicked_at AT TIME ZONE sqlc.arg(tz)::text)::int AS hour,
+  COUNT(*)::BIGINT AS clicks,
+  COUNT(DISTINCT v.ip_address)::BIGINT AS uniques
+FROM url_visits v
+JOIN urls u ON u.id = v.url_id
+WHERE u.short_code = sqlc.arg(short_code) AND u.user_id = sqlc.arg(user_id)
+  AND v.clicked_at >= sqlc.arg(from_time)::timestamptz
+  AND v.clicked_at < sqlc.arg(to_time)::timestamptz
+GROUP BY 1, 2;
+
+-- name: GetOwnedShortCodes :many
+SELECT short_code FROM urls
+WHERE user_id = sqlc.arg(user_id) AND short_code = ANY(sqlc.arg(short_codes)::text[]);
+
+-- name: ListLinksByShortCodes :many
+SELECT id, short_code, title FROM urls
+WHERE user_id = sqlc.arg(user_id) AND short_code = ANY(sqlc.arg(short_codes)::text[]);
+
+-- name: CountVisitsForExport :one
+SELECT COUNT(*) AS total
 FROM url_visits uv
 JOIN urls u ON u.id = uv.url_id
-WHERE u.short_code = $1 AND u.user_id = $2
-GROUP BY uv.device_type;
+WHERE u.user_id = sqlc.arg(user_id)
+  AND (cardinality(sqlc.arg(short_codes)::text[]) = 0 OR u.short_code = ANY(sqlc.arg(short_codes)::text[]))
+  AND uv.clicked_at >= sqlc.arg(from_time)::timestamptz
+  AND uv.clicked_at < sqlc.arg(to_time)::timestamptz;
 
--- name: GetCountryStatsScoped :many
+-- name: ListVisitsForExport :many
 SELECT
-  COALESCE(uv.country, 'unknown') AS country,
-  COUNT(*) AS clicks
+  uv.id,
+  u.short_code,
+  uv.clicked_at,
+  uv.ip_address,
+  uv.user_agent,
+  uv.device_type,
+  uv.referrer,
+  uv.referrer_domain,
+  uv.referrer_source,
+  uv.country,
+  uv.region,
+  uv.city,
+  uv.destination,
+  uv.source
 FROM url_visits uv
 JOIN urls u ON u.id = uv.url_id
-WHERE u.short_code = $1 AND u.user_id = $2
-  AND uv.clicked_at >= NOW() - (sqlc.arg(days)::int * INTERVAL '1 day')
-GROUP BY country
-ORDER BY clicks DESC
-LIMIT 10;
+WHERE u.user_id = sqlc.arg(user_id)
+  AND (cardinality(sqlc.arg(short_codes)::text[]) = 0 OR u.short_code = ANY(sqlc.arg(short_codes)::text[]))
+  AND uv.clicked_at >= sqlc.arg(from_time)::timestamptz
+  AND uv.clicked_at < sqlc.arg(to_time)::timestamptz
+  AND uv.id > sqlc.arg(after_id)::int
+ORDER BY uv.id
+LIMIT sqlc.arg(row_limit)::int;
+
+-- name: CreateExportJob :one
+INSERT INTO export_jobs (
+  user_id,
+  format,
+  columns,
+  short_codes,
+  from_time,
+  to_time,
+  timezone
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetExportJob :one
+SELECT * FROM export_jobs
+WHERE id = $1 AND user_id = $2;
 
+-- name: ListExportJobsByUser :many
+SELECT * FROM export_jobs
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 50;
+
+-- name: ClaimExportJob :one
+UPDATE export_jobs
+SET status = 'running', started_at = now()
+WHERE id = (
+  SELECT id FROM export_jobs
+  WHERE status = 'pending'
+  ORDER BY id
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
 
--- name: GetDeviceStatsByShortcode :many
+-- name: RequeueRunningExportJobs :exec
+UPDATE export_jobs
+SET status = 'pending', started_at = NULL
+WHERE status = 'running';
+
+-- name: CompleteExportJob :exec
+UPDATE export_jobs
+SET status = 'completed', file_path = $2, row_count = $3, completed_at = now()
+WHERE id = $1;
+
+-- name: FailExportJob :exec
+UPDATE export_jobs
+SET status = 'failed', error = $2, completed_at = now()
+WHERE id = $1;
+
+-- name: DeleteExpiredExportJobs :many
+DELETE FROM export_jobs
+WHERE status IN ('completed', 'failed')
+  AND completed_at < sqlc.arg(completed_before)::timestamptz
+RETURNING file_path;
+
+-- name: GetRollupWatermark :one
+SELECT watermark FROM rollup_state
+WHERE name = $1;
+
+-- name: SetRollupWatermark :exec
+INSERT INTO rollup_state (name, watermark)
+VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET watermark = EXCLUDED.watermark;
+
+-- name: GetFirstVisitTime :one
+SELECT clicked_at FROM url_visits
+ORDER BY clicked_at
+LIMIT 1;
+
+-- name: RollupVisits :exec
+INSERT INTO url_visit_rollups (url_id, bucket, clicks)
 SELECT
-  COALESCE(uv.device_type, 'unknown') AS device_type,
-  COUNT(*) AS count
-FROM url_visits uv
-INNER JOIN urls u ON uv.url_id = u.id
-WHERE u.short_code = $1
-GROUP BY device_type;
+  url_id,
+  date_bin('15 minutes', clicked_at, TIMESTAMPTZ '2000-01-01 00:00:00+00') AS bucket,
+  COUNT(*)
+FROM url_visits
+WHERE clicked_at >= sqlc.arg(from_time)::timestamptz
+  AND clicked_at < sqlc.arg(to_time)::timestamptz
+GROUP BY 1, 2
+ON CONFLICT (url_id, bucket) DO UPDATE
+SET clicks = EXCLUDED.clicks;
+
+-- name: RollupVisitDims :exec
+INSERT INTO url_visit_rollup_dims (url_id, bucket, country, device_type, referrer_domain, referrer_source, clicks)
+SELECT
+  url_id,
+  date_bin('15 minutes', clicked_at, TIMESTAMPTZ '2000-01-01 00:00:00+00') AS bucket,
+  COALESCE(country, ''),
+  COALESCE(device_type, ''),
+  COALESCE(referrer_domain, ''),
+  COALESCE(referrer_source, CASE WHEN COALESCE(referrer, '') = '' THEN 'direct' ELSE 'other' END),
+  COUNT(*)
+FROM url_visits
+WHERE clicked_at >= sqlc.arg(from_time)::timestamptz
+  AND clicked_at < sqlc.arg(to_time)::timestamptz
+GROUP BY 1, 2, 3, 4, 5, 6
+ON CONFLICT (url_id, bucket, country, device_type, referrer_domain, referrer_source) DO UPDATE
+SET clicks = EXCLUDED.clicks;
+
+-- name: RollupVisitSources :exec
+INSERT INTO url_visit_rollup_sources (url_id, bucket, source, clicks)
+SELECT
+  url_id,
+  date_bin('15 minutes', clicked_at, TIMESTAMPTZ '2000-01-01 00:00:00+00') AS bucket,
+  source,
+  COUNT(*)
+FROM url_visits
+WHERE clicked_at >= sqlc.arg(from_time)::timestamptz
+  AND clicked_at < sqlc.arg(to_time)::timestamptz
+GROUP BY 1, 2, 3
+ON CONFLICT (url_id, bucket, source) DO UPDATE
+SET clicks = EXCLUDED.clicks;
+
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (
+  user_id,
+  url,
+  secret,
+  events
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: ListWebhookEndpointsByUser :many
+SELECT * FROM webhook_endpoints
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhookEndpointByID :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: UpdateWebhookEndpoint :one
+UPDATE webhook_endpoints
+SET url = $3, events = $4, active = $5
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListWebhookEndpointsForEvent :many
+SELECT * FROM webhook_endpoints
+WHERE user_id = sqlc.arg(user_id) AND active AND sqlc.arg(event)::text = ANY(events);
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+  endpoint_id,
+  event,
+  payload
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET status = 'sending'
+WHERE id IN (
+  SELECT id FROM webhook_deliveries
+  WHERE status = 'pending' AND next_attempt_at <= now()
+  ORDER BY next_attempt_at
+  LIMIT $1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RequeueSendingWebhookDeliveries :exec
+UPDATE webhook_deliveries
+SET status = 'pending'
+WHERE status = 'sending';
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET
+  status = $2,
+  attempts = attempts + 1,
+  next_attempt_at = $3,
+  response_code = $4,
+  response_body = $5,
+  error = $6,
+  delivered_at = CASE WHEN $2 = 'delivered' THEN now() ELSE delivered_at END
+WHERE id = $1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY id DESC
+LIMIT 100;
+
+-- name: ListLinksDueForHealthCheck :many
+SELECT * FROM urls
+WHERE (expire_at IS NULL OR expire_at > now())
+  AND payload_type IS NULL
+  AND (
+    health_checked_at IS NULL
+    OR health_checked_at < sqlc.arg(checked_before)::timestamptz
+    OR ((health_status = 'unhealthy' OR health_streak > 0) AND health_checked_at < sqlc.arg(unhealthy_checked_before)::timestamptz)
+  )
+ORDER BY health_checked_at NULLS FIRST, id
+LIMIT sqlc.arg(max_links);
+
+-- name: CreateLinkHealthCheck :exec
+INSERT INTO link_health_checks (
+  url_id,
+  status,
+  status_code,
+  method,
+  latency_ms,
+  redirect_chain,
+  final_url,
+  error
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: SetURLHealth :exec
+UPDATE urls
+SET health_status = $2, health_streak = $3, health_checked_at = now()
+WHERE id = $1;
+
+-- name: DeleteLinkHealthChecksBefore :exec
+DELETE FROM link_health_checks
+WHERE checked_at < $1;
+
+-- name: ListLinkHealthChecks :many
+SELECT
+  link_health_checks.id,
+  link_health_checks.url_id,
+  link_health_checks.status,
+  link_health_checks.status_code,
+  link_health_checks.method,
+  link_health_checks.latency_ms,
+  link_health_checks.redirect_chain,
+  link_health_checks.final_url,
+  link_health_checks.error,
+  link_health_checks.checked_at
+FROM link_health_checks
+JOIN urls ON urls.id = link_health_checks.url_id
+WHERE urls.short_code = $1 AND urls.user_id = $2
+ORDER BY link_health_checks.checked_at DESC
+LIMIT 100;
+
+-- name: StartLinkFailover :exec
+INSERT INTO link_failovers (url_id)
+VALUES ($1)
+ON CONFLICT (url_id) WHERE ended_at IS NULL DO NOTHING;
+
+-- name: EndLinkFailover :exec
+UPDATE link_failovers
+SET ended_at = now()
+WHERE url_id = $1 AND ended_at IS NULL;
+
+-- name: ListLinkFailovers :many
+SELECT
+  link_failovers.id,
+  link_failovers.url_id,
+  link_failovers.started_at,
+  link_failovers.ended_at
+FROM link_failovers
+JOIN urls ON urls.id = link_failovers.url_id
+WHERE urls.short_code = $1 AND urls.user_id = $2
+ORDER BY link_failovers.started_at DESC
+LIMIT 100;
+
+-- name: ListLinksDueForSafetyScan :many
+SELECT * FROM urls
+WHERE (expire_at IS NULL OR expire_at > now())
+  AND payload_type IS NULL
+  AND (safety_checked_at IS NULL OR safety_checked_at < sqlc.arg(checked_before)::timestamptz)
+ORDER BY safety_checked_at NULLS FIRST, id
+LIMIT sqlc.arg(max_links);
+
+-- name: SetURLSafety :exec
+UPDATE urls
+SET safety_status = $2, safety_reason = $3, safety_checked_at = now()
+WHERE id = $1;
+
+-- name: LogPreviewView :exec
+WITH counted AS (
+  UPDATE urls SET preview_count = preview_count + 1 WHERE id = $1
+)
+INSERT INTO url_preview_views (
+  url_id,
+  ip_address,
+  user_agent,
+  referrer
+) VALUES (
+  $1, $2, $3, $4
+);
+
+-- name: ListLinksDueForThumbnail :many
+SELECT * FROM urls
+WHERE (expire_at IS NULL OR expire_at > now())
+  AND payload_type IS NULL
+  AND (thumbnail_checked_at IS NULL OR thumbnail_checked_at < sqlc.arg(checked_before)::timestamptz)
+ORDER BY thumbnail_checked_at NULLS FIRST, id
+LIMIT sqlc.arg(max_links);
+
+-- name: SetURLThumbnail :exec
+UPDATE urls
+SET thumbnail = $2, thumbnail_checked_at = now()
+WHERE id = $1;
+
+-- name: SetNewURLThumbnail :execrows
+UPDATE urls
+SET thumbnail = $2, thumbnail_checked_at = now()
+WHERE id = $1 AND thumbnail IS NULL;
+
+-- name: SetMissingURLTitle :exec
+UPDATE urls
+SET title = $2
+WHERE id = $1 AND title IS NULL;
+
+-- name: CreateQRCode :one
+INSERT INTO qr_codes (
+  url_id,
+  qr_code,
+  fg_color,
+  bg_color,
+  logo_url,
+  size,
+  format,
+  error_correction,
+  expire_at,
+  logo_id,
+  style,
+  quiet_zone
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING *;
+
+-- name: GetQRCode :one
+SELECT
+  qr_codes.id,
+  qr_codes.url_id,
+  urls.short_code,
+  qr_codes.qr_code,
+  qr_codes.fg_color,
+  qr_codes.bg_color,
+  qr_codes.logo_url,
+  qr_codes.size,
+  qr_codes.format,
+  qr_codes.error_correction,
+  qr_codes.expire_at,
+  qr_codes.created_at,
+  qr_codes.updated_at,
+  qr_codes.logo_id,
+  qr_codes.style,
+  qr_codes.quiet_zone
+FROM qr_codes
+JOIN urls ON urls.id = qr_codes.url_id
+WHERE qr_codes.id = $1 AND urls.user_id = $2;
+
+-- name: ListQRCodesByUser :many
+SELECT
+  qr_codes.id,
+  qr_codes.url_id,
+  urls.short_code,
+  qr_codes.qr_code,
+  qr_codes.fg_color,
+  qr_codes.bg_color,
+  qr_codes.logo_url,
+  qr_codes.size,
+  qr_codes.format,
+  qr_codes.error_correction,
+  qr_codes.expire_at,
+  qr_codes.created_at,
+  qr_codes.updated_at,
+  qr_codes.logo_id,
+  qr_codes.style,
+  qr_codes.quiet_zone
+FROM qr_codes
+JOIN urls ON urls.id = qr_codes.url_id
+WHERE urls.user_id = $1
+ORDER BY qr_codes.created_at DESC, qr_codes.id DESC;
+
+-- name: UpdateQRCode :one
+UPDATE qr_codes
+SET
+  qr_code = $2,
+  fg_color = $3,
+  bg_color = $4,
+  logo_url = $5,
+  size = $6,
+  format = $7,
+  error_correction = $8,
+  expire_at = $9,
+  logo_id = $10,
+  style = $11,
+  quiet_zone = $12,
+  updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteQRCode :exec
+DELETE FROM qr_codes
+WHERE id = $1;
+
+-- name: CreateQRLogo :one
+INSERT INTO qr_logos (
+  user_id,
+  name,
+  storage_key,
+  content_type,
+  width,
+  height,
+  size_bytes
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetQRLogo :one
+SELECT * FROM qr_logos
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListQRLogosByUser :many
+SELECT * FROM qr_logos
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC;
+
+-- name: CountQRLogosByUser :one
+SELECT COUNT(*) FROM qr_logos
+WHERE user_id = $1;
+
+-- name: DeleteQRLogo :exec
+DELETE FROM qr_logos
+WHERE id = $1 AND user_id = $2;
@@ -11,6 +11,38 @@ CREATE TABLE IF NOT EXISTS users(
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
     CONSTRAINT unique_provider_user UNIQUE (provider, provider_id)
 );
+-- Accounts that existed before email verification count as verified: the
+-- default fills them in when the column is added, then new signups get NULL.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE DEFAULT now();
+ALTER TABLE users ALTER COLUMN email_verified_at DROP DEFAULT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMP WITH TIME ZONE;
+-- Sessions carry the version they were issued under; bumping it, as a
+-- password reset does, signs out every session.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS session_version INTEGER NOT NULL DEFAULT 0;
+-- Emails are stored as typed; forgot password looks them up ignoring case.
+CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email));
+
+-- Only the SHA-256 of a reset token is kept; the token itself is only in
+-- the email. A token is deleted when used.
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+
+-- Every forgot password request, whether or not the email is registered,
+-- to rate limit them by email and by IP.
+CREATE TABLE IF NOT EXISTS password_reset_requests (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(254) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_requests_email ON password_reset_requests (email, requested_at);
+CREATE INDEX IF NOT EXISTS idx_password_reset_requests_ip ON password_reset_requests (ip_address, requested_at);
 
 CREATE TABLE IF NOT EXISTS urls (
     id SERIAL PRIMARY KEY,
@@ -24,6 +56,25 @@ CREATE TABLE IF NOT EXISTS urls (
     expire_at TIMESTAMP WITH TIME ZONE,
     user_id INTEGER REFERENCES users(id) ON DELETE SET NULL
 );
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS health_status VARCHAR(20);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS health_checked_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS backup_url TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS safety_status VARCHAR(20);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS safety_reason TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS safety_checked_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS always_preview BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS preview_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS og_title TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS og_description TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS og_image TEXT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS thumbnail_checked_at TIMESTAMP WITH TIME ZONE;
+-- Links made for structured QR payloads (contacts, email, SMS...) carry the
+-- payload type and fields; original_url holds the vCard or URI served.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS payload_type VARCHAR(10);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS payload JSONB NOT NULL DEFAULT '{}';
+-- Consecutive health checks disagreeing with health_status. The status only
+-- flips once enough of them agree, so one blip does not fail a link over.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS health_streak INTEGER NOT NULL DEFAULT 0;
 
 
 CREATE TABLE IF NOT EXISTS url_visits (
@@ -39,6 +90,97 @@ CREATE TABLE IF NOT EXISTS url_visits (
     city VARCHAR(50),
     clicked_at TIMESTAMP WITH TIME ZONE DEFAULT now()
 );
+ALTER TABLE url_visits ADD COLUMN IF NOT EXISTS referrer_domain VARCHAR(255);
+ALTER TABLE url_visits ADD COLUMN IF NOT EXISTS referrer_source VARCHAR(20);
+ALTER TABLE url_visits ADD COLUMN IF NOT EXISTS destination VARCHAR(10);
+-- How the visitor reached the link: 'link' for the /s/ URL, 'qr' for the
+-- /q/ URL encoded in QR codes, 'api' for lookups through /api/resolve.
+ALTER TABLE url_visits ADD COLUMN IF NOT EXISTS source VARCHAR(10) NOT NULL DEFAULT 'link';
+CREATE INDEX IF NOT EXISTS idx_url_visits_url_id_clicked_at ON url_visits (url_id, clicked_at);
+CREATE INDEX IF NOT EXISTS idx_url_visits_clicked_at ON url_visits (clicked_at);
+CREATE INDEX IF NOT EXISTS idx_urls_user_id ON urls (user_id);
+
+-- Views of a link's preview page, kept apart from url_visits so previews
+-- never count as clicks.
+CREATE TABLE IF NOT EXISTS url_preview_views (
+    id SERIAL PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    referrer TEXT,
+    viewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_url_preview_views_url_id ON url_preview_views (url_id, viewed_at);
+
+-- Rollups of url_visits per link and 15 minute bucket, rebuilt from
+-- rollup_state's watermark by the rollup worker. Dashboards read these
+-- instead of scanning url_visits. Every time zone in use is a whole number
+-- of quarter hours off UTC, so buckets regroup exactly into local hours and
+-- days, including in zones such as +05:30 and +05:45; ranges are resolved to
+-- whole buckets. Unique visitors can't be summed across buckets, so they are
+-- counted from url_visits directly.
+CREATE TABLE IF NOT EXISTS url_visit_rollups (
+    url_id INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (url_id, bucket)
+);
+
+-- Clicks per bucket for every combination of dimensions seen in that
+-- bucket. Unknown values are stored as ''.
+CREATE TABLE IF NOT EXISTS url_visit_rollup_dims (
+    url_id INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    country VARCHAR(50) NOT NULL DEFAULT '',
+    device_type VARCHAR(20) NOT NULL DEFAULT '',
+    referrer_domain VARCHAR(255) NOT NULL DEFAULT '',
+    referrer_source VARCHAR(20) NOT NULL DEFAULT '',
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (url_id, bucket, country, device_type, referrer_domain, referrer_source)
+);
+CREATE INDEX IF NOT EXISTS idx_url_visit_rollup_dims_bucket ON url_visit_rollup_dims (bucket);
+
+-- Clicks per bucket by url_visits.source.
+CREATE TABLE IF NOT EXISTS url_visit_rollup_sources (
+    url_id INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    source VARCHAR(10) NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (url_id, bucket, source)
+);
+CREATE INDEX IF NOT EXISTS idx_url_visit_rollup_sources_bucket ON url_visit_rollup_sources (bucket);
+-- Buckets rolled up before sources were recorded only had links.
+INSERT INTO url_visit_rollup_sources (url_id, bucket, source, clicks)
+SELECT r.url_id, r.bucket, 'link', r.clicks
+FROM url_visit_rollups r
+WHERE NOT EXISTS (
+    SELECT 1 FROM url_visit_rollup_sources s
+    WHERE s.url_id = r.url_id AND s.bucket = r.bucket
+);
+
+CREATE TABLE IF NOT EXISTS rollup_state (
+    name VARCHAR(50) PRIMARY KEY,
+    watermark TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    format VARCHAR(10) NOT NULL,
+    columns TEXT[] NOT NULL,
+    short_codes TEXT[] NOT NULL DEFAULT '{}',
+    from_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    to_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    file_path TEXT,
+    row_count BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
 CREATE TABLE IF NOT EXISTS qr_codes (
     id SERIAL PRIMARY KEY,
     url_id INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
@@ -48,6 +190,33 @@ CREATE TABLE IF NOT EXISTS qr_codes (
     expire_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
 );
+-- Logos uploaded by a user for their QR codes. storage_key holds the
+-- original upload; width and height are its intrinsic size.
+CREATE TABLE IF NOT EXISTS qr_logos (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_qr_logos_user_id ON qr_logos (user_id);
+
+-- A saved QR design. qr_code is the storage key of the rendered image,
+-- which is re-rendered whenever the design changes.
+ALTER TABLE qr_codes ADD COLUMN IF NOT EXISTS logo_url TEXT;
+ALTER TABLE qr_codes ADD COLUMN IF NOT EXISTS size INTEGER NOT NULL DEFAULT 512;
+ALTER TABLE qr_codes ADD COLUMN IF NOT EXISTS format TEXT NOT NULL DEFAULT 'png';
+ALTER TABLE qr_codes ADD COLUMN IF NOT EXISTS error_correction TEXT NOT NULL DEFAULT 'highest';
+ALTER TABLE qr_codes ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+ALTER TABLE qr_codes ADD COLUMN IF NOT EXISTS logo_id INTEGER REFERENCES qr_logos(id) ON DELETE SET NULL;
+-- Module and eye shapes, gradient and frame; see models.QRStyle.
+ALTER TABLE qr_codes ADD COLUMN IF NOT EXISTS style JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE qr_codes ADD COLUMN IF NOT EXISTS quiet_zone INTEGER NOT NULL DEFAULT 4;
+CREATE INDEX IF NOT EXISTS idx_qr_codes_url_id ON qr_codes (url_id);
 CREATE TABLE transactions (
     id SERIAL PRIMARY KEY,
     user_id INT NOT NULL,
@@ -59,3 +228,60 @@ CREATE TABLE transactions (
     status TEXT NOT NULL DEFAULT 'created',
     created_at TIMESTAMP DEFAULT now()
 );
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    response_code INTEGER,
+    response_body TEXT,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint ON webhook_deliveries (endpoint_id, id);
+
+-- One row per destination probe made by the health worker, kept for 30
+-- days. urls.health_status flips after a few probes in a row agree.
+CREATE TABLE IF NOT EXISTS link_health_checks (
+    id SERIAL PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    status_code INTEGER,
+    method VARCHAR(10) NOT NULL,
+    latency_ms INTEGER NOT NULL,
+    redirect_chain TEXT[] NOT NULL DEFAULT '{}',
+    final_url TEXT,
+    error TEXT,
+    checked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_link_health_checks_url_id ON link_health_checks (url_id, checked_at);
+CREATE INDEX IF NOT EXISTS idx_link_health_checks_checked_at ON link_health_checks (checked_at);
+CREATE INDEX IF NOT EXISTS idx_urls_health_checked_at ON urls (health_checked_at NULLS FIRST);
+CREATE INDEX IF NOT EXISTS idx_urls_safety_checked_at ON urls (safety_checked_at NULLS FIRST);
+CREATE INDEX IF NOT EXISTS idx_urls_thumbnail_checked_at ON urls (thumbnail_checked_at NULLS FIRST);
+
+-- Periods during which a link redirected to its backup_url because the
+-- health worker found original_url down. ended_at is NULL while ongoing.
+CREATE TABLE IF NOT EXISTS link_failovers (
+    id SERIAL PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES urls(id) ON DELETE CASCADE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    ended_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_link_failovers_open ON link_failovers (url_id) WHERE ended_at IS NULL;
@@ -10,6 +10,7 @@ import (
 
 	"api/configs"
 	"api/internal/db"
+	"api/internal/mail"
 	"api/internal/oauth"
 	"api/internal/utils"
 
@@ -17,12 +18,13 @@ import (
 )
 
 type authController struct {
-	store *db.Queries
-	db    *sql.DB
+	store  *db.Queries
+	db     *sql.DB
+	mailer *mail.Mailer
 }
 
-func NewAuthController(store *db.Queries, dbConn *sql.DB) *authController {
-	return &authController{store: store, db: dbConn}
+func NewAuthController(store *db.Queries, dbConn *sql.DB, mailer *mail.Mailer) *authController {
+	return &authController{store: store, db: dbConn, mailer: mailer}
 }
 
 type RegisterRequest struct {
@@ -82,15 +84,21 @@ func (a *authController) Register(ctx *gin.Context) {
 		ctx.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	// The account stands even if the email fails; the user can ask for
+	// another.
+	if err := a.sendVerificationEmail(ctx.Request.Context(), user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
 	ctx.JSON(200, gin.H{
-		"message": "User registered successfully",
+		"message": "User registered successfully, check your email to verify it",
 		"user": gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"email":      user.Email,
-			"ip":         user.IpAddress,
-			"created_at": user.CreatedAt,
-			"updated_at": user.UpdatedAt,
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"email_verified": false,
+			"ip":             user.IpAddress,
+			"created_at":     user.CreatedAt,
+			"updated_at":     user.UpdatedAt,
 		},
 	})
 	ctx.SetCookie("email", req.Email, 3600*24*10, "/", "", false, false) // 10 days
@@ -143,7 +151,7 @@ type LoginRequest struct {
 
 func (a *authController) Login(ctx *gin.Context) {
 	if token, err := ctx.Cookie("token"); err == nil && token != "" {
-		if _, err := utils.ValidateToken(token); err == nil {
+		if claims, err := utils.ValidateToken(token); err == nil && utils.CheckSession(ctx, a.store, claims) == nil {
 			ctx.JSON(200, gin.H{
 				"message": "Already logged in",
 			})
@@ -187,8 +195,13 @@ func (a *authController) Login(ctx *gin.Context) {
 		ctx.JSON(401, gin.H{"error": "Invalid password"})
 		return
 	}
+	if !user.EmailVerifiedAt.Valid && configs.GetUnverifiedEmailPolicy() == "block" {
+		tx.Rollback()
+		ctx.JSON(403, gin.H{"error": "Verify your email address before logging in"})
+		return
+	}
 
-	token, err := utils.GenerateJWT(int64(user.ID), user.Username)
+	token, err := utils.GenerateJWT(int64(user.ID), user.Username, user.SessionVersion)
 	if err != nil {
 		tx.Rollback()
 		ctx.JSON(500, gin.H{"error": "Failed to generate token"})
@@ -204,9 +217,10 @@ func (a *authController) Login(ctx *gin.Context) {
 	ctx.JSON(200, gin.H{
 		"message": "Login successful",
 		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"email_verified": user.EmailVerifiedAt.Valid,
 		},
 	})
 }
@@ -264,6 +278,10 @@ func (a *authController) ProviderCallback(ctx *gin.Context) {
 	if err == sql.ErrNoRows {
 		// Try by email as fallback
 		dbUser, err = store.GetUserByEmail(ctx, user.Email)
+		if err == nil && !dbUser.EmailVerifiedAt.Valid {
+			// Signing in with the provider proves the email is theirs.
+			dbUser, err = store.VerifyUserEmail(ctx, db.VerifyUserEmailParams{ID: dbUser.ID, Email: dbUser.Email})
+		}
 	}
 
 	if err == sql.ErrNoRows {
@@ -308,7 +326,7 @@ func (a *authController) ProviderCallback(ctx *gin.Context) {
 		return
 	}
 
-	token, err := utils.GenerateJWT(int64(dbUser.ID), dbUser.Username)
+	token, err := utils.GenerateJWT(int64(dbUser.ID), dbUser.Username, dbUser.SessionVersion)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
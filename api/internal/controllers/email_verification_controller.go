@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"api/configs"
+	"api/internal/db"
+	"api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// VerifyEmail is where the link in a verification email leads. It renders
+// a page, as it is opened from a mail client rather than the app.
+func (a *authController) VerifyEmail(ctx *gin.Context) {
+	userID, email, err := utils.ValidateEmailVerificationToken(ctx.Query("token"))
+	if err == nil {
+		_, err = a.store.VerifyUserEmail(ctx, db.VerifyUserEmailParams{ID: userID, Email: email})
+		if errors.Is(err, sql.ErrNoRows) {
+			// The account is gone or its email changed since.
+			err = utils.ErrEmailTokenInvalid
+		}
+	}
+
+	switch {
+	case errors.Is(err, utils.ErrEmailTokenExpired), errors.Is(err, utils.ErrEmailTokenInvalid):
+		ctx.HTML(http.StatusBadRequest, "email_verified.html", gin.H{
+			"PageTitle":   "Email not verified",
+			"Error":       "This " + err.Error(),
+			"ContinueURL": configs.GetAPIURL() + "/login",
+		})
+	case err != nil:
+		log.Printf("Failed to verify email of user %d: %v", userID, err)
+		ctx.HTML(http.StatusInternalServerError, "email_verified.html", gin.H{
+			"PageTitle":   "Email not verified",
+			"Error":       "Something went wrong on our side",
+			"ContinueURL": configs.GetAPIURL() + "/login",
+		})
+	default:
+		ctx.HTML(http.StatusOK, "email_verified.html", gin.H{
+			"PageTitle":   "Email verified",
+			"Email":       email,
+			"ContinueURL": configs.GetAPIURL() + "/dashboard",
+		})
+	}
+}
+
+// ResendVerificationEmail sends a new verification link. It answers the
+// same whether or not the email belongs to an unverified account, so it
+// cannot be used to find out which emails are registered.
+func (a *authController) ResendVerificationEmail(ctx *gin.Context) {
+	var req ResendVerificationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, err := a.store.GetUserByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if err == nil && !user.EmailVerifiedAt.Valid {
+		if err := a.sendVerificationEmail(ctx.Request.Context(), user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "If the email belongs to an unverified account, a verification link is on its way"})
+}
+
+// sendVerificationEmail mails user a verification link, at most once a
+// minute; more frequent calls do nothing.
+func (a *authController) sendVerificationEmail(ctx context.Context, user db.User) error {
+	claimed, err := a.store.ClaimVerificationEmail(ctx, user.ID)
+	if err != nil || claimed == 0 {
+		return err
+	}
+
+	token, err := utils.GenerateEmailVerificationToken(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+	return a.mailer.Send(ctx, user.Email, "verify_email", map[string]string{
+		"Username":  user.Username,
+		"Link":      configs.GetAPIURL() + "/api/verify-email?token=" + url.QueryEscape(token),
+		"ExpiresIn": fmt.Sprintf("%d hours", int(utils.EmailVerificationTTL.Hours())),
+	})
+}
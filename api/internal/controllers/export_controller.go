@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/configs"
+	"api/internal/db"
+	"api/internal/export"
+	"api/internal/models"
+	"api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxStreamedVisits is the largest export served inline; bigger ranges
+// must go through an export job.
+const maxStreamedVisits = 100000
+
+type ExportController struct {
+	store *db.Queries
+}
+
+func NewExportController(store *db.Queries) *ExportController {
+	return &ExportController{store: store}
+}
+
+type exportRequest struct {
+	format     string
+	columns    []export.Column
+	shortcodes []string
+	rng        utils.AnalyticsRange
+}
+
+// parseExportRequest reads format, columns, shortcode and the analytics range
+// from the query string and checks that every shortcode belongs to the
+// caller. It writes the error response itself and reports false on failure.
+func (c *ExportController) parseExportRequest(ctx *gin.Context) (exportRequest, bool) {
+	var req exportRequest
+
+	req.format = strings.ToLower(ctx.DefaultQuery("format", export.FormatCSV))
+	if !export.ValidFormat(req.format) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of csv, ndjson or parquet"})
+		return req, false
+	}
+
+	columns, err := export.ParseColumns(splitQueryList(ctx, "columns"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	req.columns = columns
+
+	req.rng, err = utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 30)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+
+	req.shortcodes = splitQueryList(ctx, "shortcode")
+	if len(req.shortcodes) > 0 {
+		owned, err := c.store.GetOwnedShortCodes(ctx, db.GetOwnedShortCodesParams{
+			UserID:     sql.NullInt32{Int32: int32(ctx.GetInt64("user_id")), Valid: true},
+			ShortCodes: req.shortcodes,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check links"})
+			return req, false
+		}
+		if len(owned) != len(req.shortcodes) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// ExportVisits streams the raw visit log for the caller's links.
+func (c *ExportController) ExportVisits(ctx *gin.Context) {
+	req, ok := c.parseExportRequest(ctx)
+	if !ok {
+		return
+	}
+	userID := int32(ctx.GetInt64("user_id"))
+
+	shortcodes := req.shortcodes
+	if shortcodes == nil {
+		shortcodes = []string{}
+	}
+	total, err := c.store.CountVisitsForExport(ctx, db.CountVisitsForExportParams{
+		UserID:     sql.NullInt32{Int32: userID, Valid: true},
+		ShortCodes: shortcodes,
+		FromTime:   req.rng.From,
+		ToTime:     req.rng.To,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count visits"})
+		return
+	}
+	if total > maxStreamedVisits {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": fmt.Sprintf("export has %d rows, create an export job for more than %d", total, maxStreamedVisits),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("visits-%s-%s.%s", req.rng.From.Format("20060102"), req.rng.To.Format("20060102"), req.format)
+	ctx.Header("Content-Type", export.ContentType(req.format))
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Status(http.StatusOK)
+
+	w, err := export.NewWriter(req.format, ctx.Writer, req.columns)
+	if err == nil {
+		_, err = export.Stream(ctx.Request.Context(), c.store, export.Query{
+			UserID:     userID,
+			ShortCodes: req.shortcodes,
+			From:       req.rng.From,
+			To:         req.rng.To,
+			Location:   req.rng.Location,
+		}, w)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		// Headers are already sent, so the client sees a truncated file.
+		log.Printf("visit export for user %d failed: %v", userID, err)
+	}
+}
+
+// CreateExportJob queues an export for the worker; it takes the same query
+// parameters as ExportVisits and has no size limit.
+func (c *ExportController) CreateExportJob(ctx *gin.Context) {
+	req, ok := c.parseExportRequest(ctx)
+	if !ok {
+		return
+	}
+
+	shortcodes := req.shortcodes
+	if shortcodes == nil {
+		shortcodes = []string{}
+	}
+	job, err := c.store.CreateExportJob(ctx, db.CreateExportJobParams{
+		UserID:     int32(ctx.GetInt64("user_id")),
+		Format:     req.format,
+		Columns:    export.ColumnNames(req.columns),
+		ShortCodes: shortcodes,
+		FromTime:   req.rng.From,
+		ToTime:     req.rng.To,
+		Timezone:   req.rng.TZ(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create export job"})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, exportJobResponse(job))
+}
+
+func (c *ExportController) ListExportJobs(ctx *gin.Context) {
+	jobs, err := c.store.ListExportJobsByUser(ctx, int32(ctx.GetInt64("user_id")))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch export jobs"})
+		return
+	}
+
+	response := make([]models.ExportJobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		response = append(response, exportJobResponse(job))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *ExportController) GetExportJob(ctx *gin.Context) {
+	job, ok := c.findExportJob(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, exportJobResponse(job))
+}
+
+func (c *ExportController) DownloadExport(ctx *gin.Context) {
+	job, ok := c.findExportJob(ctx)
+	if !ok {
+		return
+	}
+	if job.Status != export.JobCompleted || !job.FilePath.Valid {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Export is not ready", "status": job.Status})
+		return
+	}
+
+	ctx.Header("Content-Type", export.ContentType(job.Format))
+	ctx.FileAttachment(job.FilePath.String, export.FileName(job))
+}
+
+func (c *ExportController) findExportJob(ctx *gin.Context) (db.ExportJob, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export id"})
+		return db.ExportJob{}, false
+	}
+
+	job, err := c.store.GetExportJob(ctx, db.GetExportJobParams{
+		ID:     int32(id),
+		UserID: int32(ctx.GetInt64("user_id")),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return job, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch export job"})
+		return job, false
+	}
+	return job, true
+}
+
+func exportJobResponse(job db.ExportJob) models.ExportJobResponse {
+	response := models.ExportJobResponse{
+		ID:         job.ID,
+		Status:     job.Status,
+		Format:     job.Format,
+		Columns:    job.Columns,
+		Shortcodes: job.ShortCodes,
+		From:       job.FromTime,
+		To:         job.ToTime,
+		Timezone:   job.Timezone,
+		RowCount:   job.RowCount,
+		Error:      utils.NullToStr(job.Error),
+	}
+	if job.CreatedAt.Valid {
+		response.CreatedAt = job.CreatedAt.Time.Format(time.RFC3339)
+	}
+	if job.CompletedAt.Valid {
+		response.CompletedAt = job.CompletedAt.Time.Format(time.RFC3339)
+		response.ExpiresAt = job.CompletedAt.Time.Add(export.FileTTL).Format(time.RFC3339)
+	}
+	if job.Status == export.JobCompleted {
+		response.DownloadURL = fmt.Sprintf("%s/api/protected/analytics/exports/%d/download", configs.GetAPIURL(), job.ID)
+	}
+	return response
+}
+
+// splitQueryList collects a repeated or comma separated query parameter,
+// dropping blanks and duplicates.
+func splitQueryList(ctx *gin.Context, key string) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, value := range ctx.QueryArray(key) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" && !seen[item] {
+				seen[item] = true
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
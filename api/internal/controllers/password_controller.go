@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/configs"
+	"api/internal/db"
+	"api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// passwordResetWindow is the period over which forgot password requests
+	// are counted for rate limiting.
+	passwordResetWindow       = time.Hour
+	maxPasswordResetsPerEmail = 3
+	maxPasswordResetsPerIP    = 10
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email,max=254"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+// ForgotPassword emails a single use password reset link. It answers the
+// same, and as fast, whether or not the email is registered, and is rate
+// limited by email and by IP either way.
+func (a *authController) ForgotPassword(ctx *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	ip := utils.GetIP(ctx)
+
+	counts, err := a.store.CountPasswordResetRequests(ctx, db.CountPasswordResetRequestsParams{
+		Email:     email,
+		IpAddress: ip,
+		Since:     time.Now().Add(-passwordResetWindow),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if passwordResetLimited(counts) {
+		ctx.Header("Retry-After", strconv.Itoa(int(passwordResetWindow.Seconds())))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many password reset requests, try again later"})
+		return
+	}
+	if err := a.store.CreatePasswordResetRequest(ctx, db.CreatePasswordResetRequestParams{Email: email, IpAddress: ip}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	a.pruneExpiredPasswordResets(ctx)
+
+	user, err := a.store.GetUserByEmailFold(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if err == nil {
+		// Sending after answering keeps failures and the time SMTP takes
+		// from telling registered emails apart.
+		go func() {
+			if err := a.sendPasswordReset(context.Background(), user); err != nil {
+				log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+			}
+		}()
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "If the email is registered, a password reset link is on its way"})
+}
+
+// ResetPassword sets a new password with the token from a reset link,
+// which then stops working, and signs out every session of the account.
+func (a *authController) ResetPassword(ctx *gin.Context) {
+	var req ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+	password, err := utils.HashPassword(req.Password)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+	qtx := db.New(tx)
+
+	userID, err := qtx.ConsumePasswordResetToken(ctx, utils.HashPasswordResetToken(req.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset link"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check reset link"})
+		return
+	}
+	if _, err := qtx.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{ID: userID, PasswordHash: password}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+	// Other links sent before this reset must not work after it.
+	if err := qtx.DeletePasswordResetTokensByUser(ctx, userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	ctx.SetCookie("token", "", -1, "", "", false, true)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password has been reset, log in with the new password"})
+}
+
+// ChangePassword sets a new password for the logged in user after checking
+// the current one. Other sessions are signed out; this one gets a new
+// token.
+func (a *authController) ChangePassword(ctx *gin.Context) {
+	var req ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, err := a.store.GetUserByID(ctx, int32(ctx.GetInt64("user_id")))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if user.PasswordHash == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "This account has no password, use forgot password to set one"})
+		return
+	}
+	if !utils.CheckPasswordHash(req.CurrentPassword, user.PasswordHash) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	password, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	user, err = a.store.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{ID: user.ID, PasswordHash: password})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+	if err := a.store.DeletePasswordResetTokensByUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to delete password reset tokens of user %d: %v", user.ID, err)
+	}
+
+	token, err := utils.GenerateJWT(int64(user.ID), user.Username, user.SessionVersion)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	ctx.SetCookie("token", token, 3600*24*10, "", "", false, true) // 10 days
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password changed, other sessions have been signed out"})
+}
+
+// ResetPasswordPage serves the page a reset link leads to, a form that
+// posts the new password to ResetPassword.
+func (a *authController) ResetPasswordPage(ctx *gin.Context) {
+	token := ctx.Query("token")
+	status := http.StatusOK
+	if token == "" {
+		status = http.StatusBadRequest
+	}
+	ctx.HTML(status, "reset_password.html", gin.H{
+		"PageTitle": "Reset your password",
+		"Token":     token,
+		"LoginURL":  configs.GetFrontendURL() + "/login",
+	})
+}
+
+// passwordResetLimited reports whether there have been too many forgot
+// password requests for an email or from an IP within passwordResetWindow.
+func passwordResetLimited(counts db.CountPasswordResetRequestsRow) bool {
+	return counts.ByEmail >= maxPasswordResetsPerEmail || counts.ByIp >= maxPasswordResetsPerIP
+}
+
+// sendPasswordReset stores a new reset token for user and mails its link,
+// which leads to the reset page of FRONTEND_URL.
+func (a *authController) sendPasswordReset(ctx context.Context, user db.User) error {
+	token, hash, err := utils.NewPasswordResetToken()
+	if err != nil {
+		return err
+	}
+	err = a.store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(utils.PasswordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return a.mailer.Send(ctx, user.Email, "reset_password", map[string]string{
+		"Username":  user.Username,
+		"Link":      configs.GetFrontendURL() + "/reset-password?token=" + url.QueryEscape(token),
+		"ExpiresIn": fmt.Sprintf("%d minutes", int(utils.PasswordResetTTL.Minutes())),
+	})
+}
+
+// pruneExpiredPasswordResets drops requests too old to count against the
+// rate limits and tokens too old to use.
+func (a *authController) pruneExpiredPasswordResets(ctx context.Context) {
+	if err := a.store.DeletePasswordResetRequestsBefore(ctx, time.Now().Add(-passwordResetWindow)); err != nil {
+		log.Printf("Failed to prune password reset requests: %v", err)
+	}
+	if err := a.store.DeleteExpiredPasswordResetTokens(ctx); err != nil {
+		log.Printf("Failed to prune password reset tokens: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"api/internal/db"
+	"api/internal/dbtest"
+	"api/internal/mail"
+	"api/internal/pages"
+	"api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeSender hands sent messages to the test, or fails with err.
+type fakeSender struct {
+	sent chan mail.Message
+	err  error
+}
+
+func (s *fakeSender) Send(ctx context.Context, msg mail.Message) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent <- msg
+	return nil
+}
+
+func newPasswordRouter(a *authController) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.SetHTMLTemplate(pages.Templates)
+	r.POST("/api/password/forgot", a.ForgotPassword)
+	r.POST("/api/password/reset", a.ResetPassword)
+	r.GET("/reset-password", a.ResetPasswordPage)
+	return r
+}
+
+func postJSON(r http.Handler, path string, body any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+var resetLinkPattern = regexp.MustCompile(`https://app\.example\.com/reset-password\?token=(\S+)`)
+
+// resetToken waits for the reset email to user and returns the token in its
+// link.
+func resetToken(t *testing.T, sent chan mail.Message, to string) string {
+	t.Helper()
+	select {
+	case msg := <-sent:
+		if msg.To != to {
+			t.Fatalf("reset email sent to %s, want %s", msg.To, to)
+		}
+		m := resetLinkPattern.FindStringSubmatch(msg.Text)
+		if m == nil {
+			t.Fatalf("no reset link on FRONTEND_URL in %q", msg.Text)
+		}
+		token, err := url.QueryUnescape(m[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reset email was sent")
+		return ""
+	}
+}
+
+func TestPasswordResetLimited(t *testing.T) {
+	tests := []struct {
+		byEmail, byIP int64
+		want          bool
+	}{
+		{0, 0, false},
+		{maxPasswordResetsPerEmail - 1, maxPasswordResetsPerIP - 1, false},
+		{maxPasswordResetsPerEmail, 0, true},
+		{0, maxPasswordResetsPerIP, true},
+		{maxPasswordResetsPerEmail + 5, maxPasswordResetsPerIP + 5, true},
+	}
+	for _, tt := range tests {
+		if got := passwordResetLimited(db.CountPasswordResetRequestsRow{ByEmail: tt.byEmail, ByIp: tt.byIP}); got != tt.want {
+			t.Errorf("passwordResetLimited(%d by email, %d by IP) = %v, want %v", tt.byEmail, tt.byIP, got, tt.want)
+		}
+	}
+}
+
+func TestResetPasswordPage(t *testing.T) {
+	r := newPasswordRouter(&authController{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reset-password?token=abc%22def", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `name="token" value="abc&#34;def"`) || !strings.Contains(body, `"/api/password/reset"`) {
+		t.Errorf("page lacks the escaped token or the reset endpoint:\n%s", body)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/reset-password", nil))
+	if w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), "<form") {
+		t.Errorf("without a token: status = %d, want 400 and no form", w.Code)
+	}
+}
+
+func TestForgotAndResetPassword(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("FRONTEND_URL", "https://app.example.com")
+	conn, store := dbtest.Open(t)
+	ctx := context.Background()
+
+	hash, err := utils.HashPassword("old password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := store.CreateUser(ctx, db.CreateUserParams{Username: "ada", Email: "Ada@Example.com", PasswordHash: hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSession, err := utils.GenerateJWT(int64(user.ID), user.Username, user.SessionVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &fakeSender{sent: make(chan mail.Message, 10)}
+	r := newPasswordRouter(NewAuthController(store, conn, mail.NewMailer(sender, "no-reply@example.com")))
+
+	// The email is matched ignoring case.
+	if w := postJSON(r, "/api/password/forgot", gin.H{"email": "ADA@example.com"}); w.Code != http.StatusAccepted {
+		t.Fatalf("forgot: status = %d, want 202: %s", w.Code, w.Body)
+	}
+	token := resetToken(t, sender.sent, user.Email)
+
+	if w := postJSON(r, "/api/password/reset", gin.H{"token": token, "password": "new password"}); w.Code != http.StatusOK {
+		t.Fatalf("reset: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	updated, err := store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utils.CheckPasswordHash("new password", updated.PasswordHash) {
+		t.Error("the password was not changed")
+	}
+
+	// The reset signs out sessions issued before it.
+	claims, err := utils.ValidateToken(oldSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.CheckSession(ctx, store, claims); !errors.Is(err, utils.ErrSessionRevoked) {
+		t.Errorf("CheckSession(old session) = %v, want ErrSessionRevoked", err)
+	}
+
+	// A token works once.
+	if w := postJSON(r, "/api/password/reset", gin.H{"token": token, "password": "another password"}); w.Code != http.StatusBadRequest {
+		t.Errorf("reusing the token: status = %d, want 400", w.Code)
+	}
+
+	expired, expiredHash, err := utils.NewPasswordResetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: expiredHash,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if w := postJSON(r, "/api/password/reset", gin.H{"token": expired, "password": "another password"}); w.Code != http.StatusBadRequest {
+		t.Errorf("expired token: status = %d, want 400", w.Code)
+	}
+}
+
+func TestForgotPasswordAnswersTheSame(t *testing.T) {
+	t.Setenv("FRONTEND_URL", "https://app.example.com")
+	conn, store := dbtest.Open(t)
+	ctx := context.Background()
+
+	if _, err := store.CreateUser(ctx, db.CreateUserParams{Username: "ada", Email: "ada@example.com", PasswordHash: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	// Even a mail server that is down does not tell registered emails apart.
+	sender := &fakeSender{err: errors.New("connection refused")}
+	r := newPasswordRouter(NewAuthController(store, conn, mail.NewMailer(sender, "no-reply@example.com")))
+
+	registered := postJSON(r, "/api/password/forgot", gin.H{"email": "ada@example.com"})
+	unknown := postJSON(r, "/api/password/forgot", gin.H{"email": "nobody@example.com"})
+	if registered.Code != http.StatusAccepted || unknown.Code != http.StatusAccepted || registered.Body.String() != unknown.Body.String() {
+		t.Errorf("registered: %d %s; unknown: %d %s; want the same 202", registered.Code, registered.Body, unknown.Code, unknown.Body)
+	}
+}
+
+func TestForgotPasswordRateLimits(t *testing.T) {
+	conn, store := dbtest.Open(t)
+	sender := &fakeSender{sent: make(chan mail.Message, 10)}
+	r := newPasswordRouter(NewAuthController(store, conn, mail.NewMailer(sender, "no-reply@example.com")))
+
+	forgot := func(email, ip string) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(gin.H{"email": email})
+		req := httptest.NewRequest(http.MethodPost, "/api/password/forgot", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Real-IP", ip)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Requests for one email count together whatever its case.
+	for i, email := range []string{"bob@example.com", "Bob@example.com", "BOB@EXAMPLE.COM"} {
+		if w := forgot(email, "192.0.2.1"); w.Code != http.StatusAccepted {
+			t.Fatalf("request %d: status = %d, want 202", i+1, w.Code)
+		}
+	}
+	w := forgot("bob@example.com", "192.0.2.2")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("request %d for one email: status = %d, want 429 with Retry-After", maxPasswordResetsPerEmail+1, w.Code)
+	}
+
+	// Requests from one IP count together whatever the email.
+	for i := 0; i < maxPasswordResetsPerIP; i++ {
+		if w := forgot(fmt.Sprintf("user%d@example.com", i), "192.0.2.3"); w.Code != http.StatusAccepted {
+			t.Fatalf("request %d from one IP: status = %d, want 202", i+1, w.Code)
+		}
+	}
+	if w := forgot("someone@example.com", "192.0.2.3"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("request %d from one IP: status = %d, want 429", maxPasswordResetsPerIP+1, w.Code)
+	}
+}
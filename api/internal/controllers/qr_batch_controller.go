@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"api/internal/db"
+	"api/internal/models"
+	"api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// qrBatchWorkers bounds how many codes of a batch render at once.
+	qrBatchWorkers = 4
+
+	// qrBatchWindow bounds how far rendering runs ahead of the archive, so a
+	// slow download holds rendering back instead of piling codes up in
+	// memory.
+	qrBatchWindow = 2 * qrBatchWorkers
+)
+
+var qrManifestHeader = []string{"file", "shortcode", "title", "url", "status", "problem", "contrast_ratio", "error_budget_used", "warnings"}
+
+type qrBatchResult struct {
+	data   []byte
+	report utils.QRScanReport
+	err    error
+}
+
+// BatchQRCodes renders one design for many of the caller's links and
+// streams a ZIP with an image per link and a manifest.csv. A link whose
+// code fails to render or read back is listed in the manifest without an
+// image.
+func (c *QRController) BatchQRCodes(ctx *gin.Context) {
+	var req models.QRBatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	links, ok := c.findBatchLinks(ctx, req.Shortcodes)
+	if !ok {
+		return
+	}
+
+	design := withQRDefaults(req.Design)
+	opts, _, extension, ok := c.qrOptions(ctx, design)
+	if !ok {
+		return
+	}
+	// Download a logo once instead of once per code.
+	if opts.Logo == nil && opts.LogoURL != "" {
+		logo, err := utils.DownloadLogo(opts.LogoURL)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts.Logo, opts.LogoURL = logo, ""
+	}
+
+	renderCtx, cancel := context.WithCancel(ctx.Request.Context())
+	defer cancel()
+	results, release := renderQRBatch(renderCtx, links, opts)
+
+	// The codes differ only in their shortcode, so when the first does not
+	// scan the design is at fault; say so before the archive starts.
+	first := <-results[0]
+	if first.err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to render QR code: " + first.err.Error()})
+		return
+	}
+	if !first.report.Readable {
+		scan := models.QRScanReport(first.report)
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "QR code would not scan: " + scan.Problem, "scan": scan})
+		return
+	}
+
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "qr-codes.zip"}))
+	ctx.Status(http.StatusOK)
+
+	archive := zip.NewWriter(ctx.Writer)
+	var manifest [][]string
+	taken := make(map[string]bool, len(links))
+	// Compressing PNG, JPEG and PDF files again gains nothing.
+	method := zip.Store
+	if design.Format == "svg" {
+		method = zip.Deflate
+	}
+
+	err := func() error {
+		for i, link := range links {
+			result := first
+			if i > 0 {
+				result = <-results[i]
+			}
+			release()
+
+			row := qrManifestRow(link, result)
+			if result.err == nil && result.report.Readable {
+				name := qrBatchFileName(link, req.NameBy, extension, taken)
+				w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: time.Now()})
+				if err != nil {
+					return err
+				}
+				if _, err := w.Write(result.data); err != nil {
+					return err
+				}
+				row[0] = name
+			}
+			manifest = append(manifest, row)
+		}
+
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: "manifest.csv", Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		out := csv.NewWriter(w)
+		out.Write(qrManifestHeader)
+		out.WriteAll(manifest)
+		if err := out.Error(); err != nil {
+			return err
+		}
+		return archive.Close()
+	}()
+	if err != nil {
+		// Headers are already sent, so the client sees a truncated file.
+		log.Printf("QR batch for user %d failed: %v", ctx.GetInt64("user_id"), err)
+	}
+}
+
+// findBatchLinks returns the caller's links for shortcodes, in the order
+// given and without repeats. It responds itself when any is missing.
+func (c *QRController) findBatchLinks(ctx *gin.Context, shortcodes []string) ([]db.ListLinksByShortCodesRow, bool) {
+	rows, err := c.store.ListLinksByShortCodes(ctx, db.ListLinksByShortCodesParams{
+		UserID:     sql.NullInt32{Int32: int32(ctx.GetInt64("user_id")), Valid: true},
+		ShortCodes: shortcodes,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch links"})
+		return nil, false
+	}
+	byCode := make(map[string]db.ListLinksByShortCodesRow, len(rows))
+	for _, row := range rows {
+		byCode[row.ShortCode] = row
+	}
+
+	links := make([]db.ListLinksByShortCodesRow, 0, len(rows))
+	seen := make(map[string]bool, len(shortcodes))
+	var missing []string
+	for _, code := range shortcodes {
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		if link, ok := byCode[code]; ok {
+			links = append(links, link)
+		} else {
+			missing = append(missing, code)
+		}
+	}
+	if len(missing) > 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Links not found", "shortcodes": missing})
+		return nil, false
+	}
+	return links, true
+}
+
+// renderQRBatch renders and checks the code of every link on
+// qrBatchWorkers goroutines. The i-th channel receives the code of the
+// i-th link; call release after taking each one so rendering can move on.
+// Rendering stops early when ctx is done.
+func renderQRBatch(ctx context.Context, links []db.ListLinksByShortCodesRow, opts utils.QROptions) ([]chan qrBatchResult, func()) {
+	results := make([]chan qrBatchResult, len(links))
+	for i := range results {
+		results[i] = make(chan qrBatchResult, 1)
+	}
+	window := make(chan struct{}, qrBatchWindow)
+	jobs := make(chan int)
+
+	go func() {
+		defer close(jobs)
+		for i := range links {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for range qrBatchWorkers {
+		go func() {
+			for i := range jobs {
+				var result qrBatchResult
+				result.data, result.report, result.err = utils.RenderCheckedQRCode(qrScanURL(links[i].ShortCode), opts)
+				results[i] <- result
+			}
+		}()
+	}
+	return results, func() { <-window }
+}
+
+func qrManifestRow(link db.ListLinksByShortCodesRow, result qrBatchResult) []string {
+	row := []string{"", link.ShortCode, link.Title.String, qrScanURL(link.ShortCode), "ok", "", "", "", ""}
+	if result.err != nil {
+		row[4], row[5] = "error", result.err.Error()
+		return row
+	}
+	report := result.report
+	if !report.Readable {
+		row[4], row[5] = "unreadable", report.Problem
+	}
+	row[6] = strconv.FormatFloat(report.ContrastRatio, 'f', -1, 64)
+	row[7] = strconv.FormatFloat(report.ErrorBudgetUsed, 'f', -1, 64)
+	row[8] = strings.Join(report.Warnings, "; ")
+	return row
+}
+
+// qrBatchFileName names a link's image in the archive after its shortcode
+// or, with nameBy "title", its title, adding a number to repeated names.
+func qrBatchFileName(link db.ListLinksByShortCodesRow, nameBy, extension string, taken map[string]bool) string {
+	base := link.ShortCode
+	if nameBy == "title" {
+		if slug := fileSlug(link.Title.String); slug != "" {
+			base = slug
+		}
+	}
+	name := base + "." + extension
+	for n := 2; taken[name]; n++ {
+		name = fmt.Sprintf("%s-%d.%s", base, n, extension)
+	}
+	taken[name] = true
+	return name
+}
+
+// fileSlug keeps the letters and digits of s, lower cased, with single
+// dashes between words.
+func fileSlug(s string) string {
+	var b strings.Builder
+	gap := false
+	for _, r := range strings.ToLower(s) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			gap = true
+			continue
+		}
+		if gap && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		gap = false
+		b.WriteRune(r)
+		if b.Len() >= 60 {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,453 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/configs"
+	"api/internal/db"
+	"api/internal/models"
+	"api/internal/storage"
+	"api/internal/utils"
+	"api/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QRController manages saved QR designs. Each design's image is rendered
+// once when it is saved and kept in storage, so serving it needs neither
+// a render nor a logo download.
+type QRController struct {
+	store    *db.Queries
+	files    storage.Storage
+	webhooks *webhook.Dispatcher
+}
+
+func NewQRController(store *db.Queries, files storage.Storage, webhooks *webhook.Dispatcher) *QRController {
+	return &QRController{store: store, files: files, webhooks: webhooks}
+}
+
+func (c *QRController) CreateQRCode(ctx *gin.Context) {
+	var req models.CreateQRDesignRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	userID := int32(ctx.GetInt64("user_id"))
+	link, err := c.store.GetOriginalURL(ctx, req.Shortcode)
+	if err == nil && link.UserID.Int32 != userID {
+		err = sql.ErrNoRows
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch link"})
+		return
+	}
+
+	design := withQRDefaults(req.QRDesign)
+	key, scan, ok := c.render(ctx, link.ShortCode, design)
+	if !ok {
+		return
+	}
+
+	style, _ := json.Marshal(design.Style)
+	saved, err := c.store.CreateQRCode(ctx, db.CreateQRCodeParams{
+		UrlID:           link.ID,
+		QrCode:          key,
+		FgColor:         sql.NullString{String: design.FgColor, Valid: true},
+		BgColor:         sql.NullString{String: design.BgColor, Valid: true},
+		LogoUrl:         sql.NullString{String: design.LogoURL, Valid: design.LogoURL != ""},
+		Size:            int32(design.Size),
+		Format:          design.Format,
+		ErrorCorrection: design.ErrorCorrection,
+		ExpireAt:        nullTime(design.ExpireAt),
+		LogoID:          nullInt32(design.LogoID),
+		Style:           style,
+		QuietZone:       int32(*design.QuietZone),
+	})
+	if err != nil {
+		_ = c.files.Delete(ctx, key)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save QR code"})
+		return
+	}
+
+	response := qrDesignResponse(db.GetQRCodeRow{
+		ID:              saved.ID,
+		UrlID:           saved.UrlID,
+		ShortCode:       link.ShortCode,
+		QrCode:          saved.QrCode,
+		FgColor:         saved.FgColor,
+		BgColor:         saved.BgColor,
+		LogoUrl:         saved.LogoUrl,
+		Size:            saved.Size,
+		Format:          saved.Format,
+		ErrorCorrection: saved.ErrorCorrection,
+		ExpireAt:        saved.ExpireAt,
+		CreatedAt:       saved.CreatedAt,
+		UpdatedAt:       saved.UpdatedAt,
+		LogoID:          saved.LogoID,
+		Style:           saved.Style,
+		QuietZone:       saved.QuietZone,
+	})
+	response.Scan = &scan
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// ListQRCodes returns the caller's saved QR codes, newest first.
+func (c *QRController) ListQRCodes(ctx *gin.Context) {
+	codes, err := c.store.ListQRCodesByUser(ctx, sql.NullInt32{Int32: int32(ctx.GetInt64("user_id")), Valid: true})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch QR codes"})
+		return
+	}
+
+	response := make([]models.QRDesignResponse, 0, len(codes))
+	for _, code := range codes {
+		response = append(response, qrDesignResponse(db.GetQRCodeRow(code)))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *QRController) GetQRCode(ctx *gin.Context) {
+	code, ok := c.findQRCode(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, qrDesignResponse(code))
+}
+
+// UpdateQRCode replaces the design and re-renders the image under a new
+// key, so cached copies of the old one are never served for the new design.
+func (c *QRController) UpdateQRCode(ctx *gin.Context) {
+	code, ok := c.findQRCode(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.QRDesign
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	design := withQRDefaults(req)
+	key, scan, ok := c.render(ctx, code.ShortCode, design)
+	if !ok {
+		return
+	}
+
+	style, _ := json.Marshal(design.Style)
+	updated, err := c.store.UpdateQRCode(ctx, db.UpdateQRCodeParams{
+		ID:              code.ID,
+		QrCode:          key,
+		FgColor:         sql.NullString{String: design.FgColor, Valid: true},
+		BgColor:         sql.NullString{String: design.BgColor, Valid: true},
+		LogoUrl:         sql.NullString{String: design.LogoURL, Valid: design.LogoURL != ""},
+		Size:            int32(design.Size),
+		Format:          design.Format,
+		ErrorCorrection: design.ErrorCorrection,
+		ExpireAt:        nullTime(design.ExpireAt),
+		LogoID:          nullInt32(design.LogoID),
+		Style:           style,
+		QuietZone:       int32(*design.QuietZone),
+	})
+	if err != nil {
+		_ = c.files.Delete(ctx, key)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update QR code"})
+		return
+	}
+	_ = c.files.Delete(ctx, code.QrCode)
+
+	code.QrCode = updated.QrCode
+	code.FgColor = updated.FgColor
+	code.BgColor = updated.BgColor
+	code.LogoUrl = updated.LogoUrl
+	code.Size = updated.Size
+	code.Format = updated.Format
+	code.ErrorCorrection = updated.ErrorCorrection
+	code.ExpireAt = updated.ExpireAt
+	code.UpdatedAt = updated.UpdatedAt
+	code.LogoID = updated.LogoID
+	code.Style = updated.Style
+	code.QuietZone = updated.QuietZone
+	response := qrDesignResponse(code)
+	response.Scan = &scan
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *QRController) DeleteQRCode(ctx *gin.Context) {
+	code, ok := c.findQRCode(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.store.DeleteQRCode(ctx, code.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete QR code"})
+		return
+	}
+	_ = c.files.Delete(ctx, code.QrCode)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Deleted successfully"})
+}
+
+// GetQRCodeImage serves the rendered image of a saved QR code. The ETag is
+// the storage key, which changes whenever the design does.
+func (c *QRController) GetQRCodeImage(ctx *gin.Context) {
+	code, ok := c.findQRCode(ctx)
+	if !ok {
+		return
+	}
+	if isExpired(code.ExpireAt) {
+		ctx.JSON(http.StatusGone, gin.H{"error": "QR code has expired"})
+		return
+	}
+
+	etag := `"` + strings.TrimSuffix(path.Base(code.QrCode), path.Ext(code.QrCode)) + `"`
+	ctx.Header("Cache-Control", "private, max-age=86400")
+	ctx.Header("ETag", etag)
+	if ctx.GetHeader("If-None-Match") == etag {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	object, err := c.files.Get(ctx.Request.Context(), code.QrCode)
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "QR code image not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch QR code image"})
+		return
+	}
+	defer object.Body.Close()
+
+	contentType, extension, err := utils.QRFileType(code.Format)
+	if err != nil {
+		contentType, extension = object.ContentType, strings.TrimPrefix(path.Ext(code.QrCode), ".")
+	}
+	ctx.DataFromReader(http.StatusOK, object.Size, contentType, object.Body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("inline", map[string]string{
+			"filename": "qr-" + code.ShortCode + "." + extension,
+		}),
+	})
+}
+
+// render draws the design for the link with shortcode, checks that it
+// scans and stores the image under a new key. It responds itself on
+// failure.
+func (c *QRController) render(ctx *gin.Context, shortcode string, design models.QRDesign) (key string, scan models.QRScanReport, ok bool) {
+	opts, contentType, extension, ok := c.qrOptions(ctx, design)
+	if !ok {
+		return "", scan, false
+	}
+
+	image, report, err := utils.RenderCheckedQRCode(qrScanURL(shortcode), opts)
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to render QR code: " + err.Error()})
+		return "", scan, false
+	}
+	scan = models.QRScanReport(report)
+	if !scan.Readable {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "QR code would not scan: " + scan.Problem, "scan": scan})
+		return "", scan, false
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save QR code"})
+		return "", scan, false
+	}
+	key = "qr/" + hex.EncodeToString(id) + "." + extension
+	if err := c.files.Put(ctx, key, image, contentType); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save QR code"})
+		return "", scan, false
+	}
+	return key, scan, true
+}
+
+// qrOptions turns a design into render options, with the content type and
+// file extension of its format. It responds itself on failure.
+func (c *QRController) qrOptions(ctx *gin.Context, design models.QRDesign) (opts utils.QROptions, contentType, extension string, ok bool) {
+	fg, err := utils.ParseHexColor(design.FgColor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fg_color format"})
+		return opts, "", "", false
+	}
+	bg, err := utils.ParseHexColor(design.BgColor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bg_color format"})
+		return opts, "", "", false
+	}
+	if fg == bg {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "fg_color and bg_color cannot be the same"})
+		return opts, "", "", false
+	}
+	recovery, err := utils.ParseErrorCorrection(design.ErrorCorrection)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return opts, "", "", false
+	}
+	contentType, extension, err = utils.QRFileType(design.Format)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return opts, "", "", false
+	}
+	style, err := qrStyleOptions(design.Style)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return opts, "", "", false
+	}
+
+	opts = utils.QROptions{
+		Size:            design.Size,
+		Format:          design.Format,
+		Foreground:      fg,
+		Background:      bg,
+		LogoURL:         design.LogoURL,
+		ErrorCorrection: recovery,
+		QuietZone:       *design.QuietZone,
+		Style:           style,
+	}
+	if design.LogoID != nil {
+		if opts.Logo, ok = readQRLogo(ctx, c.store, c.files, *design.LogoID); !ok {
+			return opts, "", "", false
+		}
+	}
+	return opts, contentType, extension, true
+}
+
+func (c *QRController) findQRCode(ctx *gin.Context) (db.GetQRCodeRow, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid QR code id"})
+		return db.GetQRCodeRow{}, false
+	}
+
+	code, err := c.store.GetQRCode(ctx, db.GetQRCodeParams{
+		ID:     int32(id),
+		UserID: sql.NullInt32{Int32: int32(ctx.GetInt64("user_id")), Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "QR code not found"})
+		return code, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch QR code"})
+		return code, false
+	}
+	return code, true
+}
+
+func withQRDefaults(design models.QRDesign) models.QRDesign {
+	if design.FgColor == "" {
+		design.FgColor = "#000000"
+	}
+	if design.BgColor == "" {
+		design.BgColor = "#ffffff"
+	}
+	if design.Size == 0 {
+		design.Size = 512
+	}
+	if design.Format == "" {
+		design.Format = "png"
+	}
+	if design.ErrorCorrection == "" {
+		design.ErrorCorrection = "highest"
+	}
+	if design.QuietZone == nil {
+		quietZone := utils.DefaultQuietZone
+		design.QuietZone = &quietZone
+	}
+	design.Style.FrameText = strings.TrimSpace(design.Style.FrameText)
+	return design
+}
+
+// qrStyleOptions parses the colours of style for the renderer.
+func qrStyleOptions(style models.QRStyle) (utils.QRStyle, error) {
+	opts := utils.QRStyle{
+		Modules:     style.ModuleShape,
+		Eyes:        style.EyeShape,
+		Transparent: style.Transparent,
+		FrameText:   style.FrameText,
+	}
+	var err error
+	if style.EyeColor != "" {
+		if opts.EyeColor, err = utils.ParseHexColor(style.EyeColor); err != nil {
+			return opts, errors.New("Invalid eye_color format")
+		}
+	}
+	if style.FrameColor != "" {
+		if opts.FrameColor, err = utils.ParseHexColor(style.FrameColor); err != nil {
+			return opts, errors.New("Invalid frame_color format")
+		}
+	}
+	if style.Gradient != "" {
+		gradient := &utils.QRGradient{Type: style.Gradient, Angle: style.GradientAngle}
+		if gradient.From, err = utils.ParseHexColor(style.GradientStart); err != nil {
+			return opts, errors.New("Invalid gradient_start format")
+		}
+		if gradient.To, err = utils.ParseHexColor(style.GradientEnd); err != nil {
+			return opts, errors.New("Invalid gradient_end format")
+		}
+		opts.Gradient = gradient
+	}
+	return opts, opts.Validate()
+}
+
+func nullInt32(n *int32) sql.NullInt32 {
+	if n == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *n, Valid: true}
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// qrScanURL is the short link a QR code encodes. It goes through /q/ so
+// scans are counted apart from clicks on the /s/ link.
+func qrScanURL(shortcode string) string {
+	return configs.GetAPIURL() + "/q/" + shortcode
+}
+
+func qrDesignResponse(code db.GetQRCodeRow) models.QRDesignResponse {
+	response := models.QRDesignResponse{
+		ID:              code.ID,
+		Shortcode:       code.ShortCode,
+		ShortURL:        configs.GetAPIURL() + "/s/" + code.ShortCode,
+		ImageURL:        configs.GetAPIURL() + "/api/protected/qr-codes/" + strconv.Itoa(int(code.ID)) + "/image",
+		FgColor:         code.FgColor.String,
+		BgColor:         code.BgColor.String,
+		LogoURL:         code.LogoUrl.String,
+		LogoID:          code.LogoID.Int32,
+		Size:            code.Size,
+		Format:          code.Format,
+		ErrorCorrection: code.ErrorCorrection,
+		QuietZone:       code.QuietZone,
+		UpdatedAt:       code.UpdatedAt.Format(time.RFC3339),
+	}
+	_ = json.Unmarshal(code.Style, &response.Style)
+	if code.ExpireAt.Valid {
+		response.ExpireAt = code.ExpireAt.Time.Format(time.RFC3339)
+	}
+	if code.CreatedAt.Valid {
+		response.CreatedAt = code.CreatedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
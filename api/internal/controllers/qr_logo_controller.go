@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/configs"
+	"api/internal/db"
+	"api/internal/models"
+	"api/internal/storage"
+	"api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLogosPerUser caps the size of a user's logo library.
+const maxLogosPerUser = 20
+
+// UploadLogo adds a PNG, JPEG or SVG logo, sent as the multipart field
+// "file", to the caller's library.
+func (c *QRController) UploadLogo(ctx *gin.Context) {
+	userID := int32(ctx.GetInt64("user_id"))
+
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if header.Size > utils.MaxLogoUploadBytes {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Logo must be at most 2 MB"})
+		return
+	}
+
+	count, err := c.store.CountQRLogosByUser(ctx, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check logo library"})
+		return
+	}
+	if count >= maxLogosPerUser {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Logo library is full, delete a logo first"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, utils.MaxLogoUploadBytes+1))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	info, err := utils.InspectLogo(data)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(ctx.PostForm("name"))
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save logo"})
+		return
+	}
+	key := "logos/" + strconv.Itoa(int(userID)) + "/" + hex.EncodeToString(id) + "." + info.Extension
+	if err := c.files.Put(ctx, key, data, info.ContentType); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save logo"})
+		return
+	}
+
+	logo, err := c.store.CreateQRLogo(ctx, db.CreateQRLogoParams{
+		UserID:      userID,
+		Name:        name,
+		StorageKey:  key,
+		ContentType: info.ContentType,
+		Width:       int32(info.Width),
+		Height:      int32(info.Height),
+		SizeBytes:   int32(len(data)),
+	})
+	if err != nil {
+		_ = c.files.Delete(ctx, key)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save logo"})
+		return
+	}
+	ctx.JSON(http.StatusCreated, qrLogoResponse(logo))
+}
+
+func (c *QRController) ListLogos(ctx *gin.Context) {
+	logos, err := c.store.ListQRLogosByUser(ctx, int32(ctx.GetInt64("user_id")))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch logos"})
+		return
+	}
+
+	response := make([]models.QRLogoResponse, 0, len(logos))
+	for _, logo := range logos {
+		response = append(response, qrLogoResponse(logo))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetLogoImage serves the original upload. SVGs can carry scripts, so the
+// response is sandboxed.
+func (c *QRController) GetLogoImage(ctx *gin.Context) {
+	logo, ok := c.findLogo(ctx)
+	if !ok {
+		return
+	}
+
+	object, err := c.files.Get(ctx.Request.Context(), logo.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Logo not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch logo"})
+		return
+	}
+	defer object.Body.Close()
+
+	ctx.DataFromReader(http.StatusOK, object.Size, logo.ContentType, object.Body, map[string]string{
+		"Cache-Control":           "private, max-age=31536000, immutable",
+		"Content-Security-Policy": "default-src 'none'; style-src 'unsafe-inline'; sandbox",
+		"X-Content-Type-Options":  "nosniff",
+	})
+}
+
+// DeleteLogo removes a logo from the library. Saved QR codes using it keep
+// their rendered image.
+func (c *QRController) DeleteLogo(ctx *gin.Context) {
+	logo, ok := c.findLogo(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.store.DeleteQRLogo(ctx, db.DeleteQRLogoParams{ID: logo.ID, UserID: logo.UserID}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete logo"})
+		return
+	}
+	_ = c.files.Delete(ctx, logo.StorageKey)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Deleted successfully"})
+}
+
+func (c *QRController) findLogo(ctx *gin.Context) (db.QrLogo, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid logo id"})
+		return db.QrLogo{}, false
+	}
+	return findQRLogo(ctx, c.store, int32(id))
+}
+
+// findQRLogo looks up one of the caller's logos, responding itself when
+// there is none.
+func findQRLogo(ctx *gin.Context, store *db.Queries, id int32) (db.QrLogo, bool) {
+	logo, err := store.GetQRLogo(ctx, db.GetQRLogoParams{
+		ID:     id,
+		UserID: int32(ctx.GetInt64("user_id")),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Logo not found"})
+		return logo, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch logo"})
+		return logo, false
+	}
+	return logo, true
+}
+
+// readQRLogo returns the file of one of the caller's logos, responding
+// itself on failure. The logo library is a premium feature, so this is
+// checked here for every route that renders with a logo_id.
+func readQRLogo(ctx *gin.Context, store *db.Queries, files storage.Storage, id int32) ([]byte, bool) {
+	premium, err := isUserPremium(ctx, store, int32(ctx.GetInt64("user_id")))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not check premium status"})
+		return nil, false
+	}
+	if !premium {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "premium access required to use logo_id"})
+		return nil, false
+	}
+
+	logo, ok := findQRLogo(ctx, store, id)
+	if !ok {
+		return nil, false
+	}
+
+	object, err := files.Get(ctx.Request.Context(), logo.StorageKey)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch logo"})
+		return nil, false
+	}
+	defer object.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(object.Body, utils.MaxLogoUploadBytes))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch logo"})
+		return nil, false
+	}
+	return data, true
+}
+
+func qrLogoResponse(logo db.QrLogo) models.QRLogoResponse {
+	return models.QRLogoResponse{
+		ID:          logo.ID,
+		Name:        logo.Name,
+		ContentType: logo.ContentType,
+		Width:       logo.Width,
+		Height:      logo.Height,
+		SizeBytes:   logo.SizeBytes,
+		ImageURL:    configs.GetAPIURL() + "/api/protected/qr-logos/" + strconv.Itoa(int(logo.ID)) + "/image",
+		CreatedAt:   logo.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"api/configs"
+	"api/internal/db"
+	"api/internal/models"
+	"api/internal/utils"
+	"api/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePayloadQRCode renders a contact, Wi-Fi, email, SMS, phone or
+// location payload with a design. A dynamic code encodes a new short link
+// that serves the payload, named in the X-Short-URL header, so it can be
+// edited through UpdatePayload after printing.
+func (c *QRController) CreatePayloadQRCode(ctx *gin.Context) {
+	var req models.QRPayloadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	content, target, err := buildQRPayload(req.QRPayload)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Dynamic && target == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Wi-Fi codes cannot be dynamic, phones only join networks from the code itself"})
+		return
+	}
+
+	design := withQRDefaults(req.Design)
+	opts, contentType, extension, ok := c.qrOptions(ctx, design)
+	if !ok {
+		return
+	}
+
+	var shortcode string
+	if req.Dynamic {
+		shortcode, err = GetUniqueShortCode(ctx, c.store, 6, 10)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate unique shortcode"})
+			return
+		}
+		content = qrScanURL(shortcode)
+	}
+
+	image, report, err := utils.RenderCheckedQRCode(content, opts)
+	if errors.Is(err, utils.ErrTransparentJPEG) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Failed to render QR code: " + err.Error()})
+		return
+	}
+
+	// The link is only saved once its code is known to scan.
+	if req.Dynamic && report.Readable {
+		payload, _ := json.Marshal(req.QRPayload)
+		link, err := c.store.CreatePayloadLink(ctx, db.CreatePayloadLinkParams{
+			OriginalUrl: target,
+			ShortCode:   shortcode,
+			UserID:      sql.NullInt32{Int32: int32(ctx.GetInt64("user_id")), Valid: true},
+			PayloadType: sql.NullString{String: req.Type, Valid: true},
+			Payload:     payload,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create short URL"})
+			return
+		}
+		c.webhooks.Emit(ctx, link.UserID.Int32, webhook.EventLinkCreated, webhook.NewLink(link))
+		ctx.Header("X-Short-URL", configs.GetAPIURL()+"/s/"+link.ShortCode)
+	}
+
+	sendCheckedQRCode(ctx, image, report, contentType, "qr-"+req.Type+"."+extension)
+}
+
+// GetPayload returns the payload behind one of the caller's dynamic codes.
+func (c *QRController) GetPayload(ctx *gin.Context) {
+	link, ok := c.findPayloadLink(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, qrPayloadResponse(link))
+}
+
+// UpdatePayload replaces the payload behind a dynamic code. Printed codes
+// keep working, as they only hold the short link.
+func (c *QRController) UpdatePayload(ctx *gin.Context) {
+	link, ok := c.findPayloadLink(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.QRPayload
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+	_, target, err := buildQRPayload(req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if target == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Wi-Fi codes cannot be dynamic, phones only join networks from the code itself"})
+		return
+	}
+
+	payload, _ := json.Marshal(req)
+	updated, err := c.store.UpdateLinkPayload(ctx, db.UpdateLinkPayloadParams{
+		ID:          link.ID,
+		OriginalUrl: target,
+		PayloadType: sql.NullString{String: req.Type, Valid: true},
+		Payload:     payload,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payload"})
+		return
+	}
+	c.webhooks.Emit(ctx, updated.UserID.Int32, webhook.EventLinkUpdated, webhook.NewLink(updated))
+	ctx.JSON(http.StatusOK, qrPayloadResponse(updated))
+}
+
+func (c *QRController) findPayloadLink(ctx *gin.Context) (db.Url, bool) {
+	link, err := c.store.GetOriginalURL(ctx, ctx.Param("shortcode"))
+	if err == nil && (!link.PayloadType.Valid || int64(link.UserID.Int32) != ctx.GetInt64("user_id")) {
+		err = sql.ErrNoRows
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "QR payload not found"})
+		return link, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch QR payload"})
+		return link, false
+	}
+	return link, true
+}
+
+// buildQRPayload returns the content of a static code for p and what the
+// link of a dynamic one serves. target is empty when p cannot be dynamic.
+func buildQRPayload(p models.QRPayload) (content, target string, err error) {
+	switch p.Type {
+	case utils.PayloadVCard, utils.PayloadMeCard:
+		// A dynamic contact serves a vCard either way: it is the file
+		// format phones save.
+		contact := utils.Contact(*p.Contact)
+		target = utils.VCard(contact)
+		if p.Type == utils.PayloadMeCard {
+			return utils.MeCard(contact), target, nil
+		}
+		return target, target, nil
+	case utils.PayloadWiFi:
+		content, err = utils.WiFiPayload(utils.WiFiNetwork(*p.WiFi))
+		return content, "", err
+	case utils.PayloadEmail:
+		content = utils.MailtoPayload(utils.EmailMessage(*p.Email))
+	case utils.PayloadSMS:
+		content = utils.SMSPayload(utils.SMSMessage(*p.SMS))
+	case utils.PayloadTel:
+		content = utils.TelPayload(p.Tel.Phone)
+	case utils.PayloadGeo:
+		content = utils.GeoPayload(*p.Geo.Latitude, *p.Geo.Longitude)
+	default:
+		return "", "", fmt.Errorf("unknown payload type %q", p.Type)
+	}
+	return content, content, nil
+}
+
+func isContactPayload(url db.Url) bool {
+	return url.PayloadType.String == utils.PayloadVCard || url.PayloadType.String == utils.PayloadMeCard
+}
+
+func qrPayloadResponse(link db.Url) models.QRPayloadResponse {
+	response := models.QRPayloadResponse{
+		Shortcode: link.ShortCode,
+		ShortURL:  configs.GetAPIURL() + "/s/" + link.ShortCode,
+	}
+	_ = json.Unmarshal(link.Payload, &response.QRPayload)
+	return response
+}
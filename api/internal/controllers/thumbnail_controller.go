@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"api/internal/storage"
+	"api/internal/thumbnail"
+
+	"github.com/gin-gonic/gin"
+)
+
+var thumbnailName = regexp.MustCompile(`^[0-9a-f]{32}\.png$`)
+
+type ThumbnailController struct {
+	storage storage.Storage
+}
+
+func NewThumbnailController(store storage.Storage) *ThumbnailController {
+	return &ThumbnailController{storage: store}
+}
+
+// GetThumbnail serves a stored link thumbnail. Thumbnails are never
+// rewritten under the same name, so they can be cached for good.
+func (c *ThumbnailController) GetThumbnail(ctx *gin.Context) {
+	name := ctx.Param("name")
+	if !thumbnailName.MatchString(name) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+		return
+	}
+
+	object, err := c.storage.Get(ctx.Request.Context(), thumbnail.Prefix+name)
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch thumbnail"})
+		return
+	}
+	defer object.Body.Close()
+
+	headers := map[string]string{
+		"Cache-Control": "public, max-age=31536000, immutable",
+	}
+	if !object.LastModified.IsZero() {
+		headers["Last-Modified"] = object.LastModified.UTC().Format(http.TimeFormat)
+	}
+	ctx.DataFromReader(http.StatusOK, object.Size, object.ContentType, object.Body, headers)
+}
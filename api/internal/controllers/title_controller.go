@@ -1,66 +1,23 @@
 package controllers
 
 import (
-	"bytes"
-	"context"
-	"io"
 	"net/http"
-	"time"
 
-	"github.com/PuerkitoBio/goquery"
+	"api/internal/metadata"
+
 	"github.com/gin-gonic/gin"
 )
 
-type titleController struct{}
-
-func NewTitleController() *titleController {
-	return &titleController{}
-}
-
-type TitleResult struct {
-	Title string
-	URL   string
+type titleController struct {
+	fetcher *metadata.Fetcher
 }
 
-func getTitleFromURL(rawURL string) TitleResult {
-	// Set timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
-	if err != nil {
-		// Invalid URL
-		return TitleResult{Title: "", URL: rawURL}
-	}
-
-	client := http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		// Could be timeout or unreachable
-		return TitleResult{Title: "", URL: rawURL}
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return TitleResult{Title: "", URL: rawURL}
-	}
-
-	doc, err := goquery.NewDocumentFromReader(io.NopCloser(io.MultiReader(io.LimitReader(resp.Body, int64(len(body))))))
-	if err != nil {
-		return TitleResult{Title: "", URL: rawURL}
-	}
-
-	doc, err = goquery.NewDocumentFromReader(io.NopCloser(io.MultiReader(io.LimitReader(io.NopCloser(io.Reader(bytes.NewReader(body))), int64(len(body))))))
-	if err != nil {
-		return TitleResult{Title: "", URL: rawURL}
-	}
-
-	title := doc.Find("title").First().Text()
-
-	return TitleResult{Title: title, URL: resp.Request.URL.String()}
+func NewTitleController(fetcher *metadata.Fetcher) *titleController {
+	return &titleController{fetcher: fetcher}
 }
 
+// GetPageTitle returns the metadata of the page at the url query parameter:
+// title, description, canonical URL, image, favicon, site name and language.
 func (c *titleController) GetPageTitle(ctx *gin.Context) {
 	rawURL := ctx.Query("url")
 	if rawURL == "" {
@@ -68,14 +25,11 @@ func (c *titleController) GetPageTitle(ctx *gin.Context) {
 		return
 	}
 
-	result := getTitleFromURL(rawURL)
-	if result.Title == "" {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch title"})
+	meta, err := c.fetcher.Fetch(ctx.Request.Context(), rawURL)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch page metadata"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"title": result.Title,
-		"url":   result.URL,
-	})
+	ctx.JSON(http.StatusOK, meta)
 }
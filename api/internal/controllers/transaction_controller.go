@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"database/sql"
@@ -221,7 +222,13 @@ func generateRazorpaySignature(payload []byte, secret string) string {
 }
 
 func (t *TransactionController) IsUserPremium(ctx *gin.Context, userID int32) (bool, error) {
-	transactions, err := t.store.GetUserTransactionsByStatus(ctx, db.GetUserTransactionsByStatusParams{
+	return isUserPremium(ctx, t.store, userID)
+}
+
+// isUserPremium reports whether userID has a lifetime plan or a monthly
+// plan bought within the last month.
+func isUserPremium(ctx context.Context, store *db.Queries, userID int32) (bool, error) {
+	transactions, err := store.GetUserTransactionsByStatus(ctx, db.GetUserTransactionsByStatusParams{
 		UserID: userID,
 		Status: "success",
 	})
@@ -5,24 +5,58 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"api/configs"
 	"api/internal/db"
+	"api/internal/events"
+	"api/internal/health"
+	"api/internal/media"
+	"api/internal/metadata"
 	"api/internal/models"
+	"api/internal/safety"
+	"api/internal/storage"
+	"api/internal/thumbnail"
 	"api/internal/utils"
+	"api/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 )
 
 type URLController struct {
-	store *db.Queries
+	store      *db.Queries
+	hub        *events.Hub
+	webhooks   *webhook.Dispatcher
+	scanner    *safety.Scanner
+	fetcher    *metadata.Fetcher
+	thumbnails *thumbnail.Service
+	files      storage.Storage
 }
 
-func NewURLController(store *db.Queries) *URLController {
-	return &URLController{store: store}
+func NewURLController(store *db.Queries, hub *events.Hub, webhooks *webhook.Dispatcher, scanner *safety.Scanner, fetcher *metadata.Fetcher, thumbnails *thumbnail.Service, files storage.Storage) *URLController {
+	return &URLController{store: store, hub: hub, webhooks: webhooks, scanner: scanner, fetcher: fetcher, thumbnails: thumbnails, files: files}
+}
+
+// screenDestinations scans the destination and backup URL of a link being
+// saved. It rejects malicious ones itself and reports false; otherwise it
+// returns the verdict to store on the link.
+func (c *URLController) screenDestinations(ctx *gin.Context, originalURL, backupURL string) (safety.Result, bool) {
+	result := c.scanner.ScanAll(ctx, originalURL, backupURL)
+	if result.Verdict == safety.VerdictMalicious {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Destination URL was flagged as malicious",
+			"reason": result.Reason,
+		})
+		return result, false
+	}
+	return result, true
 }
 
 func (c *URLController) CreateShortURL(ctx *gin.Context) {
@@ -45,7 +79,6 @@ func (c *URLController) CreateShortURL(ctx *gin.Context) {
 	} else {
 		var err error
 		code, err = GetUniqueShortCode(ctx, c.store, 6, 10)
-		println("Generated shortcode:", code)
 		if err != nil {
 			ctx.JSON(500, gin.H{"error": "Failed to generate unique shortcode"})
 			return
@@ -78,38 +111,97 @@ func (c *URLController) CreateShortURL(ctx *gin.Context) {
 		}
 		expireAt = sql.NullTime{Time: *req.ExpireAt, Valid: true}
 	}
-	domain, err := utils.GetDomain(req.OriginalURL)
-	if err != nil {
+	if _, err := utils.GetDomain(req.OriginalURL); err != nil {
 		ctx.JSON(400, gin.H{"error": "Invalid original URL"})
 		return
 	}
-	thumbnail := "https://www.google.com/s2/favicons?sz=128&domain_url=" + domain
+
+	screen, ok := c.screenDestinations(ctx, req.OriginalURL, req.BackupURL)
+	if !ok {
+		return
+	}
+
+	var ogImage string
+	if req.OGImage != "" {
+		var err error
+		ogImage, err = media.SaveDataURL("cards", req.OGImage)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "Invalid og_image: " + err.Error()})
+			return
+		}
+	}
 
 	arg := db.CreateShortURLParams{
-		OriginalUrl:  req.OriginalURL,
-		ShortCode:    code,
-		Title:        sql.NullString{String: req.Title, Valid: req.Title != ""},
-		PasswordHash: passwordHash,
-		ExpireAt:     expireAt,
-		UserID:       sql.NullInt32{Int32: int32(userID.(int64)), Valid: true},
-		Thumbnail:    sql.NullString{String: thumbnail, Valid: true},
+		OriginalUrl:   req.OriginalURL,
+		ShortCode:     code,
+		Title:         sql.NullString{String: req.Title, Valid: req.Title != ""},
+		PasswordHash:  passwordHash,
+		ExpireAt:      expireAt,
+		UserID:        sql.NullInt32{Int32: int32(userID.(int64)), Valid: true},
+		BackupUrl:     sql.NullString{String: req.BackupURL, Valid: req.BackupURL != ""},
+		SafetyStatus:  sql.NullString{String: screen.Verdict, Valid: true},
+		SafetyReason:  sql.NullString{String: screen.Reason, Valid: screen.Reason != ""},
+		AlwaysPreview: req.AlwaysPreview,
+		OgTitle:       sql.NullString{String: req.OGTitle, Valid: req.OGTitle != ""},
+		OgDescription: sql.NullString{String: req.OGDescription, Valid: req.OGDescription != ""},
+		OgImage:       sql.NullString{String: ogImage, Valid: ogImage != ""},
 	}
 
 	url, err := c.store.CreateShortURL(ctx, arg)
 	if err != nil {
+		_ = media.Remove(ogImage)
 		ctx.JSON(500, gin.H{"error": "Failed to create short URL"})
 		return
 
 	}
+	c.webhooks.Emit(ctx, url.UserID.Int32, webhook.EventLinkCreated, webhook.NewLink(url))
+	go c.completeLink(url)
+
 	formatted := time.Now().Format("02 Jan 2006")
 
 	ctx.JSON(200, models.ShortURLResponse{
 		ShortURL:  configs.GetAPIURL() + "/s/" + url.ShortCode,
-		Thumbnail: utils.GetString(url.Thumbnail),
+		Thumbnail: thumbnail.URL(url.Thumbnail.String),
 		Format:    formatted,
 	})
 }
 
+// completeLink gives a new link the page's title when it was created
+// without one, and a thumbnail. It runs after the link is saved so
+// creating it never waits on the destination; a thumbnail it can't make is
+// left to the thumbnail worker.
+func (c *URLController) completeLink(url db.Url) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if !url.Title.Valid {
+		if meta, err := c.fetcher.Fetch(ctx, url.OriginalUrl); err == nil && meta.Title != "" {
+			if err := c.store.SetMissingURLTitle(ctx, db.SetMissingURLTitleParams{
+				ID:    url.ID,
+				Title: sql.NullString{String: meta.Title, Valid: true},
+			}); err != nil {
+				log.Printf("failed to set title of %s: %v", url.ShortCode, err)
+			}
+		}
+	}
+
+	thumb, err := c.thumbnails.Generate(ctx, url.OriginalUrl)
+	if err != nil {
+		return
+	}
+	// The thumbnail worker may have got there first.
+	rows, err := c.store.SetNewURLThumbnail(ctx, db.SetNewURLThumbnailParams{
+		ID:        url.ID,
+		Thumbnail: sql.NullString{String: thumb, Valid: true},
+	})
+	if err != nil {
+		log.Printf("failed to set thumbnail of %s: %v", url.ShortCode, err)
+	}
+	if err != nil || rows == 0 {
+		_ = c.thumbnails.Remove(ctx, thumb)
+	}
+}
+
 func (c *URLController) GetUserURLs(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
@@ -127,23 +219,90 @@ func (c *URLController) GetUserURLs(ctx *gin.Context) {
 
 	for _, link := range links {
 		var password bool = link.PasswordHash.String != ""
+		var checkedAt string
+		if link.HealthCheckedAt.Valid {
+			checkedAt = link.HealthCheckedAt.Time.Format(time.RFC3339)
+		}
 		result = append(result, models.LinkResponse{
 			ID:          int64(link.ID),
 			Title:       utils.NullToStr(link.Title),
 			OriginalURL: link.OriginalUrl,
 			ShortURL:    configs.GetAPIURL() + "/s/" + link.ShortCode,
-			Thumbnail:   utils.NullToStr(link.Thumbnail),
+			Thumbnail:   thumbnail.URL(link.Thumbnail.String),
 			Clicks:      int(link.ClickCount),
 			CreatedAt:   utils.FormatNullTime(link.CreatedAt),
 			ExpireAt:    utils.FormatNullTime(link.ExpireAt),
 			IsExpired:   isExpired(link.ExpireAt),
 			Password:    password,
+			Health:      utils.NullToStr(link.HealthStatus),
+			CheckedAt:   checkedAt,
+			BackupURL:   utils.NullToStr(link.BackupUrl),
+			Failover:    health.FailingOver(link),
+			Safety:      utils.NullToStr(link.SafetyStatus),
+			SafetyNote:  utils.NullToStr(link.SafetyReason),
+			Preview:     link.AlwaysPreview,
+			Previews:    int(link.PreviewCount),
+			OGTitle:     utils.NullToStr(link.OgTitle),
+			OGDesc:      utils.NullToStr(link.OgDescription),
+			OGImage:     ogImageURL(link),
+			PayloadType: utils.NullToStr(link.PayloadType),
 		})
 	}
 
 	ctx.JSON(200, result)
 }
 
+// GetLinkHealth returns the latest destination checks of one of the
+// caller's links, newest first.
+func (c *URLController) GetLinkHealth(ctx *gin.Context) {
+	checks, err := c.store.ListLinkHealthChecks(ctx, db.ListLinkHealthChecksParams{
+		ShortCode: ctx.Param("shortcode"),
+		UserID:    sql.NullInt32{Int32: int32(ctx.GetInt64("user_id")), Valid: true},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch link health"})
+		return
+	}
+
+	response := make([]models.LinkHealthCheckResponse, 0, len(checks))
+	for _, check := range checks {
+		response = append(response, models.LinkHealthCheckResponse{
+			Status:        check.Status,
+			StatusCode:    check.StatusCode.Int32,
+			Method:        check.Method,
+			LatencyMs:     check.LatencyMs,
+			RedirectChain: check.RedirectChain,
+			FinalURL:      utils.NullToStr(check.FinalUrl),
+			Error:         utils.NullToStr(check.Error),
+			CheckedAt:     check.CheckedAt.Format(time.RFC3339),
+		})
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetLinkFailovers returns the periods during which one of the caller's
+// links redirected to its backup URL, newest first.
+func (c *URLController) GetLinkFailovers(ctx *gin.Context) {
+	failovers, err := c.store.ListLinkFailovers(ctx, db.ListLinkFailoversParams{
+		ShortCode: ctx.Param("shortcode"),
+		UserID:    sql.NullInt32{Int32: int32(ctx.GetInt64("user_id")), Valid: true},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch failovers"})
+		return
+	}
+
+	response := make([]models.LinkFailoverResponse, 0, len(failovers))
+	for _, failover := range failovers {
+		item := models.LinkFailoverResponse{StartedAt: failover.StartedAt.Format(time.RFC3339)}
+		if failover.EndedAt.Valid {
+			item.EndedAt = failover.EndedAt.Time.Format(time.RFC3339)
+		}
+		response = append(response, item)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
 func isExpired(expireAt sql.NullTime) bool {
 	if !expireAt.Valid {
 		return false
@@ -152,44 +311,61 @@ func isExpired(expireAt sql.NullTime) bool {
 }
 
 func (c *URLController) RedirectToOriginalURL(ctx *gin.Context) {
+	c.redirect(ctx, utils.VisitSourceLink)
+}
+
+// RedirectQRScan serves the /q/ URL that QR codes encode, so that scans are
+// counted apart from clicks on the shared link.
+func (c *URLController) RedirectQRScan(ctx *gin.Context) {
+	c.redirect(ctx, utils.VisitSourceQR)
+}
+
+func (c *URLController) redirect(ctx *gin.Context, source string) {
 	shortcode := ctx.Param("shortcode")
 	password := ctx.Query("password")
 
-	url, err := c.store.GetOriginalURL(ctx, shortcode)
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+	// A trailing + asks for the preview page instead of the redirect.
+	if code, ok := strings.CutSuffix(shortcode, "+"); ok {
+		c.previewLink(ctx, code, source)
 		return
 	}
 
-	if url.ExpireAt.Valid && url.ExpireAt.Time.Before(time.Now()) {
-		ctx.JSON(http.StatusGone, gin.H{"error": "This short URL has expired"})
+	url, ok := c.findActiveLink(ctx, shortcode)
+	if !ok {
 		return
 	}
 
-	ipAddress := utils.GetIP(ctx)
-	userAgent := ctx.Request.UserAgent()
-	referrer := ctx.Request.Referer()
-	deviceType := utils.DetectDeviceTypeUA(userAgent)
-	urlID := int32(url.ID)
-
-	go func(shortCode string, urlID int32, deviceType, ipAddress, userAgent, referrer string) {
-		_ = c.store.IncrementClickCount(context.Background(), shortCode)
-
-		country, region, city := utils.ResolveGeoLocation(ipAddress)
-
-		_ = c.store.LogURLVisit(context.Background(), db.LogURLVisitParams{
-			UrlID:      urlID,
-			IpAddress:  sql.NullString{String: ipAddress, Valid: ipAddress != ""},
-			UserAgent:  sql.NullString{String: userAgent, Valid: userAgent != ""},
-			DeviceType: sql.NullString{String: deviceType, Valid: deviceType != ""},
-			Referrer:   sql.NullString{String: referrer, Valid: referrer != ""},
-			Country:    sql.NullString{String: country, Valid: country != ""},
-			Region:     sql.NullString{String: region, Valid: region != ""},
-			City:       sql.NullString{String: city, Valid: city != ""},
+	target, destination := health.Destination(url)
+
+	// proceed is set by the continue links of the warning and preview pages.
+	proceed := ctx.Query("proceed") != ""
+	switch {
+	case url.SafetyStatus.String == safety.VerdictMalicious:
+		ctx.HTML(http.StatusForbidden, "blocked.html", gin.H{
+			"PageTitle": "Link blocked",
+			"Reason":    url.SafetyReason.String,
+		})
+		return
+	case hasShareCard(url) && utils.IsUnfurler(ctx.Request.UserAgent()):
+		c.renderShareCard(ctx, url)
+		return
+	case proceed:
+	case url.SafetyStatus.String == safety.VerdictSuspicious:
+		ctx.HTML(http.StatusOK, "warning.html", gin.H{
+			"PageTitle":   "This link may be unsafe",
+			"Reason":      url.SafetyReason.String,
+			"Destination": target,
+			"ContinueURL": continueURL(ctx, url, source),
 		})
-	}(shortcode, urlID, deviceType, ipAddress, userAgent, referrer)
+		return
+	case url.AlwaysPreview && !isProtected(url):
+		c.renderPreview(ctx, url, source, previewCountdown)
+		return
+	}
+
+	go c.recordVisit(url, newVisit(ctx, url, destination, source))
 
-	if url.PasswordHash.Valid && url.PasswordHash.String != "" {
+	if isProtected(url) {
 		if password == "" {
 
 			ctx.Redirect(http.StatusTemporaryRedirect, "https://uhxnpmnnw4r7.share.zrok.io/redirect/"+shortcode)
@@ -202,7 +378,197 @@ func (c *URLController) RedirectToOriginalURL(ctx *gin.Context) {
 		}
 	}
 
-	ctx.Redirect(http.StatusFound, url.OriginalUrl)
+	// Contact links serve the vCard itself, which phones offer to save.
+	if isContactPayload(url) {
+		ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": url.ShortCode + ".vcf"}))
+		ctx.Data(http.StatusOK, "text/vcard; charset=utf-8", []byte(target))
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, target)
+}
+
+// previewCountdown is how many seconds links with always_preview show their
+// preview page before forwarding.
+const previewCountdown = 5
+
+// PreviewShortURL shows where a short link leads without redirecting.
+func (c *URLController) PreviewShortURL(ctx *gin.Context) {
+	c.previewLink(ctx, ctx.Param("shortcode"), utils.VisitSourceLink)
+}
+
+func (c *URLController) previewLink(ctx *gin.Context, shortcode, source string) {
+	url, ok := c.findActiveLink(ctx, shortcode)
+	if !ok {
+		return
+	}
+	c.renderPreview(ctx, url, source, 0)
+}
+
+// findActiveLink looks up a short link for a visitor, writing the error
+// response itself when it is missing or expired.
+func (c *URLController) findActiveLink(ctx *gin.Context, shortcode string) (db.Url, bool) {
+	url, err := c.store.GetOriginalURL(ctx, shortcode)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Short URL not found"})
+		return url, false
+	}
+
+	if url.ExpireAt.Valid && url.ExpireAt.Time.Before(time.Now()) {
+		ctx.JSON(http.StatusGone, gin.H{"error": "This short URL has expired"})
+		return url, false
+	}
+	return url, true
+}
+
+// renderPreview logs a preview view and renders the preview page. With a
+// countdown the page forwards to the destination after that many seconds.
+// The destination of password protected links is not shown.
+func (c *URLController) renderPreview(ctx *gin.Context, url db.Url, source string, countdown int) {
+	ipAddress := utils.GetIP(ctx)
+	userAgent := ctx.Request.UserAgent()
+	referrer := ctx.Request.Referer()
+	go func() {
+		if err := c.store.LogPreviewView(context.Background(), db.LogPreviewViewParams{
+			UrlID:     url.ID,
+			IpAddress: sql.NullString{String: ipAddress, Valid: ipAddress != ""},
+			UserAgent: sql.NullString{String: userAgent, Valid: userAgent != ""},
+			Referrer:  sql.NullString{String: referrer, Valid: referrer != ""},
+		}); err != nil {
+			log.Printf("failed to log preview for %s: %v", url.ShortCode, err)
+		}
+	}()
+
+	target, _ := health.Destination(url)
+	verdict := url.SafetyStatus.String
+	next := continueURL(ctx, url, source)
+	data := gin.H{
+		"PageTitle":   "Preview of " + configs.GetAPIURL() + "/s/" + url.ShortCode,
+		"Title":       url.Title.String,
+		"ShortURL":    configs.GetAPIURL() + "/s/" + url.ShortCode,
+		"Destination": target,
+		"Protected":   isProtected(url),
+		"Favicon":     thumbnail.URL(url.Thumbnail.String),
+		"CreatedAt":   utils.FormatNullTime(url.CreatedAt),
+		"Safety":      verdict,
+		"SafetyLabel": safetyLabel(verdict),
+		"Continue":    verdict != safety.VerdictMalicious,
+		"ContinueURL": next,
+		"Countdown":   countdown,
+	}
+	if countdown > 0 {
+		data["Refresh"] = fmt.Sprintf("%d;url=%s", countdown, next)
+	}
+	ctx.HTML(http.StatusOK, "preview.html", data)
+}
+
+func hasShareCard(url db.Url) bool {
+	return url.OgTitle.Valid || url.OgDescription.Valid || url.OgImage.Valid
+}
+
+// renderShareCard serves link preview crawlers a page carrying the link's
+// Open Graph and Twitter card overrides instead of redirecting them, so
+// chat apps unfurl the card rather than the destination. These requests
+// are not counted as visits.
+func (c *URLController) renderShareCard(ctx *gin.Context, url db.Url) {
+	title := url.OgTitle.String
+	if title == "" {
+		title = url.Title.String
+	}
+	ctx.HTML(http.StatusOK, "card.html", gin.H{
+		"Title":       title,
+		"Description": url.OgDescription.String,
+		"Image":       ogImageURL(url),
+		"ShortURL":    configs.GetAPIURL() + "/s/" + url.ShortCode,
+	})
+}
+
+func ogImageURL(url db.Url) string {
+	if !url.OgImage.Valid {
+		return ""
+	}
+	return media.URL(url.OgImage.String)
+}
+
+// continueURL is the short link the visitor came through with proceed set,
+// keeping their other query parameters.
+func continueURL(ctx *gin.Context, url db.Url, source string) string {
+	query := ctx.Request.URL.Query()
+	query.Set("proceed", "1")
+	path := "/s/"
+	if source == utils.VisitSourceQR {
+		path = "/q/"
+	}
+	return path + url.ShortCode + "?" + query.Encode()
+}
+
+func isProtected(url db.Url) bool {
+	return url.PasswordHash.Valid && url.PasswordHash.String != ""
+}
+
+func safetyLabel(verdict string) string {
+	switch verdict {
+	case safety.VerdictSafe:
+		return "No threats found"
+	case safety.VerdictSuspicious:
+		return "Flagged as suspicious"
+	case safety.VerdictMalicious:
+		return "Blocked as malicious"
+	}
+	return "Not checked yet"
+}
+
+// newVisit describes the request as a visit to url through source.
+func newVisit(ctx *gin.Context, url db.Url, destination, source string) db.LogURLVisitParams {
+	ipAddress := utils.GetIP(ctx)
+	userAgent := ctx.Request.UserAgent()
+	referrer := ctx.Request.Referer()
+	referrerDomain, referrerSource := utils.ClassifyReferrer(referrer, ctx.Query("utm_source"), ctx.Query("utm_medium"))
+	deviceType := utils.DetectDeviceTypeUA(userAgent)
+
+	return db.LogURLVisitParams{
+		UrlID:          int32(url.ID),
+		IpAddress:      sql.NullString{String: ipAddress, Valid: ipAddress != ""},
+		UserAgent:      sql.NullString{String: userAgent, Valid: userAgent != ""},
+		DeviceType:     sql.NullString{String: deviceType, Valid: deviceType != ""},
+		Referrer:       sql.NullString{String: referrer, Valid: referrer != ""},
+		ReferrerDomain: sql.NullString{String: referrerDomain, Valid: referrerDomain != ""},
+		ReferrerSource: sql.NullString{String: referrerSource, Valid: referrerSource != ""},
+		Destination:    sql.NullString{String: destination, Valid: true},
+		Source:         source,
+	}
+}
+
+// recordVisit resolves the visitor location, stores the visit and pushes it
+// to the owner's live dashboard streams and link.clicked webhooks. It runs
+// off the request goroutine.
+func (c *URLController) recordVisit(url db.Url, visit db.LogURLVisitParams) {
+	_ = c.store.IncrementClickCount(context.Background(), url.ShortCode)
+
+	country, region, city := utils.ResolveGeoLocation(visit.IpAddress.String)
+	visit.Country = sql.NullString{String: country, Valid: country != ""}
+	visit.Region = sql.NullString{String: region, Valid: region != ""}
+	visit.City = sql.NullString{String: city, Valid: city != ""}
+
+	if err := c.store.LogURLVisit(context.Background(), visit); err != nil {
+		log.Printf("failed to log visit for %s: %v", url.ShortCode, err)
+		return
+	}
+
+	if url.UserID.Valid {
+		event := events.Visit{
+			UserID:         url.UserID.Int32,
+			Shortcode:      url.ShortCode,
+			Country:        country,
+			Device:         visit.DeviceType.String,
+			Referrer:       visit.Referrer.String,
+			ReferrerSource: visit.ReferrerSource.String,
+			Source:         visit.Source,
+			ClickedAt:      time.Now(),
+		}
+		c.hub.Publish(event)
+		c.webhooks.Emit(context.Background(), url.UserID.Int32, webhook.EventLinkClicked, event)
+	}
 }
 
 func (c *URLController) VerifyAndRedirect(ctx *gin.Context) {
@@ -213,6 +579,7 @@ func (c *URLController) VerifyAndRedirect(ctx *gin.Context) {
 	}
 	var response struct {
 		OriginalURL string `json:"original_url"`
+		Warning     string `json:"warning,omitempty"`
 	}
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
@@ -230,8 +597,63 @@ func (c *URLController) VerifyAndRedirect(ctx *gin.Context) {
 		return
 	}
 
-	response.OriginalURL = url.OriginalUrl
+	switch url.SafetyStatus.String {
+	case safety.VerdictMalicious:
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "This link has been blocked"})
+		return
+	case safety.VerdictSuspicious:
+		response.Warning = "This link was flagged as suspicious"
+		if url.SafetyReason.Valid {
+			response.Warning += ": " + url.SafetyReason.String
+		}
+	}
+
+	response.OriginalURL, _ = health.Destination(url)
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// ResolveShortURL returns a short link's destination as JSON for API
+// clients, counting the lookup as a visit with source api. Protected links
+// take the password as a query parameter, as the redirect does.
+func (c *URLController) ResolveShortURL(ctx *gin.Context) {
+	var response struct {
+		OriginalURL string `json:"original_url"`
+		Warning     string `json:"warning,omitempty"`
+	}
+
+	url, ok := c.findActiveLink(ctx, ctx.Param("shortcode"))
+	if !ok {
+		return
+	}
+
+	if isProtected(url) {
+		password := ctx.Query("password")
+		if password == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Password required"})
+			return
+		}
+		if !utils.CheckPasswordHash(password, url.PasswordHash.String) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Invalid password"})
+			return
+		}
+	}
 
+	switch url.SafetyStatus.String {
+	case safety.VerdictMalicious:
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "This link has been blocked"})
+		return
+	case safety.VerdictSuspicious:
+		response.Warning = "This link was flagged as suspicious"
+		if url.SafetyReason.Valid {
+			response.Warning += ": " + url.SafetyReason.String
+		}
+	}
+
+	target, destination := health.Destination(url)
+	go c.recordVisit(url, newVisit(ctx, url, destination, utils.VisitSourceAPI))
+
+	response.OriginalURL = target
 	ctx.JSON(http.StatusOK, response)
 }
 
@@ -243,11 +665,20 @@ func (c *URLController) DeleteShortURL(ctx *gin.Context) {
 		return
 	}
 
-	err := c.store.DeleteURLByShortCode(ctx, shortCode)
+	url, err := c.store.GetOriginalURL(ctx, shortCode)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(500, gin.H{"error": "Failed to delete URL"})
+		return
+	}
+
+	err = c.store.DeleteURLByShortCode(ctx, shortCode)
 	if err != nil {
 		ctx.JSON(500, gin.H{"error": "Failed to delete URL"})
 		return
 	}
+	if url.UserID.Valid {
+		c.webhooks.Emit(ctx, url.UserID.Int32, webhook.EventLinkDeleted, webhook.NewLink(url))
+	}
 
 	ctx.JSON(200, gin.H{"message": "Deleted successfully"})
 }
@@ -301,20 +732,80 @@ func (c *URLController) UpdateShortURL(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
 		return
 	}
+	current, err := c.store.GetOriginalURL(ctx, shortcode)
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "short URL not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update URL"})
+		return
+	}
+	if current.PayloadType.Valid {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "this link holds a QR payload, edit it through /qr-payloads"})
+		return
+	}
+	backupURL := current.BackupUrl.String
+	if req.BackupURL != nil {
+		backupURL = *req.BackupURL
+	}
+	screen, ok := c.screenDestinations(ctx, req.OriginalURL, backupURL)
+	if !ok {
+		return
+	}
+	alwaysPreview := current.AlwaysPreview
+	if req.AlwaysPreview != nil {
+		alwaysPreview = *req.AlwaysPreview
+	}
+	ogTitle := current.OgTitle.String
+	if req.OGTitle != nil {
+		ogTitle = *req.OGTitle
+	}
+	ogDescription := current.OgDescription.String
+	if req.OGDescription != nil {
+		ogDescription = *req.OGDescription
+	}
+	ogImage := current.OgImage.String
+	if req.OGImage != nil {
+		ogImage = ""
+		if *req.OGImage != "" {
+			ogImage, err = media.SaveDataURL("cards", *req.OGImage)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid og_image: " + err.Error()})
+				return
+			}
+		}
+	}
 
 	args := db.UpdateShortURLParams{
-		ShortCode:    shortcode,
-		OriginalUrl:  req.OriginalURL,
-		Title:        sql.NullString{String: req.Title, Valid: true},
-		ExpireAt:     expireAt,
-		PasswordHash: sql.NullString{String: password, Valid: req.Password != ""},
+		ShortCode:     shortcode,
+		OriginalUrl:   req.OriginalURL,
+		Title:         sql.NullString{String: req.Title, Valid: true},
+		ExpireAt:      expireAt,
+		PasswordHash:  sql.NullString{String: password, Valid: req.Password != ""},
+		BackupUrl:     sql.NullString{String: backupURL, Valid: backupURL != ""},
+		SafetyStatus:  sql.NullString{String: screen.Verdict, Valid: true},
+		SafetyReason:  sql.NullString{String: screen.Reason, Valid: screen.Reason != ""},
+		AlwaysPreview: alwaysPreview,
+		OgTitle:       sql.NullString{String: ogTitle, Valid: ogTitle != ""},
+		OgDescription: sql.NullString{String: ogDescription, Valid: ogDescription != ""},
+		OgImage:       sql.NullString{String: ogImage, Valid: ogImage != ""},
 	}
 
 	updated, err := c.store.UpdateShortURL(ctx, args)
 	if err != nil {
+		if ogImage != current.OgImage.String {
+			_ = media.Remove(ogImage)
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update URL"})
 		return
 	}
+	if ogImage != current.OgImage.String {
+		_ = media.Remove(current.OgImage.String)
+	}
+	if updated.UserID.Valid {
+		c.webhooks.Emit(ctx, updated.UserID.Int32, webhook.EventLinkUpdated, webhook.NewLink(updated))
+	}
 
 	response := models.LinkResponse{
 		ID:          int64(updated.ID),
@@ -323,8 +814,16 @@ func (c *URLController) UpdateShortURL(ctx *gin.Context) {
 		ShortURL:    configs.GetAPIURL() + "/s/" + updated.ShortCode,
 		Clicks:      int(updated.ClickCount),
 		CreatedAt:   utils.FormatNullTime(updated.CreatedAt),
-		Thumbnail:   utils.NullToStr(updated.Thumbnail),
+		Thumbnail:   thumbnail.URL(updated.Thumbnail.String),
 		ExpireAt:    utils.FormatNullTime(updated.ExpireAt),
+		BackupURL:   utils.NullToStr(updated.BackupUrl),
+		Safety:      utils.NullToStr(updated.SafetyStatus),
+		SafetyNote:  utils.NullToStr(updated.SafetyReason),
+		Preview:     updated.AlwaysPreview,
+		Previews:    int(updated.PreviewCount),
+		OGTitle:     utils.NullToStr(updated.OgTitle),
+		OGDesc:      utils.NullToStr(updated.OgDescription),
+		OGImage:     ogImageURL(updated),
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
@@ -340,16 +839,32 @@ func (c *URLController) GetQRCode(ctx *gin.Context) {
 		ctx.JSON(400, gin.H{"error": "Shortcode is required"})
 		return
 	}
-	shortURL := configs.GetAPIURL() + "/s/" + shortcode
+	shortURL := qrScanURL(shortcode)
 
-	qrCode, err := utils.GenerateQRCode(shortURL, 256)
+	format := ctx.DefaultQuery("format", "png")
+	contentType, extension, err := utils.QRFileType(format)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := utils.QROptions{
+		Size:       256,
+		Format:     format,
+		Foreground: color.Black,
+		Background: color.White,
+	}
+	if !bindQRRenderQuery(ctx, &opts) {
+		return
+	}
+
+	qrCode, report, err := utils.RenderCheckedQRCode(shortURL, opts)
 	if err != nil {
 		ctx.JSON(500, gin.H{"error": "Failed to generate QR code"})
 		return
 	}
 
-	ctx.Header("Content-Type", "image/png")
-	ctx.Writer.Write(qrCode)
+	sendCheckedQRCode(ctx, qrCode, report, contentType, "qr-"+shortcode+"."+extension)
 }
 
 func (c *URLController) FetchQRCodeWithLogo(ctx *gin.Context) {
@@ -357,13 +872,10 @@ func (c *URLController) FetchQRCodeWithLogo(ctx *gin.Context) {
 	fg_color := ctx.DefaultQuery("fg_color", "#000000")
 	bg_color := ctx.DefaultQuery("bg_color", "#ffffff")
 	logo_url := ctx.Query("logo_url")
-	format := ctx.Query("format")
-	// check format for png or jpeg
-	if format == "" {
-		format = "png"
-	}
-	if format != "png" && format != "jpeg" {
-		ctx.JSON(400, gin.H{"error": "Unsupported format. Use png or jpeg"})
+	format := ctx.DefaultQuery("format", "png")
+	contentType, extension, err := utils.QRFileType(format)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -391,19 +903,105 @@ func (c *URLController) FetchQRCodeWithLogo(ctx *gin.Context) {
 		return
 	}
 
-	qrBytes, err := utils.GenerateQRCodeWithLogos(rawURL, logo_url, 512, "png", fgColor, bgColor)
+	var styleQuery models.QRStyle
+	if err := ctx.ShouldBindQuery(&styleQuery); err != nil {
+		ctx.Error(err)
+		return
+	}
+	styleQuery.FrameText = strings.TrimSpace(styleQuery.FrameText)
+	style, err := qrStyleOptions(styleQuery)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := utils.QROptions{
+		Size:       512,
+		Format:     format,
+		Foreground: fgColor,
+		Background: bgColor,
+		LogoURL:    logo_url,
+		Style:      style,
+	}
+	if !bindQRRenderQuery(ctx, &opts) {
+		return
+	}
+	// logo_id picks a logo from the caller's library instead of logo_url.
+	if logoID := ctx.Query("logo_id"); logoID != "" {
+		id, err := strconv.Atoi(logoID)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "Invalid logo_id"})
+			return
+		}
+		var ok bool
+		if opts.Logo, ok = readQRLogo(ctx, c.store, c.files, int32(id)); !ok {
+			return
+		}
+	}
+
+	// Our own short links are encoded through /q/ so scans are counted.
+	content := rawURL
+	if code, ok := strings.CutPrefix(rawURL, configs.GetAPIURL()+"/s/"); ok {
+		content = qrScanURL(code)
+	}
+
+	qrBytes, report, err := utils.RenderCheckedQRCode(content, opts)
+	if errors.Is(err, utils.ErrTransparentJPEG) {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(500, gin.H{"error": "Failed to generate QR code with logo"})
 		return
 	}
 
-	switch fg_color {
-	case "jpeg":
-		ctx.Header("Content-Type", "image/jpeg")
-	default:
-		ctx.Header("Content-Type", "image/png")
+	sendCheckedQRCode(ctx, qrBytes, report, contentType, "qr-code."+extension)
+}
+
+// bindQRRenderQuery applies the size, error_correction and quiet_zone query
+// parameters to opts. It responds itself on failure.
+func bindQRRenderQuery(ctx *gin.Context, opts *utils.QROptions) bool {
+	var query models.QRRenderQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.Error(err)
+		return false
 	}
-	ctx.Writer.Write(qrBytes)
+	recovery, err := utils.ParseErrorCorrection(query.ErrorCorrection)
+	if err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return false
+	}
+
+	opts.ErrorCorrection = recovery
+	opts.QuietZone = utils.DefaultQuietZone
+	if query.QuietZone != nil {
+		opts.QuietZone = *query.QuietZone
+	}
+	if query.Size != 0 {
+		opts.Size = query.Size
+	}
+	return true
+}
+
+// sendCheckedQRCode sends a code that reads back, with its contrast ratio
+// and any warnings in headers, and rejects one that does not.
+func sendCheckedQRCode(ctx *gin.Context, data []byte, report utils.QRScanReport, contentType, filename string) {
+	if !report.Readable {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "QR code would not scan: " + report.Problem, "scan": models.QRScanReport(report)})
+		return
+	}
+	ctx.Header("X-QR-Contrast-Ratio", strconv.FormatFloat(report.ContrastRatio, 'f', 2, 64))
+	if len(report.Warnings) > 0 {
+		ctx.Header("X-QR-Warnings", strings.Join(report.Warnings, "; "))
+	}
+	sendQRCode(ctx, data, contentType, filename)
+}
+
+// sendQRCode writes a generated code with a filename browsers use when it
+// is saved.
+func sendQRCode(ctx *gin.Context, data []byte, contentType, filename string) {
+	ctx.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	ctx.Data(http.StatusOK, contentType, data)
 }
 
 func (c *URLController) GetDeviceTypeStats(ctx *gin.Context) {
@@ -419,9 +1017,16 @@ func (c *URLController) GetDeviceTypeStats(ctx *gin.Context) {
 		return
 	}
 
-	params := sql.NullInt32{
-		Int32: int32(userID),
-		Valid: true,
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 0)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	params := db.GetDeviceTypeStatsByUserParams{
+		UserID:   sql.NullInt32{Int32: int32(userID), Valid: true},
+		FromTime: rng.From,
+		ToTime:   rng.To,
 	}
 
 	stats, err := c.store.GetDeviceTypeStatsByUser(ctx, params)
@@ -451,18 +1056,60 @@ func (c *URLController) GetLineChartStats(ctx *gin.Context) {
 		return
 	}
 
-	data, err := c.store.GetDailyClicksAndLinksByUser(ctx, sql.NullInt32{
-		Int32: int32(userID),
-		Valid: true,
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 7)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	buckets, err := rng.Buckets()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	owner := sql.NullInt32{Int32: int32(userID), Valid: true}
+
+	clicks, err := c.store.GetClickSeriesByUser(ctx, db.GetClickSeriesByUserParams{
+		Granularity: rng.Granularity,
+		Tz:          rng.TZ(),
+		UserID:      owner,
+		FromTime:    rng.From,
+		ToTime:      rng.To,
+	})
+	if err != nil {
+		log.Printf("failed to get click series: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load chart data"})
+		return
+	}
+	links, err := c.store.GetLinkSeriesByUser(ctx, db.GetLinkSeriesByUserParams{
+		Granularity: rng.Granularity,
+		Tz:          rng.TZ(),
+		UserID:      owner,
+		FromTime:    rng.From,
+		ToTime:      rng.To,
 	})
 	if err != nil {
-		fmt.Printf("Error from GetDailyClicksAndLinksByUser: %v\n", err) // Log error for debug
+		log.Printf("failed to get link series: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load chart data"})
 		return
 	}
 
-	if data == nil {
-		data = []db.GetDailyClicksAndLinksByUserRow{}
+	clickCounts := make(map[string]int64, len(clicks))
+	for _, row := range clicks {
+		clickCounts[utils.BucketKey(row.Bucket)] = row.Clicks
+	}
+	linkCounts := make(map[string]int64, len(links))
+	for _, row := range links {
+		linkCounts[utils.BucketKey(row.Bucket)] = row.Links
+	}
+
+	data := make([]models.ClicksAndLinksPoint, 0, len(buckets))
+	for _, bucket := range buckets {
+		key := utils.BucketKey(bucket)
+		data = append(data, models.ClicksAndLinksPoint{
+			Date:   bucket.Format(time.RFC3339),
+			Clicks: clickCounts[key],
+			Links:  linkCounts[key],
+		})
 	}
 
 	ctx.JSON(http.StatusOK, data)
@@ -481,36 +1128,59 @@ func (c *URLController) GetMonthlyClicks(ctx *gin.Context) {
 		return
 	}
 
-	rows, err := c.store.GetMonthlyClicksByUser(ctx, sql.NullInt32{
-		Int32: int32(userID),
-		Valid: true,
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityMonth, 12)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	buckets, err := rng.Buckets()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := c.store.GetClickSeriesByUser(ctx, db.GetClickSeriesByUserParams{
+		Granularity: rng.Granularity,
+		Tz:          rng.TZ(),
+		UserID:      sql.NullInt32{Int32: int32(userID), Valid: true},
+		FromTime:    rng.From,
+		ToTime:      rng.To,
 	})
 	if err != nil {
-		fmt.Println("GetMonthlyClicksByUser ERROR:", err)
+		log.Printf("failed to get click series: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load bar chart data"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, rows)
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[utils.BucketKey(row.Bucket)] = row.Clicks
+	}
+	ctx.JSON(http.StatusOK, barChartSeries(buckets, counts))
 }
 
 func (a *URLController) GetWorldMapData(ctx *gin.Context) {
 	userID := ctx.GetInt64("user_id")
 
-	daysStr := ctx.DefaultQuery("days", "30")
-	days, err := strconv.Atoi(daysStr)
-	if err != nil || days <= 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days parameter"})
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 30)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	args := db.GetCountryVisitCountsByUserParams{UserID: sql.NullInt32{Int32: int32(userID), Valid: true}, Days: int32(days)}
+	args := db.GetCountryVisitCountsByUserParams{
+		UserID:   sql.NullInt32{Int32: int32(userID), Valid: true},
+		FromTime: rng.From,
+		ToTime:   rng.To,
+	}
 	stats, err := a.store.GetCountryVisitCountsByUser(ctx, args)
-	fmt.Println(stats)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch country stats"})
 		return
 	}
+	if stats == nil {
+		stats = []db.GetCountryVisitCountsByUserRow{}
+	}
 
 	ctx.JSON(http.StatusOK, stats)
 }
@@ -528,15 +1198,43 @@ func (a *URLController) GetDashboardData(ctx *gin.Context) {
 		return
 	}
 
-	summary, err := a.store.GetDashboardSummaryByUser(ctx, sql.NullInt32{
-		Int32: int32(userID),
-		Valid: true,
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 30)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary, err := a.store.GetDashboardSummaryByUser(ctx, db.GetDashboardSummaryByUserParams{
+		FromTime: rng.From,
+		ToTime:   rng.To,
+		UserID:   sql.NullInt32{Int32: int32(userID), Valid: true},
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	ctx.JSON(http.StatusOK, summary)
+	sources, err := a.store.GetVisitSourcesByUser(ctx, db.GetVisitSourcesByUserParams{
+		UserID:   sql.NullInt32{Int32: int32(userID), Valid: true},
+		FromTime: rng.From,
+		ToTime:   rng.To,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := models.DashboardSummary{
+		TotalLinks:   summary.TotalLinks,
+		TotalClicks:  summary.TotalClicks,
+		ActiveLinks:  summary.ActiveLinks,
+		ExpiredLinks: summary.ExpiredLinks,
+		PeriodLinks:  summary.PeriodLinks,
+		PeriodClicks: summary.PeriodClicks,
+	}
+	for _, row := range sources {
+		addVisitSource(&response.Sources, row.Source, row.Clicks)
+	}
+	ctx.JSON(http.StatusOK, response)
 }
 
 func (c *URLController) GetClicksByShortcode(ctx *gin.Context) {
@@ -553,30 +1251,55 @@ func (c *URLController) GetClicksByShortcode(ctx *gin.Context) {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "URL not found or access denied"})
 		return
 	}
-	clicks, err := c.store.GetDailyClicksScoped(ctx, db.GetDailyClicksScopedParams{
-		ShortCode: shortcode,
-		UserID: sql.NullInt32{
-			Int32: int32(userID),
-		},
+
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 7)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	buckets, err := rng.Buckets()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	clicks, err := c.store.GetClickSeriesScoped(ctx, db.GetClickSeriesScopedParams{
+		Granularity: rng.Granularity,
+		Tz:          rng.TZ(),
+		ShortCode:   shortcode,
+		UserID:      sql.NullInt32{Int32: int32(userID), Valid: true},
+		FromTime:    rng.From,
+		ToTime:      rng.To,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch clicks"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, clicks)
+	ctx.JSON(http.StatusOK, clickSeries(buckets, clicks))
 }
 
 func (c *URLController) GetPieChartDataByShorcode(ctx *gin.Context) {
 	shortcode := ctx.Param("shortcode")
 
-	stats, err := c.store.GetDeviceStatsByShortcode(ctx, shortcode)
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 0)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := c.store.GetDeviceStatsScoped(ctx, db.GetDeviceStatsScopedParams{
+		ShortCode: shortcode,
+		UserID:    sql.NullInt32{Int32: int32(ctx.GetInt64("user_id")), Valid: true},
+		FromTime:  rng.From,
+		ToTime:    rng.To,
+	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch device stats"})
 		return
 	}
 	if stats == nil {
-		stats = []db.GetDeviceStatsByShortcodeRow{}
+		stats = []db.GetDeviceStatsScopedRow{}
 	}
 
 	ctx.JSON(http.StatusOK, stats)
@@ -614,27 +1337,38 @@ func (c *URLController) LineChartStatsByShortcode(ctx *gin.Context) {
 		return
 	}
 
-	stats, err := c.store.GetDailyClicksScoped(ctx, db.GetDailyClicksScopedParams{
-		ShortCode: shortcode,
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 7)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	buckets, err := rng.Buckets()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := c.store.GetClickSeriesScoped(ctx, db.GetClickSeriesScopedParams{
+		Granularity: rng.Granularity,
+		Tz:          rng.TZ(),
+		ShortCode:   shortcode,
 		UserID: sql.NullInt32{
 			Int32: int32(ctx.GetInt64("user_id")),
 			Valid: true,
 		},
+		FromTime: rng.From,
+		ToTime:   rng.To,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch line chart stats"})
 		return
 	}
 
-	if stats == nil {
-		stats = []db.GetDailyClicksScopedRow{}
-	}
-	ctx.JSON(http.StatusOK, stats)
+	ctx.JSON(http.StatusOK, clickSeries(buckets, stats))
 }
 
 func (c *URLController) GetWorldMapStatsByShortcode(ctx *gin.Context) {
 	shortcode := ctx.Param("shortcode")
-	days := ctx.DefaultQuery("days", "30")
 
 	if shortcode == "" {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "shortcode is required"})
@@ -642,32 +1376,34 @@ func (c *URLController) GetWorldMapStatsByShortcode(ctx *gin.Context) {
 	}
 	userID := ctx.GetInt64("user_id")
 
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 30)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	stats, err := c.store.GetCountryStatsScoped(ctx, db.GetCountryStatsScopedParams{
 		ShortCode: shortcode,
 		UserID: sql.NullInt32{
 			Int32: int32(userID),
 			Valid: true,
 		},
-		Days: int32(utils.ParseDaysParam(days)),
+		FromTime: rng.From,
+		ToTime:   rng.To,
 	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch world map stats"})
+		return
+	}
 
 	// remove unknown countries from stats
-	var filteredStats []db.GetCountryStatsScopedRow
+	filteredStats := []db.GetCountryStatsScopedRow{}
 	for _, s := range stats {
 		if s.Country != "unknown" {
 			filteredStats = append(filteredStats, s)
 		}
 	}
-	stats = filteredStats
-	println("World map stats:", stats)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch world map stats"})
-		return
-	}
-	if stats == nil {
-		stats = []db.GetCountryStatsScopedRow{}
-	}
-	ctx.JSON(http.StatusOK, stats)
+	ctx.JSON(http.StatusOK, filteredStats)
 }
 
 func (c *URLController) GetBarChartStatsByShortcode(ctx *gin.Context) {
@@ -679,20 +1415,335 @@ func (c *URLController) GetBarChartStatsByShortcode(ctx *gin.Context) {
 
 	userID := ctx.GetInt64("user_id")
 
-	stats, err := c.store.GetMonthlyClicksScoped(ctx, db.GetMonthlyClicksScopedParams{
-		ShortCode: shortcode,
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityMonth, 12)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	buckets, err := rng.Buckets()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := c.store.GetClickSeriesScoped(ctx, db.GetClickSeriesScopedParams{
+		Granularity: rng.Granularity,
+		Tz:          rng.TZ(),
+		ShortCode:   shortcode,
 		UserID: sql.NullInt32{
 			Int32: int32(userID),
 			Valid: true,
 		},
+		FromTime: rng.From,
+		ToTime:   rng.To,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bar chart stats"})
 		return
 	}
 
-	if stats == nil {
-		stats = []db.GetMonthlyClicksScopedRow{}
+	counts := make(map[string]int64, len(stats))
+	for _, row := range stats {
+		counts[utils.BucketKey(row.Bucket)] = row.Clicks
 	}
-	ctx.JSON(http.StatusOK, stats)
+	ctx.JSON(http.StatusOK, barChartSeries(buckets, counts))
+}
+
+func (c *URLController) GetReferrerStats(ctx *gin.Context) {
+	userID := ctx.GetInt64("user_id")
+
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 30)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := parseReferrerLimit(ctx.Query("limit"))
+	owner := sql.NullInt32{Int32: int32(userID), Valid: true}
+
+	referrers, err := c.store.GetTopReferrersByUser(ctx, db.GetTopReferrersByUserParams{
+		UserID:   owner,
+		FromTime: rng.From,
+		ToTime:   rng.To,
+		RowLimit: limit,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referrer stats"})
+		return
+	}
+	sources, err := c.store.GetReferrerSourcesByUser(ctx, db.GetReferrerSourcesByUserParams{
+		UserID:   owner,
+		FromTime: rng.From,
+		ToTime:   rng.To,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referrer sources"})
+		return
+	}
+
+	if referrers == nil {
+		referrers = []db.GetTopReferrersByUserRow{}
+	}
+	if sources == nil {
+		sources = []db.GetReferrerSourcesByUserRow{}
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"from":      rng.From,
+		"to":        rng.To,
+		"referrers": referrers,
+		"sources":   sources,
+	})
+}
+
+func (c *URLController) GetReferrerStatsByShortcode(ctx *gin.Context) {
+	shortcode := ctx.Param("shortcode")
+	if shortcode == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "shortcode is required"})
+		return
+	}
+	userID := ctx.GetInt64("user_id")
+
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 30)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := parseReferrerLimit(ctx.Query("limit"))
+	owner := sql.NullInt32{Int32: int32(userID), Valid: true}
+
+	referrers, err := c.store.GetTopReferrersScoped(ctx, db.GetTopReferrersScopedParams{
+		ShortCode: shortcode,
+		UserID:    owner,
+		FromTime:  rng.From,
+		ToTime:    rng.To,
+		RowLimit:  limit,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referrer stats"})
+		return
+	}
+	sources, err := c.store.GetReferrerSourcesScoped(ctx, db.GetReferrerSourcesScopedParams{
+		ShortCode: shortcode,
+		UserID:    owner,
+		FromTime:  rng.From,
+		ToTime:    rng.To,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referrer sources"})
+		return
+	}
+
+	if referrers == nil {
+		referrers = []db.GetTopReferrersScopedRow{}
+	}
+	if sources == nil {
+		sources = []db.GetReferrerSourcesScopedRow{}
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"from":      rng.From,
+		"to":        rng.To,
+		"referrers": referrers,
+		"sources":   sources,
+	})
+}
+
+// GetVisitSourcesByShortcode splits a link's clicks into shared link
+// clicks, QR scans and API lookups.
+func (c *URLController) GetVisitSourcesByShortcode(ctx *gin.Context) {
+	shortcode := ctx.Param("shortcode")
+	if shortcode == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "shortcode is required"})
+		return
+	}
+
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 30)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := c.store.GetVisitSourcesScoped(ctx, db.GetVisitSourcesScopedParams{
+		ShortCode: shortcode,
+		UserID:    sql.NullInt32{Int32: int32(ctx.GetInt64("user_id")), Valid: true},
+		FromTime:  rng.From,
+		ToTime:    rng.To,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch visit sources"})
+		return
+	}
+
+	var sources models.VisitSources
+	for _, row := range rows {
+		addVisitSource(&sources, row.Source, row.Clicks)
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"from":    rng.From,
+		"to":      rng.To,
+		"sources": sources,
+	})
+}
+
+func addVisitSource(sources *models.VisitSources, source string, clicks int64) {
+	switch source {
+	case utils.VisitSourceQR:
+		sources.QR += clicks
+	case utils.VisitSourceAPI:
+		sources.API += clicks
+	default:
+		sources.Link += clicks
+	}
+}
+
+func parseReferrerLimit(limit string) int32 {
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return 10
+	}
+	if n > 100 {
+		return 100
+	}
+	return int32(n)
+}
+
+func clickSeries(buckets []time.Time, rows []db.GetClickSeriesScopedRow) []models.ClicksPoint {
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[utils.BucketKey(row.Bucket)] = row.Clicks
+	}
+
+	points := make([]models.ClicksPoint, 0, len(buckets))
+	for _, bucket := range buckets {
+		points = append(points, models.ClicksPoint{
+			Date:   bucket.Format(time.RFC3339),
+			Clicks: counts[utils.BucketKey(bucket)],
+		})
+	}
+	return points
+}
+
+func barChartSeries(buckets []time.Time, counts map[string]int64) []models.BarChartPoint {
+	points := make([]models.BarChartPoint, 0, len(buckets))
+	for _, bucket := range buckets {
+		points = append(points, models.BarChartPoint{
+			Month:      bucket.Format(time.RFC3339),
+			ClickCount: counts[utils.BucketKey(bucket)],
+		})
+	}
+	return points
+}
+
+func (c *URLController) GetClickHeatmap(ctx *gin.Context) {
+	userID := ctx.GetInt64("user_id")
+
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 30)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := c.store.GetClickHeatmapByUser(ctx, db.GetClickHeatmapByUserParams{
+		Tz:       rng.TZ(),
+		UserID:   sql.NullInt32{Int32: int32(userID), Valid: true},
+		FromTime: rng.From,
+		ToTime:   rng.To,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch heatmap"})
+		return
+	}
+
+	heatmap := newHeatmap(rng)
+	for _, row := range rows {
+		addHeatmapCell(&heatmap, row.Weekday, row.Hour, row.Clicks, row.Uniques)
+	}
+	ctx.JSON(http.StatusOK, heatmap)
+}
+
+func (c *URLController) GetClickHeatmapByShortcode(ctx *gin.Context) {
+	shortcode := ctx.Param("shortcode")
+	if shortcode == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "shortcode is required"})
+		return
+	}
+	userID := ctx.GetInt64("user_id")
+
+	rng, err := utils.ParseAnalyticsRange(ctx, utils.GranularityDay, 30)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := c.store.GetClickHeatmapScoped(ctx, db.GetClickHeatmapScopedParams{
+		Tz:        rng.TZ(),
+		ShortCode: shortcode,
+		UserID:    sql.NullInt32{Int32: int32(userID), Valid: true},
+		FromTime:  rng.From,
+		ToTime:    rng.To,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch heatmap"})
+		return
+	}
+
+	heatmap := newHeatmap(rng)
+	for _, row := range rows {
+		addHeatmapCell(&heatmap, row.Weekday, row.Hour, row.Clicks, row.Uniques)
+	}
+	ctx.JSON(http.StatusOK, heatmap)
+}
+
+func newHeatmap(rng utils.AnalyticsRange) models.HeatmapResponse {
+	return models.HeatmapResponse{
+		Timezone: rng.TZ(),
+		From:     rng.From.In(rng.Location).Format(time.RFC3339),
+		To:       rng.To.In(rng.Location).Format(time.RFC3339),
+		Days:     []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"},
+	}
+}
+
+// addHeatmapCell places a row keyed by ISO weekday (1 = Monday) and hour.
+func addHeatmapCell(h *models.HeatmapResponse, weekday, hour int32, clicks, uniques int64) {
+	if weekday < 1 || weekday > 7 || hour < 0 || hour > 23 {
+		return
+	}
+	h.Clicks[weekday-1][hour] = clicks
+	h.Uniques[weekday-1][hour] = uniques
+}
+
+// StreamVisits streams the caller's visits as Server-Sent Events while the
+// connection is open. Repeat ?shortcode= (or pass a comma separated list) to
+// only receive visits for those links.
+func (c *URLController) StreamVisits(ctx *gin.Context) {
+	userID := ctx.GetInt64("user_id")
+
+	shortcodes := splitQueryList(ctx, "shortcode")
+	sub := c.hub.Subscribe(int32(userID), shortcodes)
+	defer c.hub.Unsubscribe(sub)
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	ctx.SSEvent("ready", gin.H{"shortcodes": shortcodes})
+	ctx.Writer.Flush()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case visit, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			ctx.SSEvent("visit", visit)
+			return true
+		case <-heartbeat.C:
+			ctx.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
 }
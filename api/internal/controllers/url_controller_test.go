@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"api/internal/db"
+	"api/internal/dbtest"
+	"api/internal/metadata"
+	"api/internal/safety"
+	"api/internal/storage"
+	"api/internal/thumbnail"
+	"api/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A slow destination does not hold up creating a link; its title and
+// thumbnail are filled in once it answers.
+func TestCreateShortURLCompletesLinkLater(t *testing.T) {
+	t.Setenv("ALLOW_PRIVATE_FETCH", "true")
+	_, store := dbtest.Open(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, db.CreateUserParams{Username: "ada", Email: "ada@example.com", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	unblock := sync.OnceFunc(func() { close(release) })
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/icon.png" {
+			w.Header().Set("Content-Type", "image/png")
+			_ = png.Encode(w, image.NewGray(image.Rect(0, 0, 32, 32)))
+			return
+		}
+		<-release
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head><title>Slow page</title><link rel="icon" href="/icon.png"></head></html>`))
+	}))
+	defer site.Close()
+	defer unblock()
+
+	fetcher := metadata.NewFetcher()
+	c := NewURLController(store, nil, webhook.NewDispatcher(store), safety.NewScanner(), fetcher,
+		thumbnail.NewService(storage.NewLocal(t.TempDir()), fetcher), nil)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/protected/shorten", func(ctx *gin.Context) {
+		ctx.Set("user_id", int64(user.ID))
+		c.CreateShortURL(ctx)
+	})
+
+	start := time.Now()
+	w := postJSON(r, "/api/protected/shorten", gin.H{"original_url": site.URL + "/page", "shortcode": "slow01"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("creating the link took %s while the destination hung", elapsed)
+	}
+	unblock()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		link, err := store.GetOriginalURL(ctx, "slow01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if link.Title.String == "Slow page" && link.Thumbnail.Valid {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("title, thumbnail = %q, %q, want them filled in", link.Title.String, link.Thumbnail.String)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestUpdateShortURLKeepsOmittedFields(t *testing.T) {
+	_, store := dbtest.Open(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, db.CreateUserParams{Username: "ada", Email: "ada@example.com", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateShortURL(ctx, db.CreateShortURLParams{
+		OriginalUrl:   "https://example.com/old",
+		ShortCode:     "keep01",
+		Title:         sql.NullString{String: "Launch", Valid: true},
+		UserID:        sql.NullInt32{Int32: user.ID, Valid: true},
+		BackupUrl:     sql.NullString{String: "https://example.org/backup", Valid: true},
+		SafetyStatus:  sql.NullString{String: safety.VerdictSafe, Valid: true},
+		AlwaysPreview: true,
+		OgTitle:       sql.NullString{String: "Share title", Valid: true},
+		OgDescription: sql.NullString{String: "Share description", Valid: true},
+		OgImage:       sql.NullString{String: "cards/launch.png", Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	c := NewURLController(store, nil, webhook.NewDispatcher(store), safety.NewScanner(), nil, nil, nil)
+	r.POST("/api/protected/edit/:shortcode", c.UpdateShortURL)
+
+	w := postJSON(r, "/api/protected/edit/keep01", gin.H{"title": "Launch", "original_url": "https://example.com/new"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	got, err := store.GetOriginalURL(ctx, "keep01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OriginalUrl != "https://example.com/new" {
+		t.Errorf("original_url = %q, want the edited URL", got.OriginalUrl)
+	}
+	if got.BackupUrl.String != "https://example.org/backup" {
+		t.Errorf("backup_url = %q, want it kept", got.BackupUrl.String)
+	}
+	if !got.AlwaysPreview {
+		t.Error("always_preview was turned off")
+	}
+	if got.OgTitle.String != "Share title" || got.OgDescription.String != "Share description" {
+		t.Errorf("og_title, og_description = %q, %q, want them kept", got.OgTitle.String, got.OgDescription.String)
+	}
+	if got.OgImage.String != "cards/launch.png" {
+		t.Errorf("og_image = %q, want it kept", got.OgImage.String)
+	}
+
+	// An explicit empty string still clears a field.
+	w = postJSON(r, "/api/protected/edit/keep01", gin.H{"title": "Launch", "original_url": "https://example.com/new", "backup_url": "", "og_title": ""})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	got, err = store.GetOriginalURL(ctx, "keep01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BackupUrl.Valid || got.OgTitle.Valid {
+		t.Errorf("backup_url, og_title = %v, %v, want both cleared", got.BackupUrl, got.OgTitle)
+	}
+	if got.OgDescription.String != "Share description" {
+		t.Errorf("og_description = %q, want it kept", got.OgDescription.String)
+	}
+}
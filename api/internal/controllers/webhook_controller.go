@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"api/internal/db"
+	"api/internal/models"
+	"api/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	store      *db.Queries
+	dispatcher *webhook.Dispatcher
+}
+
+func NewWebhookController(store *db.Queries, dispatcher *webhook.Dispatcher) *WebhookController {
+	return &WebhookController{store: store, dispatcher: dispatcher}
+}
+
+func (c *WebhookController) CreateEndpoint(ctx *gin.Context) {
+	var req models.WebhookEndpointRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if err := validateWebhookEvents(req.Events); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	endpoint, err := c.store.CreateWebhookEndpoint(ctx, db.CreateWebhookEndpointParams{
+		UserID: int32(ctx.GetInt64("user_id")),
+		Url:    req.URL,
+		Secret: secret,
+		Events: req.Events,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	// The secret is only returned once, when the endpoint is created.
+	response := webhookEndpointResponse(endpoint)
+	response.Secret = endpoint.Secret
+	ctx.JSON(http.StatusCreated, response)
+}
+
+func (c *WebhookController) ListEndpoints(ctx *gin.Context) {
+	endpoints, err := c.store.ListWebhookEndpointsByUser(ctx, int32(ctx.GetInt64("user_id")))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	response := make([]models.WebhookEndpointResponse, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		response = append(response, webhookEndpointResponse(endpoint))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *WebhookController) UpdateEndpoint(ctx *gin.Context) {
+	endpoint, ok := c.findEndpoint(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookEndpointRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if err := validateWebhookEvents(req.Events); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	active := endpoint.Active
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	updated, err := c.store.UpdateWebhookEndpoint(ctx, db.UpdateWebhookEndpointParams{
+		ID:     endpoint.ID,
+		UserID: endpoint.UserID,
+		Url:    req.URL,
+		Events: req.Events,
+		Active: active,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+	ctx.JSON(http.StatusOK, webhookEndpointResponse(updated))
+}
+
+func (c *WebhookController) DeleteEndpoint(ctx *gin.Context) {
+	endpoint, ok := c.findEndpoint(ctx)
+	if !ok {
+		return
+	}
+
+	if _, err := c.store.DeleteWebhookEndpoint(ctx, db.DeleteWebhookEndpointParams{
+		ID:     endpoint.ID,
+		UserID: endpoint.UserID,
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Deleted successfully"})
+}
+
+func (c *WebhookController) ListDeliveries(ctx *gin.Context) {
+	endpoint, ok := c.findEndpoint(ctx)
+	if !ok {
+		return
+	}
+
+	deliveries, err := c.store.ListWebhookDeliveries(ctx, endpoint.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deliveries"})
+		return
+	}
+
+	response := make([]models.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		response = append(response, webhookDeliveryResponse(delivery))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// SendTestEvent delivers a webhook.test event right away, without retries,
+// and returns the recorded attempt.
+func (c *WebhookController) SendTestEvent(ctx *gin.Context) {
+	endpoint, ok := c.findEndpoint(ctx)
+	if !ok {
+		return
+	}
+
+	delivery, err := c.dispatcher.Enqueue(ctx, endpoint, webhook.EventTest, gin.H{
+		"message":     "This is a test event",
+		"endpoint_id": endpoint.ID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create test event"})
+		return
+	}
+
+	attempt := c.dispatcher.Deliver(ctx.Request.Context(), endpoint, delivery, false)
+	delivery.Status = attempt.Status
+	delivery.Attempts++
+	delivery.ResponseCode = attempt.ResponseCode
+	delivery.ResponseBody = attempt.ResponseBody
+	delivery.Error = attempt.Error
+	if attempt.Status == webhook.StatusDelivered {
+		delivery.DeliveredAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	ctx.JSON(http.StatusOK, webhookDeliveryResponse(delivery))
+}
+
+func (c *WebhookController) findEndpoint(ctx *gin.Context) (db.WebhookEndpoint, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return db.WebhookEndpoint{}, false
+	}
+
+	endpoint, err := c.store.GetWebhookEndpoint(ctx, db.GetWebhookEndpointParams{
+		ID:     int32(id),
+		UserID: int32(ctx.GetInt64("user_id")),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return endpoint, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhook"})
+		return endpoint, false
+	}
+	return endpoint, true
+}
+
+func validateWebhookEvents(events []string) error {
+	for _, event := range events {
+		if !webhook.ValidEvent(event) {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+	return nil
+}
+
+func webhookEndpointResponse(endpoint db.WebhookEndpoint) models.WebhookEndpointResponse {
+	response := models.WebhookEndpointResponse{
+		ID:     endpoint.ID,
+		URL:    endpoint.Url,
+		Events: endpoint.Events,
+		Active: endpoint.Active,
+	}
+	if endpoint.CreatedAt.Valid {
+		response.CreatedAt = endpoint.CreatedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
+
+func webhookDeliveryResponse(delivery db.WebhookDelivery) models.WebhookDeliveryResponse {
+	response := models.WebhookDeliveryResponse{
+		ID:           delivery.ID,
+		Event:        delivery.Event,
+		Status:       delivery.Status,
+		Attempts:     delivery.Attempts,
+		ResponseCode: delivery.ResponseCode.Int32,
+		ResponseBody: delivery.ResponseBody.String,
+		Error:        delivery.Error.String,
+		Payload:      delivery.Payload,
+	}
+	if delivery.CreatedAt.Valid {
+		response.CreatedAt = delivery.CreatedAt.Time.Format(time.RFC3339)
+	}
+	if delivery.Status == webhook.StatusPending {
+		response.NextAttemptAt = delivery.NextAttemptAt.Format(time.RFC3339)
+	}
+	if delivery.DeliveredAt.Valid {
+		response.DeliveredAt = delivery.DeliveredAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
@@ -6,16 +6,96 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"time"
 )
 
+type ExportJob struct {
+	ID          int32          `json:"id"`
+	UserID      int32          `json:"user_id"`
+	Format      string         `json:"format"`
+	Columns     []string       `json:"columns"`
+	ShortCodes  []string       `json:"short_codes"`
+	FromTime    time.Time      `json:"from_time"`
+	ToTime      time.Time      `json:"to_time"`
+	Timezone    string         `json:"timezone"`
+	Status      string         `json:"status"`
+	FilePath    sql.NullString `json:"file_path"`
+	RowCount    int64          `json:"row_count"`
+	Error       sql.NullString `json:"error"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
+	StartedAt   sql.NullTime   `json:"started_at"`
+	CompletedAt sql.NullTime   `json:"completed_at"`
+}
+
+type LinkFailover struct {
+	ID        int32        `json:"id"`
+	UrlID     int32        `json:"url_id"`
+	StartedAt time.Time    `json:"started_at"`
+	EndedAt   sql.NullTime `json:"ended_at"`
+}
+
+type LinkHealthCheck struct {
+	ID            int32          `json:"id"`
+	UrlID         int32          `json:"url_id"`
+	Status        string         `json:"status"`
+	StatusCode    sql.NullInt32  `json:"status_code"`
+	Method        string         `json:"method"`
+	LatencyMs     int32          `json:"latency_ms"`
+	RedirectChain []string       `json:"redirect_chain"`
+	FinalUrl      sql.NullString `json:"final_url"`
+	Error         sql.NullString `json:"error"`
+	CheckedAt     time.Time      `json:"checked_at"`
+}
+
+type PasswordResetRequest struct {
+	ID          int32     `json:"id"`
+	Email       string    `json:"email"`
+	IpAddress   string    `json:"ip_address"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+type PasswordResetToken struct {
+	ID        int32     `json:"id"`
+	UserID    int32     `json:"user_id"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type QrCode struct {
-	ID        int32          `json:"id"`
-	UrlID     int32          `json:"url_id"`
-	QrCode    string         `json:"qr_code"`
-	FgColor   sql.NullString `json:"fg_color"`
-	BgColor   sql.NullString `json:"bg_color"`
-	ExpireAt  sql.NullTime   `json:"expire_at"`
-	CreatedAt sql.NullTime   `json:"created_at"`
+	ID              int32           `json:"id"`
+	UrlID           int32           `json:"url_id"`
+	QrCode          string          `json:"qr_code"`
+	FgColor         sql.NullString  `json:"fg_color"`
+	BgColor         sql.NullString  `json:"bg_color"`
+	ExpireAt        sql.NullTime    `json:"expire_at"`
+	CreatedAt       sql.NullTime    `json:"created_at"`
+	LogoUrl         sql.NullString  `json:"logo_url"`
+	Size            int32           `json:"size"`
+	Format          string          `json:"format"`
+	ErrorCorrection string          `json:"error_correction"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	LogoID          sql.NullInt32   `json:"logo_id"`
+	Style           json.RawMessage `json:"style"`
+	QuietZone       int32           `json:"quiet_zone"`
+}
+
+type QrLogo struct {
+	ID          int32     `json:"id"`
+	UserID      int32     `json:"user_id"`
+	Name        string    `json:"name"`
+	StorageKey  string    `json:"storage_key"`
+	ContentType string    `json:"content_type"`
+	Width       int32     `json:"width"`
+	Height      int32     `json:"height"`
+	SizeBytes   int32     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RollupState struct {
+	Name      string    `json:"name"`
+	Watermark time.Time `json:"watermark"`
 }
 
 type Transaction struct {
@@ -31,41 +111,120 @@ type Transaction struct {
 }
 
 type Url struct {
-	ID           int32          `json:"id"`
-	OriginalUrl  string         `json:"original_url"`
-	Title        sql.NullString `json:"title"`
-	ShortCode    string         `json:"short_code"`
-	Thumbnail    sql.NullString `json:"thumbnail"`
-	ClickCount   int32          `json:"click_count"`
-	PasswordHash sql.NullString `json:"password_hash"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	ExpireAt     sql.NullTime   `json:"expire_at"`
-	UserID       sql.NullInt32  `json:"user_id"`
+	ID                 int32           `json:"id"`
+	OriginalUrl        string          `json:"original_url"`
+	Title              sql.NullString  `json:"title"`
+	ShortCode          string          `json:"short_code"`
+	Thumbnail          sql.NullString  `json:"thumbnail"`
+	ClickCount         int32           `json:"click_count"`
+	PasswordHash       sql.NullString  `json:"password_hash"`
+	CreatedAt          sql.NullTime    `json:"created_at"`
+	ExpireAt           sql.NullTime    `json:"expire_at"`
+	UserID             sql.NullInt32   `json:"user_id"`
+	HealthStatus       sql.NullString  `json:"health_status"`
+	HealthCheckedAt    sql.NullTime    `json:"health_checked_at"`
+	BackupUrl          sql.NullString  `json:"backup_url"`
+	SafetyStatus       sql.NullString  `json:"safety_status"`
+	SafetyReason       sql.NullString  `json:"safety_reason"`
+	SafetyCheckedAt    sql.NullTime    `json:"safety_checked_at"`
+	AlwaysPreview      bool            `json:"always_preview"`
+	PreviewCount       int32           `json:"preview_count"`
+	OgTitle            sql.NullString  `json:"og_title"`
+	OgDescription      sql.NullString  `json:"og_description"`
+	OgImage            sql.NullString  `json:"og_image"`
+	ThumbnailCheckedAt sql.NullTime    `json:"thumbnail_checked_at"`
+	PayloadType        sql.NullString  `json:"payload_type"`
+	Payload            json.RawMessage `json:"payload"`
+	HealthStreak       int32           `json:"health_streak"`
+}
+
+type UrlPreviewView struct {
+	ID        int32          `json:"id"`
+	UrlID     int32          `json:"url_id"`
+	IpAddress sql.NullString `json:"ip_address"`
+	UserAgent sql.NullString `json:"user_agent"`
+	Referrer  sql.NullString `json:"referrer"`
+	ViewedAt  time.Time      `json:"viewed_at"`
 }
 
 type UrlVisit struct {
-	ID         int32          `json:"id"`
-	UrlID      int32          `json:"url_id"`
-	UserID     sql.NullInt32  `json:"user_id"`
-	IpAddress  sql.NullString `json:"ip_address"`
-	UserAgent  sql.NullString `json:"user_agent"`
-	DeviceType sql.NullString `json:"device_type"`
-	Referrer   sql.NullString `json:"referrer"`
-	Country    sql.NullString `json:"country"`
-	Region     sql.NullString `json:"region"`
-	City       sql.NullString `json:"city"`
-	ClickedAt  sql.NullTime   `json:"clicked_at"`
+	ID             int32          `json:"id"`
+	UrlID          int32          `json:"url_id"`
+	UserID         sql.NullInt32  `json:"user_id"`
+	IpAddress      sql.NullString `json:"ip_address"`
+	UserAgent      sql.NullString `json:"user_agent"`
+	DeviceType     sql.NullString `json:"device_type"`
+	Referrer       sql.NullString `json:"referrer"`
+	Country        sql.NullString `json:"country"`
+	Region         sql.NullString `json:"region"`
+	City           sql.NullString `json:"city"`
+	ClickedAt      sql.NullTime   `json:"clicked_at"`
+	ReferrerDomain sql.NullString `json:"referrer_domain"`
+	ReferrerSource sql.NullString `json:"referrer_source"`
+	Destination    sql.NullString `json:"destination"`
+	Source         string         `json:"source"`
+}
+
+type UrlVisitRollup struct {
+	UrlID  int32     `json:"url_id"`
+	Bucket time.Time `json:"bucket"`
+	Clicks int64     `json:"clicks"`
+}
+
+type UrlVisitRollupDim struct {
+	UrlID          int32     `json:"url_id"`
+	Bucket         time.Time `json:"bucket"`
+	Country        string    `json:"country"`
+	DeviceType     string    `json:"device_type"`
+	ReferrerDomain string    `json:"referrer_domain"`
+	ReferrerSource string    `json:"referrer_source"`
+	Clicks         int64     `json:"clicks"`
+}
+
+type UrlVisitRollupSource struct {
+	UrlID  int32     `json:"url_id"`
+	Bucket time.Time `json:"bucket"`
+	Source string    `json:"source"`
+	Clicks int64     `json:"clicks"`
 }
 
 type User struct {
-	ID           int32          `json:"id"`
-	Username     string         `json:"username"`
-	Email        string         `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	IpAddress    sql.NullString `json:"ip_address"`
-	Provider     sql.NullString `json:"provider"`
-	ProviderID   sql.NullString `json:"provider_id"`
-	Image        sql.NullString `json:"image"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	ID                 int32          `json:"id"`
+	Username           string         `json:"username"`
+	Email              string         `json:"email"`
+	PasswordHash       string         `json:"password_hash"`
+	IpAddress          sql.NullString `json:"ip_address"`
+	Provider           sql.NullString `json:"provider"`
+	ProviderID         sql.NullString `json:"provider_id"`
+	Image              sql.NullString `json:"image"`
+	CreatedAt          sql.NullTime   `json:"created_at"`
+	UpdatedAt          sql.NullTime   `json:"updated_at"`
+	EmailVerifiedAt    sql.NullTime   `json:"email_verified_at"`
+	VerificationSentAt sql.NullTime   `json:"verification_sent_at"`
+	SessionVersion     int32          `json:"session_version"`
+}
+
+type WebhookDelivery struct {
+	ID            int32           `json:"id"`
+	EndpointID    int32           `json:"endpoint_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	ResponseCode  sql.NullInt32   `json:"response_code"`
+	ResponseBody  sql.NullString  `json:"response_body"`
+	Error         sql.NullString  `json:"error"`
+	CreatedAt     sql.NullTime    `json:"created_at"`
+	DeliveredAt   sql.NullTime    `json:"delivered_at"`
+}
+
+type WebhookEndpoint struct {
+	ID        int32        `json:"id"`
+	UserID    int32        `json:"user_id"`
+	Url       string       `json:"url"`
+	Secret    string       `json:"secret"`
+	Events    []string     `json:"events"`
+	Active    bool         `json:"active"`
+	CreatedAt sql.NullTime `json:"created_at"`
 }
@@ -7,44 +7,118 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 type Querier interface {
+	ClaimDueWebhookDeliveries(ctx context.Context, limit int32) ([]WebhookDelivery, error)
+	ClaimExportJob(ctx context.Context) (ExportJob, error)
+	ClaimVerificationEmail(ctx context.Context, id int32) (int64, error)
+	CompleteExportJob(ctx context.Context, arg CompleteExportJobParams) error
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (int32, error)
+	CountPasswordResetRequests(ctx context.Context, arg CountPasswordResetRequestsParams) (CountPasswordResetRequestsRow, error)
+	CountQRLogosByUser(ctx context.Context, userID int32) (int64, error)
+	CountVisitsForExport(ctx context.Context, arg CountVisitsForExportParams) (int64, error)
+	CreateExportJob(ctx context.Context, arg CreateExportJobParams) (ExportJob, error)
+	CreateLinkHealthCheck(ctx context.Context, arg CreateLinkHealthCheckParams) error
 	CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams) (User, error)
+	CreatePasswordResetRequest(ctx context.Context, arg CreatePasswordResetRequestParams) error
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error
+	CreatePayloadLink(ctx context.Context, arg CreatePayloadLinkParams) (Url, error)
+	CreateQRCode(ctx context.Context, arg CreateQRCodeParams) (QrCode, error)
+	CreateQRLogo(ctx context.Context, arg CreateQRLogoParams) (QrLogo, error)
 	CreateShortURL(ctx context.Context, arg CreateShortURLParams) (Url, error)
 	CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
+	CreateWebhookEndpoint(ctx context.Context, arg CreateWebhookEndpointParams) (WebhookEndpoint, error)
+	DeleteExpiredExportJobs(ctx context.Context, completedBefore time.Time) ([]sql.NullString, error)
+	DeleteExpiredPasswordResetTokens(ctx context.Context) error
+	DeleteLinkHealthChecksBefore(ctx context.Context, checkedAt time.Time) error
+	DeletePasswordResetRequestsBefore(ctx context.Context, requestedAt time.Time) error
+	DeletePasswordResetTokensByUser(ctx context.Context, userID int32) error
+	DeleteQRCode(ctx context.Context, id int32) error
+	DeleteQRLogo(ctx context.Context, arg DeleteQRLogoParams) error
 	DeleteURLByShortCode(ctx context.Context, shortCode string) error
+	DeleteWebhookEndpoint(ctx context.Context, arg DeleteWebhookEndpointParams) (int64, error)
+	EndLinkFailover(ctx context.Context, urlID int32) error
+	FailExportJob(ctx context.Context, arg FailExportJobParams) error
 	GetAnalyticsShortcode(ctx context.Context, shortCode string) ([]GetAnalyticsShortcodeRow, error)
+	GetClickHeatmapByUser(ctx context.Context, arg GetClickHeatmapByUserParams) ([]GetClickHeatmapByUserRow, error)
+	GetClickHeatmapScoped(ctx context.Context, arg GetClickHeatmapScopedParams) ([]GetClickHeatmapScopedRow, error)
+	GetClickSeriesByUser(ctx context.Context, arg GetClickSeriesByUserParams) ([]GetClickSeriesByUserRow, error)
+	GetClickSeriesScoped(ctx context.Context, arg GetClickSeriesScopedParams) ([]GetClickSeriesScopedRow, error)
 	GetCountryStatsScoped(ctx context.Context, arg GetCountryStatsScopedParams) ([]GetCountryStatsScopedRow, error)
 	GetCountryVisitCountsByUser(ctx context.Context, arg GetCountryVisitCountsByUserParams) ([]GetCountryVisitCountsByUserRow, error)
-	GetDailyClicksAndLinksByUser(ctx context.Context, userID sql.NullInt32) ([]GetDailyClicksAndLinksByUserRow, error)
-	GetDailyClicksScoped(ctx context.Context, arg GetDailyClicksScopedParams) ([]GetDailyClicksScopedRow, error)
-	GetDashboardSummaryByUser(ctx context.Context, userID sql.NullInt32) (GetDashboardSummaryByUserRow, error)
-	GetDeviceStatsByShortcode(ctx context.Context, shortCode string) ([]GetDeviceStatsByShortcodeRow, error)
+	GetDashboardSummaryByUser(ctx context.Context, arg GetDashboardSummaryByUserParams) (GetDashboardSummaryByUserRow, error)
 	GetDeviceStatsScoped(ctx context.Context, arg GetDeviceStatsScopedParams) ([]GetDeviceStatsScopedRow, error)
 	GetDeviceTypeStatsByShortCode(ctx context.Context, shortCode string) ([]GetDeviceTypeStatsByShortCodeRow, error)
-	GetDeviceTypeStatsByUser(ctx context.Context, userID sql.NullInt32) ([]GetDeviceTypeStatsByUserRow, error)
-	GetMonthlyClicksByUser(ctx context.Context, userID sql.NullInt32) ([]GetMonthlyClicksByUserRow, error)
-	GetMonthlyClicksScoped(ctx context.Context, arg GetMonthlyClicksScopedParams) ([]GetMonthlyClicksScopedRow, error)
+	GetDeviceTypeStatsByUser(ctx context.Context, arg GetDeviceTypeStatsByUserParams) ([]GetDeviceTypeStatsByUserRow, error)
+	GetExportJob(ctx context.Context, arg GetExportJobParams) (ExportJob, error)
+	GetFirstVisitTime(ctx context.Context) (sql.NullTime, error)
+	GetLinkSeriesByUser(ctx context.Context, arg GetLinkSeriesByUserParams) ([]GetLinkSeriesByUserRow, error)
 	GetOriginalURL(ctx context.Context, shortCode string) (Url, error)
+	GetOwnedShortCodes(ctx context.Context, arg GetOwnedShortCodesParams) ([]string, error)
+	GetQRCode(ctx context.Context, arg GetQRCodeParams) (GetQRCodeRow, error)
+	GetQRLogo(ctx context.Context, arg GetQRLogoParams) (QrLogo, error)
+	GetReferrerSourcesByUser(ctx context.Context, arg GetReferrerSourcesByUserParams) ([]GetReferrerSourcesByUserRow, error)
+	GetReferrerSourcesScoped(ctx context.Context, arg GetReferrerSourcesScopedParams) ([]GetReferrerSourcesScopedRow, error)
+	GetRollupWatermark(ctx context.Context, name string) (time.Time, error)
 	GetTitleAndUrlByUser(ctx context.Context, userID sql.NullInt32) ([]GetTitleAndUrlByUserRow, error)
+	GetTopReferrersByUser(ctx context.Context, arg GetTopReferrersByUserParams) ([]GetTopReferrersByUserRow, error)
+	GetTopReferrersScoped(ctx context.Context, arg GetTopReferrersScopedParams) ([]GetTopReferrersScopedRow, error)
 	GetURLVisits(ctx context.Context, urlID int32) ([]UrlVisit, error)
 	GetUrlsByUserID(ctx context.Context, userID sql.NullInt32) ([]Url, error)
-	// -- name: GetUserTransactions :many
-	// SELECT * FROM transactions
-	// WHERE user_id = $1
-	// ORDER BY created_at DESC;
 	GetUserAccountDetails(ctx context.Context, id int32) (GetUserAccountDetailsRow, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByEmailFold(ctx context.Context, lower string) (User, error)
+	GetUserByID(ctx context.Context, id int32) (User, error)
 	GetUserByProviderID(ctx context.Context, arg GetUserByProviderIDParams) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
+	GetUserSessionVersion(ctx context.Context, id int32) (int32, error)
 	GetUserTransactions(ctx context.Context, userID int32) ([]Transaction, error)
 	GetUserTransactionsByStatus(ctx context.Context, arg GetUserTransactionsByStatusParams) ([]Transaction, error)
+	GetVisitSourcesByUser(ctx context.Context, arg GetVisitSourcesByUserParams) ([]GetVisitSourcesByUserRow, error)
+	GetVisitSourcesScoped(ctx context.Context, arg GetVisitSourcesScopedParams) ([]GetVisitSourcesScopedRow, error)
+	GetWebhookEndpoint(ctx context.Context, arg GetWebhookEndpointParams) (WebhookEndpoint, error)
+	GetWebhookEndpointByID(ctx context.Context, id int32) (WebhookEndpoint, error)
 	IncrementClickCount(ctx context.Context, shortCode string) error
+	IsUserEmailVerified(ctx context.Context, id int32) (bool, error)
+	ListExportJobsByUser(ctx context.Context, userID int32) ([]ExportJob, error)
+	ListLinkFailovers(ctx context.Context, arg ListLinkFailoversParams) ([]LinkFailover, error)
+	ListLinkHealthChecks(ctx context.Context, arg ListLinkHealthChecksParams) ([]LinkHealthCheck, error)
+	ListLinksByShortCodes(ctx context.Context, arg ListLinksByShortCodesParams) ([]ListLinksByShortCodesRow, error)
+	ListLinksDueForHealthCheck(ctx context.Context, arg ListLinksDueForHealthCheckParams) ([]Url, error)
+	ListLinksDueForSafetyScan(ctx context.Context, arg ListLinksDueForSafetyScanParams) ([]Url, error)
+	ListLinksDueForThumbnail(ctx context.Context, arg ListLinksDueForThumbnailParams) ([]Url, error)
+	ListQRCodesByUser(ctx context.Context, userID sql.NullInt32) ([]ListQRCodesByUserRow, error)
+	ListQRLogosByUser(ctx context.Context, userID int32) ([]QrLogo, error)
+	ListVisitsForExport(ctx context.Context, arg ListVisitsForExportParams) ([]ListVisitsForExportRow, error)
+	ListWebhookDeliveries(ctx context.Context, endpointID int32) ([]WebhookDelivery, error)
+	ListWebhookEndpointsByUser(ctx context.Context, userID int32) ([]WebhookEndpoint, error)
+	ListWebhookEndpointsForEvent(ctx context.Context, arg ListWebhookEndpointsForEventParams) ([]WebhookEndpoint, error)
+	LogPreviewView(ctx context.Context, arg LogPreviewViewParams) error
 	LogURLVisit(ctx context.Context, arg LogURLVisitParams) error
+	RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) error
+	RequeueRunningExportJobs(ctx context.Context) error
+	RequeueSendingWebhookDeliveries(ctx context.Context) error
+	RollupVisitDims(ctx context.Context, arg RollupVisitDimsParams) error
+	RollupVisitSources(ctx context.Context, arg RollupVisitSourcesParams) error
+	RollupVisits(ctx context.Context, arg RollupVisitsParams) error
+	SetMissingURLTitle(ctx context.Context, arg SetMissingURLTitleParams) error
+	SetNewURLThumbnail(ctx context.Context, arg SetNewURLThumbnailParams) (int64, error)
+	SetRollupWatermark(ctx context.Context, arg SetRollupWatermarkParams) error
+	SetURLHealth(ctx context.Context, arg SetURLHealthParams) error
+	SetURLSafety(ctx context.Context, arg SetURLSafetyParams) error
+	SetURLThumbnail(ctx context.Context, arg SetURLThumbnailParams) error
+	StartLinkFailover(ctx context.Context, urlID int32) error
+	UpdateLinkPayload(ctx context.Context, arg UpdateLinkPayloadParams) (Url, error)
+	UpdateQRCode(ctx context.Context, arg UpdateQRCodeParams) (QrCode, error)
 	UpdateShortURL(ctx context.Context, arg UpdateShortURLParams) (Url, error)
 	UpdateTransactionPayment(ctx context.Context, arg UpdateTransactionPaymentParams) error
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateWebhookEndpoint(ctx context.Context, arg UpdateWebhookEndpointParams) (WebhookEndpoint, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
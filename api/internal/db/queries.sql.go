@@ -8,13 +8,311 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/lib/pq"
+)
+
+const claimDueWebhookDeliveries = `-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET status = 'sending'
+WHERE id IN (
+  SELECT id FROM webhook_deliveries
+  WHERE status = 'pending' AND next_attempt_at <= now()
+  ORDER BY next_attempt_at
+  LIMIT $1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, endpoint_id, event, payload, status, attempts, next_attempt_at, response_code, response_body, error, created_at, delivered_at
+`
+
+func (q *Queries) ClaimDueWebhookDeliveries(ctx context.Context, limit int32) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueWebhookDeliveries, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.EndpointID,
+			&i.Event,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.ResponseCode,
+			&i.ResponseBody,
+			&i.Error,
+			&i.CreatedAt,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimExportJob = `-- name: ClaimExportJob :one
+UPDATE export_jobs
+SET status = 'running', started_at = now()
+WHERE id = (
+  SELECT id FROM export_jobs
+  WHERE status = 'pending'
+  ORDER BY id
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, format, columns, short_codes, from_time, to_time, timezone, status, file_path, row_count, error, created_at, started_at, completed_at
+`
+
+func (q *Queries) ClaimExportJob(ctx context.Context) (ExportJob, error) {
+	row := q.db.QueryRowContext(ctx, claimExportJob)
+	var i ExportJob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Format,
+		pq.Array(&i.Columns),
+		pq.Array(&i.ShortCodes),
+		&i.FromTime,
+		&i.ToTime,
+		&i.Timezone,
+		&i.Status,
+		&i.FilePath,
+		&i.RowCount,
+		&i.Error,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const claimVerificationEmail = `-- name: ClaimVerificationEmail :execrows
+UPDATE users
+SET verification_sent_at = now()
+WHERE id = $1
+  AND email_verified_at IS NULL
+  AND (verification_sent_at IS NULL OR verification_sent_at < now() - INTERVAL '1 minute')
+`
+
+func (q *Queries) ClaimVerificationEmail(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, claimVerificationEmail, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const completeExportJob = `-- name: CompleteExportJob :exec
+UPDATE export_jobs
+SET status = 'completed', file_path = $2, row_count = $3, completed_at = now()
+WHERE id = $1
+`
+
+type CompleteExportJobParams struct {
+	ID       int32          `json:"id"`
+	FilePath sql.NullString `json:"file_path"`
+	RowCount int64          `json:"row_count"`
+}
+
+func (q *Queries) CompleteExportJob(ctx context.Context, arg CompleteExportJobParams) error {
+	_, err := q.db.ExecContext(ctx, completeExportJob, arg.ID, arg.FilePath, arg.RowCount)
+	return err
+}
+
+const consumePasswordResetToken = `-- name: ConsumePasswordResetToken :one
+DELETE FROM password_reset_tokens
+WHERE token_hash = $1 AND expires_at > now()
+RETURNING user_id
+`
+
+func (q *Queries) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (int32, error) {
+	row := q.db.QueryRowContext(ctx, consumePasswordResetToken, tokenHash)
+	var user_id int32
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const countPasswordResetRequests = `-- name: CountPasswordResetRequests :one
+SELECT
+  COUNT(*) FILTER (WHERE email = $1) AS by_email,
+  COUNT(*) FILTER (WHERE ip_address = $2) AS by_ip
+FROM password_reset_requests
+WHERE requested_at > $3
+  AND (email = $1 OR ip_address = $2)
+`
+
+type CountPasswordResetRequestsParams struct {
+	Email     string    `json:"email"`
+	IpAddress string    `json:"ip_address"`
+	Since     time.Time `json:"since"`
+}
+
+type CountPasswordResetRequestsRow struct {
+	ByEmail int64 `json:"by_email"`
+	ByIp    int64 `json:"by_ip"`
+}
+
+func (q *Queries) CountPasswordResetRequests(ctx context.Context, arg CountPasswordResetRequestsParams) (CountPasswordResetRequestsRow, error) {
+	row := q.db.QueryRowContext(ctx, countPasswordResetRequests, arg.Email, arg.IpAddress, arg.Since)
+	var i CountPasswordResetRequestsRow
+	err := row.Scan(&i.ByEmail, &i.ByIp)
+	return i, err
+}
+
+const countQRLogosByUser = `-- name: CountQRLogosByUser :one
+SELECT COUNT(*) FROM qr_logos
+WHERE user_id = $1
+`
+
+func (q *Queries) CountQRLogosByUser(ctx context.Context, userID int32) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countQRLogosByUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countVisitsForExport = `-- name: CountVisitsForExport :one
+SELECT COUNT(*) AS total
+FROM url_visits uv
+JOIN urls u ON u.id = uv.url_id
+WHERE u.user_id = $1
+  AND (cardinality($2::text[]) = 0 OR u.short_code = ANY($2::text[]))
+  AND uv.clicked_at >= $3::timestamptz
+  AND uv.clicked_at < $4::timestamptz
+`
+
+type CountVisitsForExportParams struct {
+	UserID     sql.NullInt32 `json:"user_id"`
+	ShortCodes []string      `json:"short_codes"`
+	FromTime   time.Time     `json:"from_time"`
+	ToTime     time.Time     `json:"to_time"`
+}
+
+func (q *Queries) CountVisitsForExport(ctx context.Context, arg CountVisitsForExportParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countVisitsForExport,
+		arg.UserID,
+		pq.Array(arg.ShortCodes),
+		arg.FromTime,
+		arg.ToTime,
+	)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const createExportJob = `-- name: CreateExportJob :one
+INSERT INTO export_jobs (
+  user_id,
+  format,
+  columns,
+  short_codes,
+  from_time,
+  to_time,
+  timezone
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING id, user_id, format, columns, short_codes, from_time, to_time, timezone, status, file_path, row_count, error, created_at, started_at, completed_at
+`
+
+type CreateExportJobParams struct {
+	UserID     int32     `json:"user_id"`
+	Format     string    `json:"format"`
+	Columns    []string  `json:"columns"`
+	ShortCodes []string  `json:"short_codes"`
+	FromTime   time.Time `json:"from_time"`
+	ToTime     time.Time `json:"to_time"`
+	Timezone   string    `json:"timezone"`
+}
+
+func (q *Queries) CreateExportJob(ctx context.Context, arg CreateExportJobParams) (ExportJob, error) {
+	row := q.db.QueryRowContext(ctx, createExportJob,
+		arg.UserID,
+		arg.Format,
+		pq.Array(arg.Columns),
+		pq.Array(arg.ShortCodes),
+		arg.FromTime,
+		arg.ToTime,
+		arg.Timezone,
+	)
+	var i ExportJob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Format,
+		pq.Array(&i.Columns),
+		pq.Array(&i.ShortCodes),
+		&i.FromTime,
+		&i.ToTime,
+		&i.Timezone,
+		&i.Status,
+		&i.FilePath,
+		&i.RowCount,
+		&i.Error,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const createLinkHealthCheck = `-- name: CreateLinkHealthCheck :exec
+INSERT INTO link_health_checks (
+  url_id,
+  status,
+  status_code,
+  method,
+  latency_ms,
+  redirect_chain,
+  final_url,
+  error
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
 )
+`
+
+type CreateLinkHealthCheckParams struct {
+	UrlID         int32          `json:"url_id"`
+	Status        string         `json:"status"`
+	StatusCode    sql.NullInt32  `json:"status_code"`
+	Method        string         `json:"method"`
+	LatencyMs     int32          `json:"latency_ms"`
+	RedirectChain []string       `json:"redirect_chain"`
+	FinalUrl      sql.NullString `json:"final_url"`
+	Error         sql.NullString `json:"error"`
+}
+
+func (q *Queries) CreateLinkHealthCheck(ctx context.Context, arg CreateLinkHealthCheckParams) error {
+	_, err := q.db.ExecContext(ctx, createLinkHealthCheck,
+		arg.UrlID,
+		arg.Status,
+		arg.StatusCode,
+		arg.Method,
+		arg.LatencyMs,
+		pq.Array(arg.RedirectChain),
+		arg.FinalUrl,
+		arg.Error,
+	)
+	return err
+}
 
 const createOAuthUser = `-- name: CreateOAuthUser :one
-INSERT INTO users (username, email,password_hash, ip_address, provider, provider_id, image)
-VALUES ($1, $2, $3, $4, $5, $6,$7)
-RETURNING id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at
+INSERT INTO users (username, email,password_hash, ip_address, provider, provider_id, image, email_verified_at)
+VALUES ($1, $2, $3, $4, $5, $6,$7, now())
+RETURNING id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at, verification_sent_at, session_version
 `
 
 type CreateOAuthUserParams struct {
@@ -49,6 +347,222 @@ func (q *Queries) CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams
 		&i.Image,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.VerificationSentAt,
+		&i.SessionVersion,
+	)
+	return i, err
+}
+
+const createPasswordResetRequest = `-- name: CreatePasswordResetRequest :exec
+INSERT INTO password_reset_requests (email, ip_address)
+VALUES ($1, $2)
+`
+
+type CreatePasswordResetRequestParams struct {
+	Email     string `json:"email"`
+	IpAddress string `json:"ip_address"`
+}
+
+func (q *Queries) CreatePasswordResetRequest(ctx context.Context, arg CreatePasswordResetRequestParams) error {
+	_, err := q.db.ExecContext(ctx, createPasswordResetRequest, arg.Email, arg.IpAddress)
+	return err
+}
+
+const createPasswordResetToken = `-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3)
+`
+
+type CreatePasswordResetTokenParams struct {
+	UserID    int32     `json:"user_id"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createPasswordResetToken, arg.UserID, arg.TokenHash, arg.ExpiresAt)
+	return err
+}
+
+const createPayloadLink = `-- name: CreatePayloadLink :one
+INSERT INTO urls (
+  original_url,
+  short_code,
+  user_id,
+  payload_type,
+  payload,
+  click_count
+) VALUES (
+  $1, $2, $3, $4, $5, 0
+)
+RETURNING id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id, health_status, health_checked_at, backup_url, safety_status, safety_reason, safety_checked_at, always_preview, preview_count, og_title, og_description, og_image, thumbnail_checked_at, payload_type, payload, health_streak
+`
+
+type CreatePayloadLinkParams struct {
+	OriginalUrl string          `json:"original_url"`
+	ShortCode   string          `json:"short_code"`
+	UserID      sql.NullInt32   `json:"user_id"`
+	PayloadType sql.NullString  `json:"payload_type"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+func (q *Queries) CreatePayloadLink(ctx context.Context, arg CreatePayloadLinkParams) (Url, error) {
+	row := q.db.QueryRowContext(ctx, createPayloadLink,
+		arg.OriginalUrl,
+		arg.ShortCode,
+		arg.UserID,
+		arg.PayloadType,
+		arg.Payload,
+	)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.Title,
+		&i.ShortCode,
+		&i.Thumbnail,
+		&i.ClickCount,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.ExpireAt,
+		&i.UserID,
+		&i.HealthStatus,
+		&i.HealthCheckedAt,
+		&i.BackupUrl,
+		&i.SafetyStatus,
+		&i.SafetyReason,
+		&i.SafetyCheckedAt,
+		&i.AlwaysPreview,
+		&i.PreviewCount,
+		&i.OgTitle,
+		&i.OgDescription,
+		&i.OgImage,
+		&i.ThumbnailCheckedAt,
+		&i.PayloadType,
+		&i.Payload,
+		&i.HealthStreak,
+	)
+	return i, err
+}
+
+const createQRCode = `-- name: CreateQRCode :one
+INSERT INTO qr_codes (
+  url_id,
+  qr_code,
+  fg_color,
+  bg_color,
+  logo_url,
+  size,
+  format,
+  error_correction,
+  expire_at,
+  logo_id,
+  style,
+  quiet_zone
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING id, url_id, qr_code, fg_color, bg_color, expire_at, created_at, logo_url, size, format, error_correction, updated_at, logo_id, style, quiet_zone
+`
+
+type CreateQRCodeParams struct {
+	UrlID           int32           `json:"url_id"`
+	QrCode          string          `json:"qr_code"`
+	FgColor         sql.NullString  `json:"fg_color"`
+	BgColor         sql.NullString  `json:"bg_color"`
+	LogoUrl         sql.NullString  `json:"logo_url"`
+	Size            int32           `json:"size"`
+	Format          string          `json:"format"`
+	ErrorCorrection string          `json:"error_correction"`
+	ExpireAt        sql.NullTime    `json:"expire_at"`
+	LogoID          sql.NullInt32   `json:"logo_id"`
+	Style           json.RawMessage `json:"style"`
+	QuietZone       int32           `json:"quiet_zone"`
+}
+
+func (q *Queries) CreateQRCode(ctx context.Context, arg CreateQRCodeParams) (QrCode, error) {
+	row := q.db.QueryRowContext(ctx, createQRCode,
+		arg.UrlID,
+		arg.QrCode,
+		arg.FgColor,
+		arg.BgColor,
+		arg.LogoUrl,
+		arg.Size,
+		arg.Format,
+		arg.ErrorCorrection,
+		arg.ExpireAt,
+		arg.LogoID,
+		arg.Style,
+		arg.QuietZone,
+	)
+	var i QrCode
+	err := row.Scan(
+		&i.ID,
+		&i.UrlID,
+		&i.QrCode,
+		&i.FgColor,
+		&i.BgColor,
+		&i.ExpireAt,
+		&i.CreatedAt,
+		&i.LogoUrl,
+		&i.Size,
+		&i.Format,
+		&i.ErrorCorrection,
+		&i.UpdatedAt,
+		&i.LogoID,
+		&i.Style,
+		&i.QuietZone,
+	)
+	return i, err
+}
+
+const createQRLogo = `-- name: CreateQRLogo :one
+INSERT INTO qr_logos (
+  user_id,
+  name,
+  storage_key,
+  content_type,
+  width,
+  height,
+  size_bytes
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING id, user_id, name, storage_key, content_type, width, height, size_bytes, created_at
+`
+
+type CreateQRLogoParams struct {
+	UserID      int32  `json:"user_id"`
+	Name        string `json:"name"`
+	StorageKey  string `json:"storage_key"`
+	ContentType string `json:"content_type"`
+	Width       int32  `json:"width"`
+	Height      int32  `json:"height"`
+	SizeBytes   int32  `json:"size_bytes"`
+}
+
+func (q *Queries) CreateQRLogo(ctx context.Context, arg CreateQRLogoParams) (QrLogo, error) {
+	row := q.db.QueryRowContext(ctx, createQRLogo,
+		arg.UserID,
+		arg.Name,
+		arg.StorageKey,
+		arg.ContentType,
+		arg.Width,
+		arg.Height,
+		arg.SizeBytes,
+	)
+	var i QrLogo
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.StorageKey,
+		&i.ContentType,
+		&i.Width,
+		&i.Height,
+		&i.SizeBytes,
+		&i.CreatedAt,
 	)
 	return i, err
 }
@@ -62,21 +576,38 @@ INSERT INTO urls (
   expire_at,
   user_id,
   thumbnail,
+  backup_url,
+  safety_status,
+  safety_reason,
+  safety_checked_at,
+  always_preview,
+  og_title,
+  og_description,
+  og_image,
+  thumbnail_checked_at,
   click_count
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, 0
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, now(), $11, $12, $13, $14,
+  CASE WHEN $7 IS NULL THEN NULL ELSE now() END, 0
 )
-RETURNING id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id
+RETURNING id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id, health_status, health_checked_at, backup_url, safety_status, safety_reason, safety_checked_at, always_preview, preview_count, og_title, og_description, og_image, thumbnail_checked_at, payload_type, payload, health_streak
 `
 
 type CreateShortURLParams struct {
-	OriginalUrl  string         `json:"original_url"`
-	ShortCode    string         `json:"short_code"`
-	Title        sql.NullString `json:"title"`
-	PasswordHash sql.NullString `json:"password_hash"`
-	ExpireAt     sql.NullTime   `json:"expire_at"`
-	UserID       sql.NullInt32  `json:"user_id"`
-	Thumbnail    sql.NullString `json:"thumbnail"`
+	OriginalUrl   string         `json:"original_url"`
+	ShortCode     string         `json:"short_code"`
+	Title         sql.NullString `json:"title"`
+	PasswordHash  sql.NullString `json:"password_hash"`
+	ExpireAt      sql.NullTime   `json:"expire_at"`
+	UserID        sql.NullInt32  `json:"user_id"`
+	Thumbnail     sql.NullString `json:"thumbnail"`
+	BackupUrl     sql.NullString `json:"backup_url"`
+	SafetyStatus  sql.NullString `json:"safety_status"`
+	SafetyReason  sql.NullString `json:"safety_reason"`
+	AlwaysPreview bool           `json:"always_preview"`
+	OgTitle       sql.NullString `json:"og_title"`
+	OgDescription sql.NullString `json:"og_description"`
+	OgImage       sql.NullString `json:"og_image"`
 }
 
 func (q *Queries) CreateShortURL(ctx context.Context, arg CreateShortURLParams) (Url, error) {
@@ -88,6 +619,13 @@ func (q *Queries) CreateShortURL(ctx context.Context, arg CreateShortURLParams)
 		arg.ExpireAt,
 		arg.UserID,
 		arg.Thumbnail,
+		arg.BackupUrl,
+		arg.SafetyStatus,
+		arg.SafetyReason,
+		arg.AlwaysPreview,
+		arg.OgTitle,
+		arg.OgDescription,
+		arg.OgImage,
 	)
 	var i Url
 	err := row.Scan(
@@ -101,6 +639,21 @@ func (q *Queries) CreateShortURL(ctx context.Context, arg CreateShortURLParams)
 		&i.CreatedAt,
 		&i.ExpireAt,
 		&i.UserID,
+		&i.HealthStatus,
+		&i.HealthCheckedAt,
+		&i.BackupUrl,
+		&i.SafetyStatus,
+		&i.SafetyReason,
+		&i.SafetyCheckedAt,
+		&i.AlwaysPreview,
+		&i.PreviewCount,
+		&i.OgTitle,
+		&i.OgDescription,
+		&i.OgImage,
+		&i.ThumbnailCheckedAt,
+		&i.PayloadType,
+		&i.Payload,
+		&i.HealthStreak,
 	)
 	return i, err
 }
@@ -149,7 +702,7 @@ func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionPa
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, email, password_hash, ip_address)
 VALUES ($1, $2, $3, $4)
-RETURNING id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at
+RETURNING id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at, verification_sent_at, session_version
 `
 
 type CreateUserParams struct {
@@ -178,64 +731,283 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Image,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.VerificationSentAt,
+		&i.SessionVersion,
 	)
 	return i, err
 }
 
-const deleteURLByShortCode = `-- name: DeleteURLByShortCode :exec
-DELETE FROM urls
-WHERE short_code = $1
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+  endpoint_id,
+  event,
+  payload
+) VALUES (
+  $1, $2, $3
+)
+RETURNING id, endpoint_id, event, payload, status, attempts, next_attempt_at, response_code, response_body, error, created_at, delivered_at
 `
 
-func (q *Queries) DeleteURLByShortCode(ctx context.Context, shortCode string) error {
-	_, err := q.db.ExecContext(ctx, deleteURLByShortCode, shortCode)
-	return err
+type CreateWebhookDeliveryParams struct {
+	EndpointID int32           `json:"endpoint_id"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload"`
 }
 
-const getAnalyticsShortcode = `-- name: GetAnalyticsShortcode :many
-SELECT 
-  u.short_code, 
-  u.original_url, 
-  u.click_count, 
-  v.clicked_at, 
-  v.ip_address, 
-  v.user_agent,
-  v.referrer,
-  v.country,
-  v.region,
-  v.city,
-  u.user_id
-FROM urls u
-JOIN url_visits v ON u.id = v.url_id
-WHERE u.short_code = $1
-ORDER BY v.clicked_at DESC
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, createWebhookDelivery, arg.EndpointID, arg.Event, arg.Payload)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.EndpointID,
+		&i.Event,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseCode,
+		&i.ResponseBody,
+		&i.Error,
+		&i.CreatedAt,
+		&i.DeliveredAt,
+	)
+	return i, err
+}
+
+const createWebhookEndpoint = `-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (
+  user_id,
+  url,
+  secret,
+  events
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING id, user_id, url, secret, events, active, created_at
 `
 
-type GetAnalyticsShortcodeRow struct {
-	ShortCode   string         `json:"short_code"`
-	OriginalUrl string         `json:"original_url"`
-	ClickCount  int32          `json:"click_count"`
-	ClickedAt   sql.NullTime   `json:"clicked_at"`
-	IpAddress   sql.NullString `json:"ip_address"`
-	UserAgent   sql.NullString `json:"user_agent"`
-	Referrer    sql.NullString `json:"referrer"`
-	Country     sql.NullString `json:"country"`
-	Region      sql.NullString `json:"region"`
-	City        sql.NullString `json:"city"`
-	UserID      sql.NullInt32  `json:"user_id"`
+type CreateWebhookEndpointParams struct {
+	UserID int32    `json:"user_id"`
+	Url    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
 }
 
-func (q *Queries) GetAnalyticsShortcode(ctx context.Context, shortCode string) ([]GetAnalyticsShortcodeRow, error) {
-	rows, err := q.db.QueryContext(ctx, getAnalyticsShortcode, shortCode)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []GetAnalyticsShortcodeRow
-	for rows.Next() {
-		var i GetAnalyticsShortcodeRow
-		if err := rows.Scan(
-			&i.ShortCode,
+func (q *Queries) CreateWebhookEndpoint(ctx context.Context, arg CreateWebhookEndpointParams) (WebhookEndpoint, error) {
+	row := q.db.QueryRowContext(ctx, createWebhookEndpoint,
+		arg.UserID,
+		arg.Url,
+		arg.Secret,
+		pq.Array(arg.Events),
+	)
+	var i WebhookEndpoint
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		pq.Array(&i.Events),
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteExpiredExportJobs = `-- name: DeleteExpiredExportJobs :many
+DELETE FROM export_jobs
+WHERE status IN ('completed', 'failed')
+  AND completed_at < $1::timestamptz
+RETURNING file_path
+`
+
+func (q *Queries) DeleteExpiredExportJobs(ctx context.Context, completedBefore time.Time) ([]sql.NullString, error) {
+	rows, err := q.db.QueryContext(ctx, deleteExpiredExportJobs, completedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sql.NullString
+	for rows.Next() {
+		var file_path sql.NullString
+		if err := rows.Scan(&file_path); err != nil {
+			return nil, err
+		}
+		items = append(items, file_path)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteExpiredPasswordResetTokens = `-- name: DeleteExpiredPasswordResetTokens :exec
+DELETE FROM password_reset_tokens
+WHERE expires_at < now()
+`
+
+func (q *Queries) DeleteExpiredPasswordResetTokens(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredPasswordResetTokens)
+	return err
+}
+
+const deleteLinkHealthChecksBefore = `-- name: DeleteLinkHealthChecksBefore :exec
+DELETE FROM link_health_checks
+WHERE checked_at < $1
+`
+
+func (q *Queries) DeleteLinkHealthChecksBefore(ctx context.Context, checkedAt time.Time) error {
+	_, err := q.db.ExecContext(ctx, deleteLinkHealthChecksBefore, checkedAt)
+	return err
+}
+
+const deletePasswordResetRequestsBefore = `-- name: DeletePasswordResetRequestsBefore :exec
+DELETE FROM password_reset_requests
+WHERE requested_at < $1
+`
+
+func (q *Queries) DeletePasswordResetRequestsBefore(ctx context.Context, requestedAt time.Time) error {
+	_, err := q.db.ExecContext(ctx, deletePasswordResetRequestsBefore, requestedAt)
+	return err
+}
+
+const deletePasswordResetTokensByUser = `-- name: DeletePasswordResetTokensByUser :exec
+DELETE FROM password_reset_tokens
+WHERE user_id = $1
+`
+
+func (q *Queries) DeletePasswordResetTokensByUser(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, deletePasswordResetTokensByUser, userID)
+	return err
+}
+
+const deleteQRCode = `-- name: DeleteQRCode :exec
+DELETE FROM qr_codes
+WHERE id = $1
+`
+
+func (q *Queries) DeleteQRCode(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, deleteQRCode, id)
+	return err
+}
+
+const deleteQRLogo = `-- name: DeleteQRLogo :exec
+DELETE FROM qr_logos
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteQRLogoParams struct {
+	ID     int32 `json:"id"`
+	UserID int32 `json:"user_id"`
+}
+
+func (q *Queries) DeleteQRLogo(ctx context.Context, arg DeleteQRLogoParams) error {
+	_, err := q.db.ExecContext(ctx, deleteQRLogo, arg.ID, arg.UserID)
+	return err
+}
+
+const deleteURLByShortCode = `-- name: DeleteURLByShortCode :exec
+DELETE FROM urls
+WHERE short_code = $1
+`
+
+func (q *Queries) DeleteURLByShortCode(ctx context.Context, shortCode string) error {
+	_, err := q.db.ExecContext(ctx, deleteURLByShortCode, shortCode)
+	return err
+}
+
+const deleteWebhookEndpoint = `-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteWebhookEndpointParams struct {
+	ID     int32 `json:"id"`
+	UserID int32 `json:"user_id"`
+}
+
+func (q *Queries) DeleteWebhookEndpoint(ctx context.Context, arg DeleteWebhookEndpointParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteWebhookEndpoint, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const endLinkFailover = `-- name: EndLinkFailover :exec
+UPDATE link_failovers
+SET ended_at = now()
+WHERE url_id = $1 AND ended_at IS NULL
+`
+
+func (q *Queries) EndLinkFailover(ctx context.Context, urlID int32) error {
+	_, err := q.db.ExecContext(ctx, endLinkFailover, urlID)
+	return err
+}
+
+const failExportJob = `-- name: FailExportJob :exec
+UPDATE export_jobs
+SET status = 'failed', error = $2, completed_at = now()
+WHERE id = $1
+`
+
+type FailExportJobParams struct {
+	ID    int32          `json:"id"`
+	Error sql.NullString `json:"error"`
+}
+
+func (q *Queries) FailExportJob(ctx context.Context, arg FailExportJobParams) error {
+	_, err := q.db.ExecContext(ctx, failExportJob, arg.ID, arg.Error)
+	return err
+}
+
+const getAnalyticsShortcode = `-- name: GetAnalyticsShortcode :many
+SELECT 
+  u.short_code, 
+  u.original_url, 
+  u.click_count, 
+  v.clicked_at, 
+  v.ip_address, 
+  v.user_agent,
+  v.referrer,
+  v.country,
+  v.region,
+  v.city,
+  u.user_id
+FROM urls u
+JOIN url_visits v ON u.id = v.url_id
+WHERE u.short_code = $1
+ORDER BY v.clicked_at DESC
+`
+
+type GetAnalyticsShortcodeRow struct {
+	ShortCode   string         `json:"short_code"`
+	OriginalUrl string         `json:"original_url"`
+	ClickCount  int32          `json:"click_count"`
+	ClickedAt   sql.NullTime   `json:"clicked_at"`
+	IpAddress   sql.NullString `json:"ip_address"`
+	UserAgent   sql.NullString `json:"user_agent"`
+	Referrer    sql.NullString `json:"referrer"`
+	Country     sql.NullString `json:"country"`
+	Region      sql.NullString `json:"region"`
+	City        sql.NullString `json:"city"`
+	UserID      sql.NullInt32  `json:"user_id"`
+}
+
+func (q *Queries) GetAnalyticsShortcode(ctx context.Context, shortCode string) ([]GetAnalyticsShortcodeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAnalyticsShortcode, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAnalyticsShortcodeRow
+	for rows.Next() {
+		var i GetAnalyticsShortcodeRow
+		if err := rows.Scan(
+			&i.ShortCode,
 			&i.OriginalUrl,
 			&i.ClickCount,
 			&i.ClickedAt,
@@ -260,40 +1032,54 @@ func (q *Queries) GetAnalyticsShortcode(ctx context.Context, shortCode string) (
 	return items, nil
 }
 
-const getCountryStatsScoped = `-- name: GetCountryStatsScoped :many
+const getClickHeatmapByUser = `-- name: GetClickHeatmapByUser :many
 SELECT
-  COALESCE(uv.country, 'unknown') AS country,
-  COUNT(*) AS clicks
-FROM url_visits uv
-JOIN urls u ON u.id = uv.url_id
-WHERE u.short_code = $1 AND u.user_id = $2
-  AND uv.clicked_at >= NOW() - ($3::int * INTERVAL '1 day')
-GROUP BY country
-ORDER BY clicks DESC
-LIMIT 10
+  EXTRACT(ISODOW FROM v.clicked_at AT TIME ZONE $1::text)::int AS weekday,
+  EXTRACT(HOUR FROM v.clicked_at AT TIME ZONE $1::text)::int AS hour,
+  COUNT(*)::BIGINT AS clicks,
+  COUNT(DISTINCT v.ip_address)::BIGINT AS uniques
+FROM url_visits v
+JOIN urls u ON v.url_id = u.id
+WHERE u.user_id = $2
+  AND v.clicked_at >= $3::timestamptz
+  AND v.clicked_at < $4::timestamptz
+GROUP BY 1, 2
 `
 
-type GetCountryStatsScopedParams struct {
-	ShortCode string        `json:"short_code"`
-	UserID    sql.NullInt32 `json:"user_id"`
-	Days      int32         `json:"days"`
+type GetClickHeatmapByUserParams struct {
+	Tz       string        `json:"tz"`
+	UserID   sql.NullInt32 `json:"user_id"`
+	FromTime time.Time     `json:"from_time"`
+	ToTime   time.Time     `json:"to_time"`
 }
 
-type GetCountryStatsScopedRow struct {
-	Country string `json:"country"`
-	Clicks  int64  `json:"clicks"`
+type GetClickHeatmapByUserRow struct {
+	Weekday int32 `json:"weekday"`
+	Hour    int32 `json:"hour"`
+	Clicks  int64 `json:"clicks"`
+	Uniques int64 `json:"uniques"`
 }
 
-func (q *Queries) GetCountryStatsScoped(ctx context.Context, arg GetCountryStatsScopedParams) ([]GetCountryStatsScopedRow, error) {
-	rows, err := q.db.QueryContext(ctx, getCountryStatsScoped, arg.ShortCode, arg.UserID, arg.Days)
+func (q *Queries) GetClickHeatmapByUser(ctx context.Context, arg GetClickHeatmapByUserParams) ([]GetClickHeatmapByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickHeatmapByUser,
+		arg.Tz,
+		arg.UserID,
+		arg.FromTime,
+		arg.ToTime,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetCountryStatsScopedRow
+	var items []GetClickHeatmapByUserRow
 	for rows.Next() {
-		var i GetCountryStatsScopedRow
-		if err := rows.Scan(&i.Country, &i.Clicks); err != nil {
+		var i GetClickHeatmapByUserRow
+		if err := rows.Scan(
+			&i.Weekday,
+			&i.Hour,
+			&i.Clicks,
+			&i.Uniques,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -307,39 +1093,56 @@ func (q *Queries) GetCountryStatsScoped(ctx context.Context, arg GetCountryStats
 	return items, nil
 }
 
-const getCountryVisitCountsByUser = `-- name: GetCountryVisitCountsByUser :many
+const getClickHeatmapScoped = `-- name: GetClickHeatmapScoped :many
 SELECT
-  COALESCE(uv.country, '') AS country,
-  COUNT(*) AS clicks
-FROM url_visits uv
-JOIN urls u ON uv.url_id = u.id
-WHERE u.user_id = $1
-  AND uv.clicked_at >= NOW() - ($2::int * INTERVAL '1 day')
-  AND uv.country IS NOT NULL
-GROUP BY country
-ORDER BY clicks DESC
+  EXTRACT(ISODOW FROM v.clicked_at AT TIME ZONE $1::text)::int AS weekday,
+  EXTRACT(HOUR FROM v.clicked_at AT TIME ZONE $1::text)::int AS hour,
+  COUNT(*)::BIGINT AS clicks,
+  COUNT(DISTINCT v.ip_address)::BIGINT AS uniques
+FROM url_visits v
+JOIN urls u ON u.id = v.url_id
+WHERE u.short_code = $2 AND u.user_id = $3
+  AND v.clicked_at >= $4::timestamptz
+  AND v.clicked_at < $5::timestamptz
+GROUP BY 1, 2
 `
 
-type GetCountryVisitCountsByUserParams struct {
-	UserID sql.NullInt32 `json:"user_id"`
-	Days   int32         `json:"days"`
+type GetClickHeatmapScopedParams struct {
+	Tz        string        `json:"tz"`
+	ShortCode string        `json:"short_code"`
+	UserID    sql.NullInt32 `json:"user_id"`
+	FromTime  time.Time     `json:"from_time"`
+	ToTime    time.Time     `json:"to_time"`
 }
 
-type GetCountryVisitCountsByUserRow struct {
-	Country string `json:"country"`
-	Clicks  int64  `json:"clicks"`
+type GetClickHeatmapScopedRow struct {
+	Weekday int32 `json:"weekday"`
+	Hour    int32 `json:"hour"`
+	Clicks  int64 `json:"clicks"`
+	Uniques int64 `json:"uniques"`
 }
 
-func (q *Queries) GetCountryVisitCountsByUser(ctx context.Context, arg GetCountryVisitCountsByUserParams) ([]GetCountryVisitCountsByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getCountryVisitCountsByUser, arg.UserID, arg.Days)
+func (q *Queries) GetClickHeatmapScoped(ctx context.Context, arg GetClickHeatmapScopedParams) ([]GetClickHeatmapScopedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickHeatmapScoped,
+		arg.Tz,
+		arg.ShortCode,
+		arg.UserID,
+		arg.FromTime,
+		arg.ToTime,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetCountryVisitCountsByUserRow
+	var items []GetClickHeatmapScopedRow
 	for rows.Next() {
-		var i GetCountryVisitCountsByUserRow
-		if err := rows.Scan(&i.Country, &i.Clicks); err != nil {
+		var i GetClickHeatmapScopedRow
+		if err := rows.Scan(
+			&i.Weekday,
+			&i.Hour,
+			&i.Clicks,
+			&i.Uniques,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -353,48 +1156,48 @@ func (q *Queries) GetCountryVisitCountsByUser(ctx context.Context, arg GetCountr
 	return items, nil
 }
 
-const getDailyClicksAndLinksByUser = `-- name: GetDailyClicksAndLinksByUser :many
+const getClickSeriesByUser = `-- name: GetClickSeriesByUser :many
 SELECT
-  d::date AS date,
-  COALESCE(clicks.count, 0) AS clicks,
-  COALESCE(links.count, 0) AS links
-FROM generate_series(
-         CURRENT_DATE - INTERVAL '6 days',
-         CURRENT_DATE,
-         INTERVAL '1 day'
-     ) AS d
-LEFT JOIN (
-    SELECT uv.clicked_at::date AS date, COUNT(*) AS count
-    FROM url_visits uv
-    INNER JOIN urls u ON uv.url_id = u.id
-    WHERE u.user_id = $1
-    GROUP BY uv.clicked_at::date
-) AS clicks ON clicks.date = d
-LEFT JOIN (
-    SELECT created_at::date AS date, COUNT(*) AS count
-    FROM urls
-    WHERE user_id = $1
-    GROUP BY created_at::date
-) AS links ON links.date = d
-ORDER BY d
-`
-
-type GetDailyClicksAndLinksByUserRow struct {
-	Date   time.Time `json:"date"`
+  date_trunc($1::text, h.bucket AT TIME ZONE $2::text)::timestamp AS bucket,
+  SUM(h.clicks)::BIGINT AS clicks
+FROM url_visit_rollups h
+INNER JOIN urls u ON h.url_id = u.id
+WHERE u.user_id = $3
+  AND h.bucket >= $4::timestamptz
+  AND h.bucket < $5::timestamptz
+GROUP BY bucket
+ORDER BY bucket
+`
+
+type GetClickSeriesByUserParams struct {
+	Granularity string        `json:"granularity"`
+	Tz          string        `json:"tz"`
+	UserID      sql.NullInt32 `json:"user_id"`
+	FromTime    time.Time     `json:"from_time"`
+	ToTime      time.Time     `json:"to_time"`
+}
+
+type GetClickSeriesByUserRow struct {
+	Bucket time.Time `json:"bucket"`
 	Clicks int64     `json:"clicks"`
-	Links  int64     `json:"links"`
 }
 
-func (q *Queries) GetDailyClicksAndLinksByUser(ctx context.Context, userID sql.NullInt32) ([]GetDailyClicksAndLinksByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getDailyClicksAndLinksByUser, userID)
+func (q *Queries) GetClickSeriesByUser(ctx context.Context, arg GetClickSeriesByUserParams) ([]GetClickSeriesByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickSeriesByUser,
+		arg.Granularity,
+		arg.Tz,
+		arg.UserID,
+		arg.FromTime,
+		arg.ToTime,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetDailyClicksAndLinksByUserRow
+	var items []GetClickSeriesByUserRow
 	for rows.Next() {
-		var i GetDailyClicksAndLinksByUserRow
-		if err := rows.Scan(&i.Date, &i.Clicks, &i.Links); err != nil {
+		var i GetClickSeriesByUserRow
+		if err := rows.Scan(&i.Bucket, &i.Clicks); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -408,38 +1211,50 @@ func (q *Queries) GetDailyClicksAndLinksByUser(ctx context.Context, userID sql.N
 	return items, nil
 }
 
-const getDailyClicksScoped = `-- name: GetDailyClicksScoped :many
+const getClickSeriesScoped = `-- name: GetClickSeriesScoped :many
 SELECT
-  DATE(uv.clicked_at) AS date,
-  COUNT(*) AS clicks
-FROM url_visits uv
-JOIN urls u ON u.id = uv.url_id
-WHERE u.short_code = $1 AND u.user_id = $2
-GROUP BY date
-ORDER BY date DESC
-LIMIT 7
+  date_trunc($1::text, h.bucket AT TIME ZONE $2::text)::timestamp AS bucket,
+  SUM(h.clicks)::BIGINT AS clicks
+FROM url_visit_rollups h
+JOIN urls u ON u.id = h.url_id
+WHERE u.short_code = $3 AND u.user_id = $4
+  AND h.bucket >= $5::timestamptz
+  AND h.bucket < $6::timestamptz
+GROUP BY bucket
+ORDER BY bucket
 `
 
-type GetDailyClicksScopedParams struct {
-	ShortCode string        `json:"short_code"`
-	UserID    sql.NullInt32 `json:"user_id"`
+type GetClickSeriesScopedParams struct {
+	Granularity string        `json:"granularity"`
+	Tz          string        `json:"tz"`
+	ShortCode   string        `json:"short_code"`
+	UserID      sql.NullInt32 `json:"user_id"`
+	FromTime    time.Time     `json:"from_time"`
+	ToTime      time.Time     `json:"to_time"`
 }
 
-type GetDailyClicksScopedRow struct {
-	Date   time.Time `json:"date"`
+type GetClickSeriesScopedRow struct {
+	Bucket time.Time `json:"bucket"`
 	Clicks int64     `json:"clicks"`
 }
 
-func (q *Queries) GetDailyClicksScoped(ctx context.Context, arg GetDailyClicksScopedParams) ([]GetDailyClicksScopedRow, error) {
-	rows, err := q.db.QueryContext(ctx, getDailyClicksScoped, arg.ShortCode, arg.UserID)
+func (q *Queries) GetClickSeriesScoped(ctx context.Context, arg GetClickSeriesScopedParams) ([]GetClickSeriesScopedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getClickSeriesScoped,
+		arg.Granularity,
+		arg.Tz,
+		arg.ShortCode,
+		arg.UserID,
+		arg.FromTime,
+		arg.ToTime,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetDailyClicksScopedRow
+	var items []GetClickSeriesScopedRow
 	for rows.Next() {
-		var i GetDailyClicksScopedRow
-		if err := rows.Scan(&i.Date, &i.Clicks); err != nil {
+		var i GetClickSeriesScopedRow
+		if err := rows.Scan(&i.Bucket, &i.Clicks); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -453,64 +1268,47 @@ func (q *Queries) GetDailyClicksScoped(ctx context.Context, arg GetDailyClicksSc
 	return items, nil
 }
 
-const getDashboardSummaryByUser = `-- name: GetDashboardSummaryByUser :one
+const getCountryStatsScoped = `-- name: GetCountryStatsScoped :many
 SELECT
-  COUNT(*) AS total_links,
-  COALESCE(SUM(click_count), 0)::BIGINT AS total_clicks,
-  COUNT(*) FILTER (
-    WHERE (expire_at IS NULL OR expire_at > now())
-  ) AS active_links,
-  COUNT(*) FILTER (
-    WHERE expire_at IS NOT NULL AND expire_at <= now()
-  ) AS expired_links
-FROM urls
-WHERE user_id = $1
+  COALESCE(NULLIF(d.country, ''), 'unknown')::text AS country,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON u.id = d.url_id
+WHERE u.short_code = $1 AND u.user_id = $2
+  AND d.bucket >= $3::timestamptz
+  AND d.bucket < $4::timestamptz
+GROUP BY 1
+ORDER BY clicks DESC
+LIMIT 10
 `
 
-type GetDashboardSummaryByUserRow struct {
-	TotalLinks   int64 `json:"total_links"`
-	TotalClicks  int64 `json:"total_clicks"`
-	ActiveLinks  int64 `json:"active_links"`
-	ExpiredLinks int64 `json:"expired_links"`
-}
-
-func (q *Queries) GetDashboardSummaryByUser(ctx context.Context, userID sql.NullInt32) (GetDashboardSummaryByUserRow, error) {
-	row := q.db.QueryRowContext(ctx, getDashboardSummaryByUser, userID)
-	var i GetDashboardSummaryByUserRow
-	err := row.Scan(
-		&i.TotalLinks,
-		&i.TotalClicks,
-		&i.ActiveLinks,
-		&i.ExpiredLinks,
-	)
-	return i, err
+type GetCountryStatsScopedParams struct {
+	ShortCode string        `json:"short_code"`
+	UserID    sql.NullInt32 `json:"user_id"`
+	FromTime  time.Time     `json:"from_time"`
+	ToTime    time.Time     `json:"to_time"`
 }
 
-const getDeviceStatsByShortcode = `-- name: GetDeviceStatsByShortcode :many
-SELECT
-  COALESCE(uv.device_type, 'unknown') AS device_type,
-  COUNT(*) AS count
-FROM url_visits uv
-INNER JOIN urls u ON uv.url_id = u.id
-WHERE u.short_code = $1
-GROUP BY device_type
-`
-
-type GetDeviceStatsByShortcodeRow struct {
-	DeviceType string `json:"device_type"`
-	Count      int64  `json:"count"`
+type GetCountryStatsScopedRow struct {
+	Country string `json:"country"`
+	Clicks  int64  `json:"clicks"`
 }
 
-func (q *Queries) GetDeviceStatsByShortcode(ctx context.Context, shortCode string) ([]GetDeviceStatsByShortcodeRow, error) {
-	rows, err := q.db.QueryContext(ctx, getDeviceStatsByShortcode, shortCode)
+func (q *Queries) GetCountryStatsScoped(ctx context.Context, arg GetCountryStatsScopedParams) ([]GetCountryStatsScopedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCountryStatsScoped,
+		arg.ShortCode,
+		arg.UserID,
+		arg.FromTime,
+		arg.ToTime,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetDeviceStatsByShortcodeRow
+	var items []GetCountryStatsScopedRow
 	for rows.Next() {
-		var i GetDeviceStatsByShortcodeRow
-		if err := rows.Scan(&i.DeviceType, &i.Count); err != nil {
+		var i GetCountryStatsScopedRow
+		if err := rows.Scan(&i.Country, &i.Clicks); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -524,19 +1322,125 @@ func (q *Queries) GetDeviceStatsByShortcode(ctx context.Context, shortCode strin
 	return items, nil
 }
 
-const getDeviceStatsScoped = `-- name: GetDeviceStatsScoped :many
+const getCountryVisitCountsByUser = `-- name: GetCountryVisitCountsByUser :many
 SELECT
-  COALESCE(uv.device_type, 'unknown') AS device_type,
-  COUNT(*) AS count
-FROM url_visits uv
-JOIN urls u ON u.id = uv.url_id
-WHERE u.short_code = $1 AND u.user_id = $2
-GROUP BY uv.device_type
+  d.country,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON d.url_id = u.id
+WHERE u.user_id = $1
+  AND d.bucket >= $2::timestamptz
+  AND d.bucket < $3::timestamptz
+  AND d.country <> ''
+GROUP BY d.country
+ORDER BY clicks DESC
+`
+
+type GetCountryVisitCountsByUserParams struct {
+	UserID   sql.NullInt32 `json:"user_id"`
+	FromTime time.Time     `json:"from_time"`
+	ToTime   time.Time     `json:"to_time"`
+}
+
+type GetCountryVisitCountsByUserRow struct {
+	Country string `json:"country"`
+	Clicks  int64  `json:"clicks"`
+}
+
+func (q *Queries) GetCountryVisitCountsByUser(ctx context.Context, arg GetCountryVisitCountsByUserParams) ([]GetCountryVisitCountsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCountryVisitCountsByUser, arg.UserID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCountryVisitCountsByUserRow
+	for rows.Next() {
+		var i GetCountryVisitCountsByUserRow
+		if err := rows.Scan(&i.Country, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDashboardSummaryByUser = `-- name: GetDashboardSummaryByUser :one
+SELECT
+  COUNT(*) AS total_links,
+  COALESCE(SUM(click_count), 0)::BIGINT AS total_clicks,
+  COUNT(*) FILTER (
+    WHERE (expire_at IS NULL OR expire_at > now())
+  ) AS active_links,
+  COUNT(*) FILTER (
+    WHERE expire_at IS NOT NULL AND expire_at <= now()
+  ) AS expired_links,
+  COUNT(*) FILTER (
+    WHERE created_at >= $1::timestamptz AND created_at < $2::timestamptz
+  ) AS period_links,
+  (
+    SELECT COALESCE(SUM(h.clicks), 0)
+    FROM url_visit_rollups h
+    JOIN urls pu ON pu.id = h.url_id
+    WHERE pu.user_id = $3
+      AND h.bucket >= $1::timestamptz
+      AND h.bucket < $2::timestamptz
+  )::BIGINT AS period_clicks
+FROM urls
+WHERE user_id = $3
+`
+
+type GetDashboardSummaryByUserParams struct {
+	FromTime time.Time     `json:"from_time"`
+	ToTime   time.Time     `json:"to_time"`
+	UserID   sql.NullInt32 `json:"user_id"`
+}
+
+type GetDashboardSummaryByUserRow struct {
+	TotalLinks   int64 `json:"total_links"`
+	TotalClicks  int64 `json:"total_clicks"`
+	ActiveLinks  int64 `json:"active_links"`
+	ExpiredLinks int64 `json:"expired_links"`
+	PeriodLinks  int64 `json:"period_links"`
+	PeriodClicks int64 `json:"period_clicks"`
+}
+
+func (q *Queries) GetDashboardSummaryByUser(ctx context.Context, arg GetDashboardSummaryByUserParams) (GetDashboardSummaryByUserRow, error) {
+	row := q.db.QueryRowContext(ctx, getDashboardSummaryByUser, arg.FromTime, arg.ToTime, arg.UserID)
+	var i GetDashboardSummaryByUserRow
+	err := row.Scan(
+		&i.TotalLinks,
+		&i.TotalClicks,
+		&i.ActiveLinks,
+		&i.ExpiredLinks,
+		&i.PeriodLinks,
+		&i.PeriodClicks,
+	)
+	return i, err
+}
+
+const getDeviceStatsScoped = `-- name: GetDeviceStatsScoped :many
+SELECT
+  COALESCE(NULLIF(d.device_type, ''), 'unknown')::text AS device_type,
+  SUM(d.clicks)::BIGINT AS count
+FROM url_visit_rollup_dims d
+JOIN urls u ON u.id = d.url_id
+WHERE u.short_code = $1 AND u.user_id = $2
+  AND d.bucket >= $3::timestamptz
+  AND d.bucket < $4::timestamptz
+GROUP BY 1
 `
 
 type GetDeviceStatsScopedParams struct {
 	ShortCode string        `json:"short_code"`
 	UserID    sql.NullInt32 `json:"user_id"`
+	FromTime  time.Time     `json:"from_time"`
+	ToTime    time.Time     `json:"to_time"`
 }
 
 type GetDeviceStatsScopedRow struct {
@@ -545,7 +1449,12 @@ type GetDeviceStatsScopedRow struct {
 }
 
 func (q *Queries) GetDeviceStatsScoped(ctx context.Context, arg GetDeviceStatsScopedParams) ([]GetDeviceStatsScopedRow, error) {
-	rows, err := q.db.QueryContext(ctx, getDeviceStatsScoped, arg.ShortCode, arg.UserID)
+	rows, err := q.db.QueryContext(ctx, getDeviceStatsScoped,
+		arg.ShortCode,
+		arg.UserID,
+		arg.FromTime,
+		arg.ToTime,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -607,21 +1516,29 @@ func (q *Queries) GetDeviceTypeStatsByShortCode(ctx context.Context, shortCode s
 
 const getDeviceTypeStatsByUser = `-- name: GetDeviceTypeStatsByUser :many
 SELECT
-  COALESCE(uv.device_type, 'unknown') AS device_type,
-  COUNT(*) AS count
-FROM url_visits uv
-INNER JOIN urls u ON uv.url_id = u.id
+  COALESCE(NULLIF(d.device_type, ''), 'unknown')::text AS device_type,
+  SUM(d.clicks)::BIGINT AS count
+FROM url_visit_rollup_dims d
+INNER JOIN urls u ON d.url_id = u.id
 WHERE u.user_id = $1
-GROUP BY uv.device_type
+  AND d.bucket >= $2::timestamptz
+  AND d.bucket < $3::timestamptz
+GROUP BY 1
 `
 
+type GetDeviceTypeStatsByUserParams struct {
+	UserID   sql.NullInt32 `json:"user_id"`
+	FromTime time.Time     `json:"from_time"`
+	ToTime   time.Time     `json:"to_time"`
+}
+
 type GetDeviceTypeStatsByUserRow struct {
 	DeviceType string `json:"device_type"`
 	Count      int64  `json:"count"`
 }
 
-func (q *Queries) GetDeviceTypeStatsByUser(ctx context.Context, userID sql.NullInt32) ([]GetDeviceTypeStatsByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getDeviceTypeStatsByUser, userID)
+func (q *Queries) GetDeviceTypeStatsByUser(ctx context.Context, arg GetDeviceTypeStatsByUserParams) ([]GetDeviceTypeStatsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDeviceTypeStatsByUser, arg.UserID, arg.FromTime, arg.ToTime)
 	if err != nil {
 		return nil, err
 	}
@@ -643,31 +1560,93 @@ func (q *Queries) GetDeviceTypeStatsByUser(ctx context.Context, userID sql.NullI
 	return items, nil
 }
 
-const getMonthlyClicksByUser = `-- name: GetMonthlyClicksByUser :many
-SELECT DATE_TRUNC('month', url_visits.clicked_at)::date AS month,
-      COUNT(*) AS click_count
-      FROM url_visits
-LEFT JOIN urls ON url_visits.url_id = urls.id
-WHERE urls.user_id = $1
-  AND url_visits.clicked_at >= NOW() - INTERVAL '1 year'
-GROUP BY month
+const getExportJob = `-- name: GetExportJob :one
+SELECT id, user_id, format, columns, short_codes, from_time, to_time, timezone, status, file_path, row_count, error, created_at, started_at, completed_at FROM export_jobs
+WHERE id = $1 AND user_id = $2
+`
+
+type GetExportJobParams struct {
+	ID     int32 `json:"id"`
+	UserID int32 `json:"user_id"`
+}
+
+func (q *Queries) GetExportJob(ctx context.Context, arg GetExportJobParams) (ExportJob, error) {
+	row := q.db.QueryRowContext(ctx, getExportJob, arg.ID, arg.UserID)
+	var i ExportJob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Format,
+		pq.Array(&i.Columns),
+		pq.Array(&i.ShortCodes),
+		&i.FromTime,
+		&i.ToTime,
+		&i.Timezone,
+		&i.Status,
+		&i.FilePath,
+		&i.RowCount,
+		&i.Error,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const getFirstVisitTime = `-- name: GetFirstVisitTime :one
+SELECT clicked_at FROM url_visits
+ORDER BY clicked_at
+LIMIT 1
+`
+
+func (q *Queries) GetFirstVisitTime(ctx context.Context) (sql.NullTime, error) {
+	row := q.db.QueryRowContext(ctx, getFirstVisitTime)
+	var clicked_at sql.NullTime
+	err := row.Scan(&clicked_at)
+	return clicked_at, err
+}
+
+const getLinkSeriesByUser = `-- name: GetLinkSeriesByUser :many
+SELECT
+  date_trunc($1::text, created_at AT TIME ZONE $2::text)::timestamp AS bucket,
+  COUNT(*) AS links
+FROM urls
+WHERE user_id = $3
+  AND created_at >= $4::timestamptz
+  AND created_at < $5::timestamptz
+GROUP BY bucket
+ORDER BY bucket
 `
 
-type GetMonthlyClicksByUserRow struct {
-	Month      time.Time `json:"month"`
-	ClickCount int64     `json:"click_count"`
+type GetLinkSeriesByUserParams struct {
+	Granularity string        `json:"granularity"`
+	Tz          string        `json:"tz"`
+	UserID      sql.NullInt32 `json:"user_id"`
+	FromTime    time.Time     `json:"from_time"`
+	ToTime      time.Time     `json:"to_time"`
+}
+
+type GetLinkSeriesByUserRow struct {
+	Bucket time.Time `json:"bucket"`
+	Links  int64     `json:"links"`
 }
 
-func (q *Queries) GetMonthlyClicksByUser(ctx context.Context, userID sql.NullInt32) ([]GetMonthlyClicksByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getMonthlyClicksByUser, userID)
+func (q *Queries) GetLinkSeriesByUser(ctx context.Context, arg GetLinkSeriesByUserParams) ([]GetLinkSeriesByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLinkSeriesByUser,
+		arg.Granularity,
+		arg.Tz,
+		arg.UserID,
+		arg.FromTime,
+		arg.ToTime,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetMonthlyClicksByUserRow
+	var items []GetLinkSeriesByUserRow
 	for rows.Next() {
-		var i GetMonthlyClicksByUserRow
-		if err := rows.Scan(&i.Month, &i.ClickCount); err != nil {
+		var i GetLinkSeriesByUserRow
+		if err := rows.Scan(&i.Bucket, &i.Links); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -681,41 +1660,68 @@ func (q *Queries) GetMonthlyClicksByUser(ctx context.Context, userID sql.NullInt
 	return items, nil
 }
 
-const getMonthlyClicksScoped = `-- name: GetMonthlyClicksScoped :many
-SELECT
-  DATE_TRUNC('month', uv.clicked_at)::date AS month,
-  COUNT(*) AS click_count
-FROM url_visits uv
-JOIN urls u ON u.id = uv.url_id
-WHERE u.short_code = $1 AND u.user_id = $2
-  AND uv.clicked_at >= NOW() - INTERVAL '1 year'
-GROUP BY month
-ORDER BY month
+const getOriginalURL = `-- name: GetOriginalURL :one
+SELECT id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id, health_status, health_checked_at, backup_url, safety_status, safety_reason, safety_checked_at, always_preview, preview_count, og_title, og_description, og_image, thumbnail_checked_at, payload_type, payload, health_streak FROM urls
+WHERE short_code = $1
+LIMIT 1
 `
 
-type GetMonthlyClicksScopedParams struct {
-	ShortCode string        `json:"short_code"`
-	UserID    sql.NullInt32 `json:"user_id"`
+func (q *Queries) GetOriginalURL(ctx context.Context, shortCode string) (Url, error) {
+	row := q.db.QueryRowContext(ctx, getOriginalURL, shortCode)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.Title,
+		&i.ShortCode,
+		&i.Thumbnail,
+		&i.ClickCount,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.ExpireAt,
+		&i.UserID,
+		&i.HealthStatus,
+		&i.HealthCheckedAt,
+		&i.BackupUrl,
+		&i.SafetyStatus,
+		&i.SafetyReason,
+		&i.SafetyCheckedAt,
+		&i.AlwaysPreview,
+		&i.PreviewCount,
+		&i.OgTitle,
+		&i.OgDescription,
+		&i.OgImage,
+		&i.ThumbnailCheckedAt,
+		&i.PayloadType,
+		&i.Payload,
+		&i.HealthStreak,
+	)
+	return i, err
 }
 
-type GetMonthlyClicksScopedRow struct {
-	Month      time.Time `json:"month"`
-	ClickCount int64     `json:"click_count"`
+const getOwnedShortCodes = `-- name: GetOwnedShortCodes :many
+SELECT short_code FROM urls
+WHERE user_id = $1 AND short_code = ANY($2::text[])
+`
+
+type GetOwnedShortCodesParams struct {
+	UserID     sql.NullInt32 `json:"user_id"`
+	ShortCodes []string      `json:"short_codes"`
 }
 
-func (q *Queries) GetMonthlyClicksScoped(ctx context.Context, arg GetMonthlyClicksScopedParams) ([]GetMonthlyClicksScopedRow, error) {
-	rows, err := q.db.QueryContext(ctx, getMonthlyClicksScoped, arg.ShortCode, arg.UserID)
+func (q *Queries) GetOwnedShortCodes(ctx context.Context, arg GetOwnedShortCodesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getOwnedShortCodes, arg.UserID, pq.Array(arg.ShortCodes))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetMonthlyClicksScopedRow
+	var items []string
 	for rows.Next() {
-		var i GetMonthlyClicksScopedRow
-		if err := rows.Scan(&i.Month, &i.ClickCount); err != nil {
+		var short_code string
+		if err := rows.Scan(&short_code); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, short_code)
 	}
 	if err := rows.Close(); err != nil {
 		return nil, err
@@ -726,55 +1732,138 @@ func (q *Queries) GetMonthlyClicksScoped(ctx context.Context, arg GetMonthlyClic
 	return items, nil
 }
 
-const getOriginalURL = `-- name: GetOriginalURL :one
-SELECT id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id FROM urls
-WHERE short_code = $1
-LIMIT 1
+const getQRCode = `-- name: GetQRCode :one
+SELECT
+  qr_codes.id,
+  qr_codes.url_id,
+  urls.short_code,
+  qr_codes.qr_code,
+  qr_codes.fg_color,
+  qr_codes.bg_color,
+  qr_codes.logo_url,
+  qr_codes.size,
+  qr_codes.format,
+  qr_codes.error_correction,
+  qr_codes.expire_at,
+  qr_codes.created_at,
+  qr_codes.updated_at,
+  qr_codes.logo_id,
+  qr_codes.style,
+  qr_codes.quiet_zone
+FROM qr_codes
+JOIN urls ON urls.id = qr_codes.url_id
+WHERE qr_codes.id = $1 AND urls.user_id = $2
 `
 
-func (q *Queries) GetOriginalURL(ctx context.Context, shortCode string) (Url, error) {
-	row := q.db.QueryRowContext(ctx, getOriginalURL, shortCode)
-	var i Url
+type GetQRCodeParams struct {
+	ID     int32         `json:"id"`
+	UserID sql.NullInt32 `json:"user_id"`
+}
+
+type GetQRCodeRow struct {
+	ID              int32           `json:"id"`
+	UrlID           int32           `json:"url_id"`
+	ShortCode       string          `json:"short_code"`
+	QrCode          string          `json:"qr_code"`
+	FgColor         sql.NullString  `json:"fg_color"`
+	BgColor         sql.NullString  `json:"bg_color"`
+	LogoUrl         sql.NullString  `json:"logo_url"`
+	Size            int32           `json:"size"`
+	Format          string          `json:"format"`
+	ErrorCorrection string          `json:"error_correction"`
+	ExpireAt        sql.NullTime    `json:"expire_at"`
+	CreatedAt       sql.NullTime    `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	LogoID          sql.NullInt32   `json:"logo_id"`
+	Style           json.RawMessage `json:"style"`
+	QuietZone       int32           `json:"quiet_zone"`
+}
+
+func (q *Queries) GetQRCode(ctx context.Context, arg GetQRCodeParams) (GetQRCodeRow, error) {
+	row := q.db.QueryRowContext(ctx, getQRCode, arg.ID, arg.UserID)
+	var i GetQRCodeRow
 	err := row.Scan(
 		&i.ID,
-		&i.OriginalUrl,
-		&i.Title,
+		&i.UrlID,
 		&i.ShortCode,
-		&i.Thumbnail,
-		&i.ClickCount,
-		&i.PasswordHash,
-		&i.CreatedAt,
+		&i.QrCode,
+		&i.FgColor,
+		&i.BgColor,
+		&i.LogoUrl,
+		&i.Size,
+		&i.Format,
+		&i.ErrorCorrection,
 		&i.ExpireAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LogoID,
+		&i.Style,
+		&i.QuietZone,
+	)
+	return i, err
+}
+
+const getQRLogo = `-- name: GetQRLogo :one
+SELECT id, user_id, name, storage_key, content_type, width, height, size_bytes, created_at FROM qr_logos
+WHERE id = $1 AND user_id = $2
+`
+
+type GetQRLogoParams struct {
+	ID     int32 `json:"id"`
+	UserID int32 `json:"user_id"`
+}
+
+func (q *Queries) GetQRLogo(ctx context.Context, arg GetQRLogoParams) (QrLogo, error) {
+	row := q.db.QueryRowContext(ctx, getQRLogo, arg.ID, arg.UserID)
+	var i QrLogo
+	err := row.Scan(
+		&i.ID,
 		&i.UserID,
+		&i.Name,
+		&i.StorageKey,
+		&i.ContentType,
+		&i.Width,
+		&i.Height,
+		&i.SizeBytes,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getTitleAndUrlByUser = `-- name: GetTitleAndUrlByUser :many
-SELECT 
-  id,
-  title,
-  short_code
-FROM urls
-WHERE user_id = $1
+const getReferrerSourcesByUser = `-- name: GetReferrerSourcesByUser :many
+SELECT
+  d.referrer_source AS source,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON d.url_id = u.id
+WHERE u.user_id = $1
+  AND d.bucket >= $2::timestamptz
+  AND d.bucket < $3::timestamptz
+GROUP BY d.referrer_source
+ORDER BY clicks DESC
 `
 
-type GetTitleAndUrlByUserRow struct {
-	ID        int32          `json:"id"`
-	Title     sql.NullString `json:"title"`
-	ShortCode string         `json:"short_code"`
+type GetReferrerSourcesByUserParams struct {
+	UserID   sql.NullInt32 `json:"user_id"`
+	FromTime time.Time     `json:"from_time"`
+	ToTime   time.Time     `json:"to_time"`
 }
 
-func (q *Queries) GetTitleAndUrlByUser(ctx context.Context, userID sql.NullInt32) ([]GetTitleAndUrlByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getTitleAndUrlByUser, userID)
+type GetReferrerSourcesByUserRow struct {
+	Source string `json:"source"`
+	Clicks int64  `json:"clicks"`
+}
+
+func (q *Queries) GetReferrerSourcesByUser(ctx context.Context, arg GetReferrerSourcesByUserParams) ([]GetReferrerSourcesByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReferrerSourcesByUser, arg.UserID, arg.FromTime, arg.ToTime)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetTitleAndUrlByUserRow
+	var items []GetReferrerSourcesByUserRow
 	for rows.Next() {
-		var i GetTitleAndUrlByUserRow
-		if err := rows.Scan(&i.ID, &i.Title, &i.ShortCode); err != nil {
+		var i GetReferrerSourcesByUserRow
+		if err := rows.Scan(&i.Source, &i.Clicks); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -788,34 +1877,46 @@ func (q *Queries) GetTitleAndUrlByUser(ctx context.Context, userID sql.NullInt32
 	return items, nil
 }
 
-const getURLVisits = `-- name: GetURLVisits :many
-SELECT id, url_id, user_id, ip_address, user_agent, device_type, referrer, country, region, city, clicked_at FROM url_visits
-WHERE url_id = $1
-ORDER BY clicked_at DESC
+const getReferrerSourcesScoped = `-- name: GetReferrerSourcesScoped :many
+SELECT
+  d.referrer_source AS source,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON u.id = d.url_id
+WHERE u.short_code = $1 AND u.user_id = $2
+  AND d.bucket >= $3::timestamptz
+  AND d.bucket < $4::timestamptz
+GROUP BY d.referrer_source
+ORDER BY clicks DESC
 `
 
-func (q *Queries) GetURLVisits(ctx context.Context, urlID int32) ([]UrlVisit, error) {
-	rows, err := q.db.QueryContext(ctx, getURLVisits, urlID)
+type GetReferrerSourcesScopedParams struct {
+	ShortCode string        `json:"short_code"`
+	UserID    sql.NullInt32 `json:"user_id"`
+	FromTime  time.Time     `json:"from_time"`
+	ToTime    time.Time     `json:"to_time"`
+}
+
+type GetReferrerSourcesScopedRow struct {
+	Source string `json:"source"`
+	Clicks int64  `json:"clicks"`
+}
+
+func (q *Queries) GetReferrerSourcesScoped(ctx context.Context, arg GetReferrerSourcesScopedParams) ([]GetReferrerSourcesScopedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReferrerSourcesScoped,
+		arg.ShortCode,
+		arg.UserID,
+		arg.FromTime,
+		arg.ToTime,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []UrlVisit
+	var items []GetReferrerSourcesScopedRow
 	for rows.Next() {
-		var i UrlVisit
-		if err := rows.Scan(
-			&i.ID,
-			&i.UrlID,
-			&i.UserID,
-			&i.IpAddress,
-			&i.UserAgent,
-			&i.DeviceType,
-			&i.Referrer,
-			&i.Country,
-			&i.Region,
-			&i.City,
-			&i.ClickedAt,
-		); err != nil {
+		var i GetReferrerSourcesScopedRow
+		if err := rows.Scan(&i.Source, &i.Clicks); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -829,44 +1930,43 @@ func (q *Queries) GetURLVisits(ctx context.Context, urlID int32) ([]UrlVisit, er
 	return items, nil
 }
 
-const getUrlsByUserID = `-- name: GetUrlsByUserID :many
+const getRollupWatermark = `-- name: GetRollupWatermark :one
+SELECT watermark FROM rollup_state
+WHERE name = $1
+`
+
+func (q *Queries) GetRollupWatermark(ctx context.Context, name string) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getRollupWatermark, name)
+	var watermark time.Time
+	err := row.Scan(&watermark)
+	return watermark, err
+}
+
+const getTitleAndUrlByUser = `-- name: GetTitleAndUrlByUser :many
 SELECT 
   id,
-  original_url,
   title,
-  short_code,
-  thumbnail,
-  click_count,
-  password_hash,
-  created_at,
-  expire_at,
-  user_id
+  short_code
 FROM urls
 WHERE user_id = $1
-ORDER BY created_at DESC
 `
 
-func (q *Queries) GetUrlsByUserID(ctx context.Context, userID sql.NullInt32) ([]Url, error) {
-	rows, err := q.db.QueryContext(ctx, getUrlsByUserID, userID)
+type GetTitleAndUrlByUserRow struct {
+	ID        int32          `json:"id"`
+	Title     sql.NullString `json:"title"`
+	ShortCode string         `json:"short_code"`
+}
+
+func (q *Queries) GetTitleAndUrlByUser(ctx context.Context, userID sql.NullInt32) ([]GetTitleAndUrlByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTitleAndUrlByUser, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Url
+	var items []GetTitleAndUrlByUserRow
 	for rows.Next() {
-		var i Url
-		if err := rows.Scan(
-			&i.ID,
-			&i.OriginalUrl,
-			&i.Title,
-			&i.ShortCode,
-			&i.Thumbnail,
-			&i.ClickCount,
-			&i.PasswordHash,
-			&i.CreatedAt,
-			&i.ExpireAt,
-			&i.UserID,
-		); err != nil {
+		var i GetTitleAndUrlByUserRow
+		if err := rows.Scan(&i.ID, &i.Title, &i.ShortCode); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -880,31 +1980,267 @@ func (q *Queries) GetUrlsByUserID(ctx context.Context, userID sql.NullInt32) ([]
 	return items, nil
 }
 
-const getUserAccountDetails = `-- name: GetUserAccountDetails :one
-
+const getTopReferrersByUser = `-- name: GetTopReferrersByUser :many
+SELECT
+  d.referrer_domain AS domain,
+  d.referrer_source AS source,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON d.url_id = u.id
+WHERE u.user_id = $1
+  AND d.bucket >= $2::timestamptz
+  AND d.bucket < $3::timestamptz
+GROUP BY d.referrer_domain, d.referrer_source
+ORDER BY clicks DESC
+LIMIT $4::int
+`
+
+type GetTopReferrersByUserParams struct {
+	UserID   sql.NullInt32 `json:"user_id"`
+	FromTime time.Time     `json:"from_time"`
+	ToTime   time.Time     `json:"to_time"`
+	RowLimit int32         `json:"row_limit"`
+}
+
+type GetTopReferrersByUserRow struct {
+	Domain string `json:"domain"`
+	Source string `json:"source"`
+	Clicks int64  `json:"clicks"`
+}
+
+func (q *Queries) GetTopReferrersByUser(ctx context.Context, arg GetTopReferrersByUserParams) ([]GetTopReferrersByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTopReferrersByUser,
+		arg.UserID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTopReferrersByUserRow
+	for rows.Next() {
+		var i GetTopReferrersByUserRow
+		if err := rows.Scan(&i.Domain, &i.Source, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTopReferrersScoped = `-- name: GetTopReferrersScoped :many
+SELECT
+  d.referrer_domain AS domain,
+  d.referrer_source AS source,
+  SUM(d.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_dims d
+JOIN urls u ON u.id = d.url_id
+WHERE u.short_code = $1 AND u.user_id = $2
+  AND d.bucket >= $3::timestamptz
+  AND d.bucket < $4::timestamptz
+GROUP BY d.referrer_domain, d.referrer_source
+ORDER BY clicks DESC
+LIMIT $5::int
+`
+
+type GetTopReferrersScopedParams struct {
+	ShortCode string        `json:"short_code"`
+	UserID    sql.NullInt32 `json:"user_id"`
+	FromTime  time.Time     `json:"from_time"`
+	ToTime    time.Time     `json:"to_time"`
+	RowLimit  int32         `json:"row_limit"`
+}
+
+type GetTopReferrersScopedRow struct {
+	Domain string `json:"domain"`
+	Source string `json:"source"`
+	Clicks int64  `json:"clicks"`
+}
+
+func (q *Queries) GetTopReferrersScoped(ctx context.Context, arg GetTopReferrersScopedParams) ([]GetTopReferrersScopedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTopReferrersScoped,
+		arg.ShortCode,
+		arg.UserID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTopReferrersScopedRow
+	for rows.Next() {
+		var i GetTopReferrersScopedRow
+		if err := rows.Scan(&i.Domain, &i.Source, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getURLVisits = `-- name: GetURLVisits :many
+SELECT id, url_id, user_id, ip_address, user_agent, device_type, referrer, country, region, city, clicked_at, referrer_domain, referrer_source, destination, source FROM url_visits
+WHERE url_id = $1
+ORDER BY clicked_at DESC
+`
+
+func (q *Queries) GetURLVisits(ctx context.Context, urlID int32) ([]UrlVisit, error) {
+	rows, err := q.db.QueryContext(ctx, getURLVisits, urlID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UrlVisit
+	for rows.Next() {
+		var i UrlVisit
+		if err := rows.Scan(
+			&i.ID,
+			&i.UrlID,
+			&i.UserID,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.DeviceType,
+			&i.Referrer,
+			&i.Country,
+			&i.Region,
+			&i.City,
+			&i.ClickedAt,
+			&i.ReferrerDomain,
+			&i.ReferrerSource,
+			&i.Destination,
+			&i.Source,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUrlsByUserID = `-- name: GetUrlsByUserID :many
+SELECT 
+  id,
+  original_url,
+  title,
+  short_code,
+  thumbnail,
+  click_count,
+  password_hash,
+  created_at,
+  expire_at,
+  user_id,
+  health_status,
+  health_checked_at,
+  backup_url,
+  safety_status,
+  safety_reason,
+  safety_checked_at,
+  always_preview,
+  preview_count,
+  og_title,
+  og_description,
+  og_image,
+  thumbnail_checked_at,
+  payload_type,
+  payload,
+  health_streak
+FROM urls
+WHERE user_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetUrlsByUserID(ctx context.Context, userID sql.NullInt32) ([]Url, error) {
+	rows, err := q.db.QueryContext(ctx, getUrlsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Url
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalUrl,
+			&i.Title,
+			&i.ShortCode,
+			&i.Thumbnail,
+			&i.ClickCount,
+			&i.PasswordHash,
+			&i.CreatedAt,
+			&i.ExpireAt,
+			&i.UserID,
+			&i.HealthStatus,
+			&i.HealthCheckedAt,
+			&i.BackupUrl,
+			&i.SafetyStatus,
+			&i.SafetyReason,
+			&i.SafetyCheckedAt,
+			&i.AlwaysPreview,
+			&i.PreviewCount,
+			&i.OgTitle,
+			&i.OgDescription,
+			&i.OgImage,
+			&i.ThumbnailCheckedAt,
+			&i.PayloadType,
+			&i.Payload,
+			&i.HealthStreak,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserAccountDetails = `-- name: GetUserAccountDetails :one
 SELECT 
-  id, username, email, ip_address, provider, provider_id, image, created_at, updated_at
+  id, username, email, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at
 FROM users
 WHERE id = $1
 LIMIT 1
 `
 
 type GetUserAccountDetailsRow struct {
-	ID         int32          `json:"id"`
-	Username   string         `json:"username"`
-	Email      string         `json:"email"`
-	IpAddress  sql.NullString `json:"ip_address"`
-	Provider   sql.NullString `json:"provider"`
-	ProviderID sql.NullString `json:"provider_id"`
-	Image      sql.NullString `json:"image"`
-	CreatedAt  sql.NullTime   `json:"created_at"`
-	UpdatedAt  sql.NullTime   `json:"updated_at"`
+	ID              int32          `json:"id"`
+	Username        string         `json:"username"`
+	Email           string         `json:"email"`
+	IpAddress       sql.NullString `json:"ip_address"`
+	Provider        sql.NullString `json:"provider"`
+	ProviderID      sql.NullString `json:"provider_id"`
+	Image           sql.NullString `json:"image"`
+	CreatedAt       sql.NullTime   `json:"created_at"`
+	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
 }
 
-// -- name: GetUserTransactions :many
-// SELECT * FROM transactions
-// WHERE user_id = $1
-// ORDER BY created_at DESC;
 func (q *Queries) GetUserAccountDetails(ctx context.Context, id int32) (GetUserAccountDetailsRow, error) {
 	row := q.db.QueryRowContext(ctx, getUserAccountDetails, id)
 	var i GetUserAccountDetailsRow
@@ -918,12 +2254,13 @@ func (q *Queries) GetUserAccountDetails(ctx context.Context, id int32) (GetUserA
 		&i.Image,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at, verification_sent_at, session_version FROM users
 WHERE email = $1
 LIMIT 1
 `
@@ -942,12 +2279,70 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Image,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.VerificationSentAt,
+		&i.SessionVersion,
+	)
+	return i, err
+}
+
+const getUserByEmailFold = `-- name: GetUserByEmailFold :one
+SELECT id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at, verification_sent_at, session_version FROM users
+WHERE lower(email) = lower($1)
+ORDER BY id
+LIMIT 1
+`
+
+func (q *Queries) GetUserByEmailFold(ctx context.Context, lower string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmailFold, lower)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.IpAddress,
+		&i.Provider,
+		&i.ProviderID,
+		&i.Image,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.VerificationSentAt,
+		&i.SessionVersion,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at, verification_sent_at, session_version FROM users
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.IpAddress,
+		&i.Provider,
+		&i.ProviderID,
+		&i.Image,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.VerificationSentAt,
+		&i.SessionVersion,
 	)
 	return i, err
 }
 
 const getUserByProviderID = `-- name: GetUserByProviderID :one
-SELECT id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at, verification_sent_at, session_version FROM users
 WHERE provider = $1 AND provider_id = $2
 LIMIT 1
 `
@@ -971,12 +2366,15 @@ func (q *Queries) GetUserByProviderID(ctx context.Context, arg GetUserByProvider
 		&i.Image,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.VerificationSentAt,
+		&i.SessionVersion,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at, verification_sent_at, session_version FROM users
 WHERE username = $1
 LIMIT 1
 `
@@ -995,10 +2393,25 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.Image,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.VerificationSentAt,
+		&i.SessionVersion,
 	)
 	return i, err
 }
 
+const getUserSessionVersion = `-- name: GetUserSessionVersion :one
+SELECT session_version FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserSessionVersion(ctx context.Context, id int32) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getUserSessionVersion, id)
+	var session_version int32
+	err := row.Scan(&session_version)
+	return session_version, err
+}
+
 const getUserTransactions = `-- name: GetUserTransactions :many
 SELECT id, user_id, razorpay_order_id, razorpay_payment_id, amount, currency, plan, status, created_at FROM transactions
 WHERE user_id = $1
@@ -1082,77 +2495,1386 @@ func (q *Queries) GetUserTransactionsByStatus(ctx context.Context, arg GetUserTr
 	return items, nil
 }
 
-const incrementClickCount = `-- name: IncrementClickCount :exec
-UPDATE urls
-SET click_count = click_count + 1
-WHERE short_code = $1
+const getVisitSourcesByUser = `-- name: GetVisitSourcesByUser :many
+SELECT
+  s.source,
+  SUM(s.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_sources s
+JOIN urls u ON s.url_id = u.id
+WHERE u.user_id = $1
+  AND s.bucket >= $2::timestamptz
+  AND s.bucket < $3::timestamptz
+GROUP BY s.source
 `
 
-func (q *Queries) IncrementClickCount(ctx context.Context, shortCode string) error {
-	_, err := q.db.ExecContext(ctx, incrementClickCount, shortCode)
-	return err
+type GetVisitSourcesByUserParams struct {
+	UserID   sql.NullInt32 `json:"user_id"`
+	FromTime time.Time     `json:"from_time"`
+	ToTime   time.Time     `json:"to_time"`
 }
 
-const logURLVisit = `-- name: LogURLVisit :exec
-INSERT INTO url_visits (
-  url_id,
-  user_id,
-  ip_address,
-  device_type,
-  user_agent,
-  referrer,
-  country,
-  region,
-  city
-) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9
-)
+type GetVisitSourcesByUserRow struct {
+	Source string `json:"source"`
+	Clicks int64  `json:"clicks"`
+}
+
+func (q *Queries) GetVisitSourcesByUser(ctx context.Context, arg GetVisitSourcesByUserParams) ([]GetVisitSourcesByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getVisitSourcesByUser, arg.UserID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetVisitSourcesByUserRow
+	for rows.Next() {
+		var i GetVisitSourcesByUserRow
+		if err := rows.Scan(&i.Source, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisitSourcesScoped = `-- name: GetVisitSourcesScoped :many
+SELECT
+  s.source,
+  SUM(s.clicks)::BIGINT AS clicks
+FROM url_visit_rollup_sources s
+JOIN urls u ON u.id = s.url_id
+WHERE u.short_code = $1 AND u.user_id = $2
+  AND s.bucket >= $3::timestamptz
+  AND s.bucket < $4::timestamptz
+GROUP BY s.source
 `
 
-type LogURLVisitParams struct {
-	UrlID      int32          `json:"url_id"`
-	UserID     sql.NullInt32  `json:"user_id"`
-	IpAddress  sql.NullString `json:"ip_address"`
-	DeviceType sql.NullString `json:"device_type"`
-	UserAgent  sql.NullString `json:"user_agent"`
-	Referrer   sql.NullString `json:"referrer"`
-	Country    sql.NullString `json:"country"`
-	Region     sql.NullString `json:"region"`
-	City       sql.NullString `json:"city"`
+type GetVisitSourcesScopedParams struct {
+	ShortCode string        `json:"short_code"`
+	UserID    sql.NullInt32 `json:"user_id"`
+	FromTime  time.Time     `json:"from_time"`
+	ToTime    time.Time     `json:"to_time"`
 }
 
-func (q *Queries) LogURLVisit(ctx context.Context, arg LogURLVisitParams) error {
-	_, err := q.db.ExecContext(ctx, logURLVisit,
-		arg.UrlID,
+type GetVisitSourcesScopedRow struct {
+	Source string `json:"source"`
+	Clicks int64  `json:"clicks"`
+}
+
+func (q *Queries) GetVisitSourcesScoped(ctx context.Context, arg GetVisitSourcesScopedParams) ([]GetVisitSourcesScopedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getVisitSourcesScoped,
+		arg.ShortCode,
 		arg.UserID,
-		arg.IpAddress,
-		arg.DeviceType,
-		arg.UserAgent,
-		arg.Referrer,
-		arg.Country,
+		arg.FromTime,
+		arg.ToTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetVisitSourcesScopedRow
+	for rows.Next() {
+		var i GetVisitSourcesScopedRow
+		if err := rows.Scan(&i.Source, &i.Clicks); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookEndpoint = `-- name: GetWebhookEndpoint :one
+SELECT id, user_id, url, secret, events, active, created_at FROM webhook_endpoints
+WHERE id = $1 AND user_id = $2
+`
+
+type GetWebhookEndpointParams struct {
+	ID     int32 `json:"id"`
+	UserID int32 `json:"user_id"`
+}
+
+func (q *Queries) GetWebhookEndpoint(ctx context.Context, arg GetWebhookEndpointParams) (WebhookEndpoint, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookEndpoint, arg.ID, arg.UserID)
+	var i WebhookEndpoint
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		pq.Array(&i.Events),
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWebhookEndpointByID = `-- name: GetWebhookEndpointByID :one
+SELECT id, user_id, url, secret, events, active, created_at FROM webhook_endpoints
+WHERE id = $1
+`
+
+func (q *Queries) GetWebhookEndpointByID(ctx context.Context, id int32) (WebhookEndpoint, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookEndpointByID, id)
+	var i WebhookEndpoint
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		pq.Array(&i.Events),
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const incrementClickCount = `-- name: IncrementClickCount :exec
+UPDATE urls
+SET click_count = click_count + 1
+WHERE short_code = $1
+`
+
+func (q *Queries) IncrementClickCount(ctx context.Context, shortCode string) error {
+	_, err := q.db.ExecContext(ctx, incrementClickCount, shortCode)
+	return err
+}
+
+const isUserEmailVerified = `-- name: IsUserEmailVerified :one
+SELECT (email_verified_at IS NOT NULL)::bool AS verified
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) IsUserEmailVerified(ctx context.Context, id int32) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isUserEmailVerified, id)
+	var verified bool
+	err := row.Scan(&verified)
+	return verified, err
+}
+
+const listExportJobsByUser = `-- name: ListExportJobsByUser :many
+SELECT id, user_id, format, columns, short_codes, from_time, to_time, timezone, status, file_path, row_count, error, created_at, started_at, completed_at FROM export_jobs
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 50
+`
+
+func (q *Queries) ListExportJobsByUser(ctx context.Context, userID int32) ([]ExportJob, error) {
+	rows, err := q.db.QueryContext(ctx, listExportJobsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportJob
+	for rows.Next() {
+		var i ExportJob
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Format,
+			pq.Array(&i.Columns),
+			pq.Array(&i.ShortCodes),
+			&i.FromTime,
+			&i.ToTime,
+			&i.Timezone,
+			&i.Status,
+			&i.FilePath,
+			&i.RowCount,
+			&i.Error,
+			&i.CreatedAt,
+			&i.StartedAt,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLinkFailovers = `-- name: ListLinkFailovers :many
+SELECT
+  link_failovers.id,
+  link_failovers.url_id,
+  link_failovers.started_at,
+  link_failovers.ended_at
+FROM link_failovers
+JOIN urls ON urls.id = link_failovers.url_id
+WHERE urls.short_code = $1 AND urls.user_id = $2
+ORDER BY link_failovers.started_at DESC
+LIMIT 100
+`
+
+type ListLinkFailoversParams struct {
+	ShortCode string        `json:"short_code"`
+	UserID    sql.NullInt32 `json:"user_id"`
+}
+
+func (q *Queries) ListLinkFailovers(ctx context.Context, arg ListLinkFailoversParams) ([]LinkFailover, error) {
+	rows, err := q.db.QueryContext(ctx, listLinkFailovers, arg.ShortCode, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LinkFailover
+	for rows.Next() {
+		var i LinkFailover
+		if err := rows.Scan(
+			&i.ID,
+			&i.UrlID,
+			&i.StartedAt,
+			&i.EndedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLinkHealthChecks = `-- name: ListLinkHealthChecks :many
+SELECT
+  link_health_checks.id,
+  link_health_checks.url_id,
+  link_health_checks.status,
+  link_health_checks.status_code,
+  link_health_checks.method,
+  link_health_checks.latency_ms,
+  link_health_checks.redirect_chain,
+  link_health_checks.final_url,
+  link_health_checks.error,
+  link_health_checks.checked_at
+FROM link_health_checks
+JOIN urls ON urls.id = link_health_checks.url_id
+WHERE urls.short_code = $1 AND urls.user_id = $2
+ORDER BY link_health_checks.checked_at DESC
+LIMIT 100
+`
+
+type ListLinkHealthChecksParams struct {
+	ShortCode string        `json:"short_code"`
+	UserID    sql.NullInt32 `json:"user_id"`
+}
+
+func (q *Queries) ListLinkHealthChecks(ctx context.Context, arg ListLinkHealthChecksParams) ([]LinkHealthCheck, error) {
+	rows, err := q.db.QueryContext(ctx, listLinkHealthChecks, arg.ShortCode, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LinkHealthCheck
+	for rows.Next() {
+		var i LinkHealthCheck
+		if err := rows.Scan(
+			&i.ID,
+			&i.UrlID,
+			&i.Status,
+			&i.StatusCode,
+			&i.Method,
+			&i.LatencyMs,
+			pq.Array(&i.RedirectChain),
+			&i.FinalUrl,
+			&i.Error,
+			&i.CheckedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLinksByShortCodes = `-- name: ListLinksByShortCodes :many
+SELECT id, short_code, title FROM urls
+WHERE user_id = $1 AND short_code = ANY($2::text[])
+`
+
+type ListLinksByShortCodesParams struct {
+	UserID     sql.NullInt32 `json:"user_id"`
+	ShortCodes []string      `json:"short_codes"`
+}
+
+type ListLinksByShortCodesRow struct {
+	ID        int32          `json:"id"`
+	ShortCode string         `json:"short_code"`
+	Title     sql.NullString `json:"title"`
+}
+
+func (q *Queries) ListLinksByShortCodes(ctx context.Context, arg ListLinksByShortCodesParams) ([]ListLinksByShortCodesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLinksByShortCodes, arg.UserID, pq.Array(arg.ShortCodes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLinksByShortCodesRow
+	for rows.Next() {
+		var i ListLinksByShortCodesRow
+		if err := rows.Scan(&i.ID, &i.ShortCode, &i.Title); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLinksDueForHealthCheck = `-- name: ListLinksDueForHealthCheck :many
+SELECT id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id, health_status, health_checked_at, backup_url, safety_status, safety_reason, safety_checked_at, always_preview, preview_count, og_title, og_description, og_image, thumbnail_checked_at, payload_type, payload, health_streak FROM urls
+WHERE (expire_at IS NULL OR expire_at > now())
+  AND payload_type IS NULL
+  AND (
+    health_checked_at IS NULL
+    OR health_checked_at < $1::timestamptz
+    OR ((health_status = 'unhealthy' OR health_streak > 0) AND health_checked_at < $2::timestamptz)
+  )
+ORDER BY health_checked_at NULLS FIRST, id
+LIMIT $3
+`
+
+type ListLinksDueForHealthCheckParams struct {
+	CheckedBefore          time.Time `json:"checked_before"`
+	UnhealthyCheckedBefore time.Time `json:"unhealthy_checked_before"`
+	MaxLinks               int32     `json:"max_links"`
+}
+
+func (q *Queries) ListLinksDueForHealthCheck(ctx context.Context, arg ListLinksDueForHealthCheckParams) ([]Url, error) {
+	rows, err := q.db.QueryContext(ctx, listLinksDueForHealthCheck, arg.CheckedBefore, arg.UnhealthyCheckedBefore, arg.MaxLinks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Url
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalUrl,
+			&i.Title,
+			&i.ShortCode,
+			&i.Thumbnail,
+			&i.ClickCount,
+			&i.PasswordHash,
+			&i.CreatedAt,
+			&i.ExpireAt,
+			&i.UserID,
+			&i.HealthStatus,
+			&i.HealthCheckedAt,
+			&i.BackupUrl,
+			&i.SafetyStatus,
+			&i.SafetyReason,
+			&i.SafetyCheckedAt,
+			&i.AlwaysPreview,
+			&i.PreviewCount,
+			&i.OgTitle,
+			&i.OgDescription,
+			&i.OgImage,
+			&i.ThumbnailCheckedAt,
+			&i.PayloadType,
+			&i.Payload,
+			&i.HealthStreak,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLinksDueForSafetyScan = `-- name: ListLinksDueForSafetyScan :many
+SELECT id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id, health_status, health_checked_at, backup_url, safety_status, safety_reason, safety_checked_at, always_preview, preview_count, og_title, og_description, og_image, thumbnail_checked_at, payload_type, payload, health_streak FROM urls
+WHERE (expire_at IS NULL OR expire_at > now())
+  AND payload_type IS NULL
+  AND (safety_checked_at IS NULL OR safety_checked_at < $1::timestamptz)
+ORDER BY safety_checked_at NULLS FIRST, id
+LIMIT $2
+`
+
+type ListLinksDueForSafetyScanParams struct {
+	CheckedBefore time.Time `json:"checked_before"`
+	MaxLinks      int32     `json:"max_links"`
+}
+
+func (q *Queries) ListLinksDueForSafetyScan(ctx context.Context, arg ListLinksDueForSafetyScanParams) ([]Url, error) {
+	rows, err := q.db.QueryContext(ctx, listLinksDueForSafetyScan, arg.CheckedBefore, arg.MaxLinks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Url
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalUrl,
+			&i.Title,
+			&i.ShortCode,
+			&i.Thumbnail,
+			&i.ClickCount,
+			&i.PasswordHash,
+			&i.CreatedAt,
+			&i.ExpireAt,
+			&i.UserID,
+			&i.HealthStatus,
+			&i.HealthCheckedAt,
+			&i.BackupUrl,
+			&i.SafetyStatus,
+			&i.SafetyReason,
+			&i.SafetyCheckedAt,
+			&i.AlwaysPreview,
+			&i.PreviewCount,
+			&i.OgTitle,
+			&i.OgDescription,
+			&i.OgImage,
+			&i.ThumbnailCheckedAt,
+			&i.PayloadType,
+			&i.Payload,
+			&i.HealthStreak,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLinksDueForThumbnail = `-- name: ListLinksDueForThumbnail :many
+SELECT id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id, health_status, health_checked_at, backup_url, safety_status, safety_reason, safety_checked_at, always_preview, preview_count, og_title, og_description, og_image, thumbnail_checked_at, payload_type, payload, health_streak FROM urls
+WHERE (expire_at IS NULL OR expire_at > now())
+  AND payload_type IS NULL
+  AND (thumbnail_checked_at IS NULL OR thumbnail_checked_at < $1::timestamptz)
+ORDER BY thumbnail_checked_at NULLS FIRST, id
+LIMIT $2
+`
+
+type ListLinksDueForThumbnailParams struct {
+	CheckedBefore time.Time `json:"checked_before"`
+	MaxLinks      int32     `json:"max_links"`
+}
+
+func (q *Queries) ListLinksDueForThumbnail(ctx context.Context, arg ListLinksDueForThumbnailParams) ([]Url, error) {
+	rows, err := q.db.QueryContext(ctx, listLinksDueForThumbnail, arg.CheckedBefore, arg.MaxLinks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Url
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalUrl,
+			&i.Title,
+			&i.ShortCode,
+			&i.Thumbnail,
+			&i.ClickCount,
+			&i.PasswordHash,
+			&i.CreatedAt,
+			&i.ExpireAt,
+			&i.UserID,
+			&i.HealthStatus,
+			&i.HealthCheckedAt,
+			&i.BackupUrl,
+			&i.SafetyStatus,
+			&i.SafetyReason,
+			&i.SafetyCheckedAt,
+			&i.AlwaysPreview,
+			&i.PreviewCount,
+			&i.OgTitle,
+			&i.OgDescription,
+			&i.OgImage,
+			&i.ThumbnailCheckedAt,
+			&i.PayloadType,
+			&i.Payload,
+			&i.HealthStreak,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQRCodesByUser = `-- name: ListQRCodesByUser :many
+SELECT
+  qr_codes.id,
+  qr_codes.url_id,
+  urls.short_code,
+  qr_codes.qr_code,
+  qr_codes.fg_color,
+  qr_codes.bg_color,
+  qr_codes.logo_url,
+  qr_codes.size,
+  qr_codes.format,
+  qr_codes.error_correction,
+  qr_codes.expire_at,
+  qr_codes.created_at,
+  qr_codes.updated_at,
+  qr_codes.logo_id,
+  qr_codes.style,
+  qr_codes.quiet_zone
+FROM qr_codes
+JOIN urls ON urls.id = qr_codes.url_id
+WHERE urls.user_id = $1
+ORDER BY qr_codes.created_at DESC, qr_codes.id DESC
+`
+
+type ListQRCodesByUserRow struct {
+	ID              int32           `json:"id"`
+	UrlID           int32           `json:"url_id"`
+	ShortCode       string          `json:"short_code"`
+	QrCode          string          `json:"qr_code"`
+	FgColor         sql.NullString  `json:"fg_color"`
+	BgColor         sql.NullString  `json:"bg_color"`
+	LogoUrl         sql.NullString  `json:"logo_url"`
+	Size            int32           `json:"size"`
+	Format          string          `json:"format"`
+	ErrorCorrection string          `json:"error_correction"`
+	ExpireAt        sql.NullTime    `json:"expire_at"`
+	CreatedAt       sql.NullTime    `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	LogoID          sql.NullInt32   `json:"logo_id"`
+	Style           json.RawMessage `json:"style"`
+	QuietZone       int32           `json:"quiet_zone"`
+}
+
+func (q *Queries) ListQRCodesByUser(ctx context.Context, userID sql.NullInt32) ([]ListQRCodesByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listQRCodesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListQRCodesByUserRow
+	for rows.Next() {
+		var i ListQRCodesByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UrlID,
+			&i.ShortCode,
+			&i.QrCode,
+			&i.FgColor,
+			&i.BgColor,
+			&i.LogoUrl,
+			&i.Size,
+			&i.Format,
+			&i.ErrorCorrection,
+			&i.ExpireAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LogoID,
+			&i.Style,
+			&i.QuietZone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQRLogosByUser = `-- name: ListQRLogosByUser :many
+SELECT id, user_id, name, storage_key, content_type, width, height, size_bytes, created_at FROM qr_logos
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) ListQRLogosByUser(ctx context.Context, userID int32) ([]QrLogo, error) {
+	rows, err := q.db.QueryContext(ctx, listQRLogosByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QrLogo
+	for rows.Next() {
+		var i QrLogo
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.StorageKey,
+			&i.ContentType,
+			&i.Width,
+			&i.Height,
+			&i.SizeBytes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVisitsForExport = `-- name: ListVisitsForExport :many
+SELECT
+  uv.id,
+  u.short_code,
+  uv.clicked_at,
+  uv.ip_address,
+  uv.user_agent,
+  uv.device_type,
+  uv.referrer,
+  uv.referrer_domain,
+  uv.referrer_source,
+  uv.country,
+  uv.region,
+  uv.city,
+  uv.destination,
+  uv.source
+FROM url_visits uv
+JOIN urls u ON u.id = uv.url_id
+WHERE u.user_id = $1
+  AND (cardinality($2::text[]) = 0 OR u.short_code = ANY($2::text[]))
+  AND uv.clicked_at >= $3::timestamptz
+  AND uv.clicked_at < $4::timestamptz
+  AND uv.id > $5::int
+ORDER BY uv.id
+LIMIT $6::int
+`
+
+type ListVisitsForExportParams struct {
+	UserID     sql.NullInt32 `json:"user_id"`
+	ShortCodes []string      `json:"short_codes"`
+	FromTime   time.Time     `json:"from_time"`
+	ToTime     time.Time     `json:"to_time"`
+	AfterID    int32         `json:"after_id"`
+	RowLimit   int32         `json:"row_limit"`
+}
+
+type ListVisitsForExportRow struct {
+	ID             int32          `json:"id"`
+	ShortCode      string         `json:"short_code"`
+	ClickedAt      sql.NullTime   `json:"clicked_at"`
+	IpAddress      sql.NullString `json:"ip_address"`
+	UserAgent      sql.NullString `json:"user_agent"`
+	DeviceType     sql.NullString `json:"device_type"`
+	Referrer       sql.NullString `json:"referrer"`
+	ReferrerDomain sql.NullString `json:"referrer_domain"`
+	ReferrerSource sql.NullString `json:"referrer_source"`
+	Country        sql.NullString `json:"country"`
+	Region         sql.NullString `json:"region"`
+	City           sql.NullString `json:"city"`
+	Destination    sql.NullString `json:"destination"`
+	Source         string         `json:"source"`
+}
+
+func (q *Queries) ListVisitsForExport(ctx context.Context, arg ListVisitsForExportParams) ([]ListVisitsForExportRow, error) {
+	rows, err := q.db.QueryContext(ctx, listVisitsForExport,
+		arg.UserID,
+		pq.Array(arg.ShortCodes),
+		arg.FromTime,
+		arg.ToTime,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListVisitsForExportRow
+	for rows.Next() {
+		var i ListVisitsForExportRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.ClickedAt,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.DeviceType,
+			&i.Referrer,
+			&i.ReferrerDomain,
+			&i.ReferrerSource,
+			&i.Country,
+			&i.Region,
+			&i.City,
+			&i.Destination,
+			&i.Source,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT id, endpoint_id, event, payload, status, attempts, next_attempt_at, response_code, response_body, error, created_at, delivered_at FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY id DESC
+LIMIT 100
+`
+
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, endpointID int32) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhookDeliveries, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.EndpointID,
+			&i.Event,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.ResponseCode,
+			&i.ResponseBody,
+			&i.Error,
+			&i.CreatedAt,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookEndpointsByUser = `-- name: ListWebhookEndpointsByUser :many
+SELECT id, user_id, url, secret, events, active, created_at FROM webhook_endpoints
+WHERE user_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListWebhookEndpointsByUser(ctx context.Context, userID int32) ([]WebhookEndpoint, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhookEndpointsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookEndpoint
+	for rows.Next() {
+		var i WebhookEndpoint
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			pq.Array(&i.Events),
+			&i.Active,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookEndpointsForEvent = `-- name: ListWebhookEndpointsForEvent :many
+SELECT id, user_id, url, secret, events, active, created_at FROM webhook_endpoints
+WHERE user_id = $1 AND active AND $2::text = ANY(events)
+`
+
+type ListWebhookEndpointsForEventParams struct {
+	UserID int32  `json:"user_id"`
+	Event  string `json:"event"`
+}
+
+func (q *Queries) ListWebhookEndpointsForEvent(ctx context.Context, arg ListWebhookEndpointsForEventParams) ([]WebhookEndpoint, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhookEndpointsForEvent, arg.UserID, arg.Event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookEndpoint
+	for rows.Next() {
+		var i WebhookEndpoint
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			pq.Array(&i.Events),
+			&i.Active,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const logPreviewView = `-- name: LogPreviewView :exec
+WITH counted AS (
+  UPDATE urls SET preview_count = preview_count + 1 WHERE id = $1
+)
+INSERT INTO url_preview_views (
+  url_id,
+  ip_address,
+  user_agent,
+  referrer
+) VALUES (
+  $1, $2, $3, $4
+)
+`
+
+type LogPreviewViewParams struct {
+	UrlID     int32          `json:"url_id"`
+	IpAddress sql.NullString `json:"ip_address"`
+	UserAgent sql.NullString `json:"user_agent"`
+	Referrer  sql.NullString `json:"referrer"`
+}
+
+func (q *Queries) LogPreviewView(ctx context.Context, arg LogPreviewViewParams) error {
+	_, err := q.db.ExecContext(ctx, logPreviewView,
+		arg.UrlID,
+		arg.IpAddress,
+		arg.UserAgent,
+		arg.Referrer,
+	)
+	return err
+}
+
+const logURLVisit = `-- name: LogURLVisit :exec
+INSERT INTO url_visits (
+  url_id,
+  user_id,
+  ip_address,
+  device_type,
+  user_agent,
+  referrer,
+  country,
+  region,
+  city,
+  referrer_domain,
+  referrer_source,
+  destination,
+  source
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+)
+`
+
+type LogURLVisitParams struct {
+	UrlID          int32          `json:"url_id"`
+	UserID         sql.NullInt32  `json:"user_id"`
+	IpAddress      sql.NullString `json:"ip_address"`
+	DeviceType     sql.NullString `json:"device_type"`
+	UserAgent      sql.NullString `json:"user_agent"`
+	Referrer       sql.NullString `json:"referrer"`
+	Country        sql.NullString `json:"country"`
+	Region         sql.NullString `json:"region"`
+	City           sql.NullString `json:"city"`
+	ReferrerDomain sql.NullString `json:"referrer_domain"`
+	ReferrerSource sql.NullString `json:"referrer_source"`
+	Destination    sql.NullString `json:"destination"`
+	Source         string         `json:"source"`
+}
+
+func (q *Queries) LogURLVisit(ctx context.Context, arg LogURLVisitParams) error {
+	_, err := q.db.ExecContext(ctx, logURLVisit,
+		arg.UrlID,
+		arg.UserID,
+		arg.IpAddress,
+		arg.DeviceType,
+		arg.UserAgent,
+		arg.Referrer,
+		arg.Country,
 		arg.Region,
 		arg.City,
+		arg.ReferrerDomain,
+		arg.ReferrerSource,
+		arg.Destination,
+		arg.Source,
+	)
+	return err
+}
+
+const recordWebhookAttempt = `-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET
+  status = $2,
+  attempts = attempts + 1,
+  next_attempt_at = $3,
+  response_code = $4,
+  response_body = $5,
+  error = $6,
+  delivered_at = CASE WHEN $2 = 'delivered' THEN now() ELSE delivered_at END
+WHERE id = $1
+`
+
+type RecordWebhookAttemptParams struct {
+	ID            int32          `json:"id"`
+	Status        string         `json:"status"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	ResponseCode  sql.NullInt32  `json:"response_code"`
+	ResponseBody  sql.NullString `json:"response_body"`
+	Error         sql.NullString `json:"error"`
+}
+
+func (q *Queries) RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) error {
+	_, err := q.db.ExecContext(ctx, recordWebhookAttempt,
+		arg.ID,
+		arg.Status,
+		arg.NextAttemptAt,
+		arg.ResponseCode,
+		arg.ResponseBody,
+		arg.Error,
 	)
 	return err
 }
 
+const requeueRunningExportJobs = `-- name: RequeueRunningExportJobs :exec
+UPDATE export_jobs
+SET status = 'pending', started_at = NULL
+WHERE status = 'running'
+`
+
+func (q *Queries) RequeueRunningExportJobs(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, requeueRunningExportJobs)
+	return err
+}
+
+const requeueSendingWebhookDeliveries = `-- name: RequeueSendingWebhookDeliveries :exec
+UPDATE webhook_deliveries
+SET status = 'pending'
+WHERE status = 'sending'
+`
+
+func (q *Queries) RequeueSendingWebhookDeliveries(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, requeueSendingWebhookDeliveries)
+	return err
+}
+
+const rollupVisitDims = `-- name: RollupVisitDims :exec
+INSERT INTO url_visit_rollup_dims (url_id, bucket, country, device_type, referrer_domain, referrer_source, clicks)
+SELECT
+  url_id,
+  date_bin('15 minutes', clicked_at, TIMESTAMPTZ '2000-01-01 00:00:00+00') AS bucket,
+  COALESCE(country, ''),
+  COALESCE(device_type, ''),
+  COALESCE(referrer_domain, ''),
+  COALESCE(referrer_source, CASE WHEN COALESCE(referrer, '') = '' THEN 'direct' ELSE 'other' END),
+  COUNT(*)
+FROM url_visits
+WHERE clicked_at >= $1::timestamptz
+  AND clicked_at < $2::timestamptz
+GROUP BY 1, 2, 3, 4, 5, 6
+ON CONFLICT (url_id, bucket, country, device_type, referrer_domain, referrer_source) DO UPDATE
+SET clicks = EXCLUDED.clicks
+`
+
+type RollupVisitDimsParams struct {
+	FromTime time.Time `json:"from_time"`
+	ToTime   time.Time `json:"to_time"`
+}
+
+func (q *Queries) RollupVisitDims(ctx context.Context, arg RollupVisitDimsParams) error {
+	_, err := q.db.ExecContext(ctx, rollupVisitDims, arg.FromTime, arg.ToTime)
+	return err
+}
+
+const rollupVisitSources = `-- name: RollupVisitSources :exec
+INSERT INTO url_visit_rollup_sources (url_id, bucket, source, clicks)
+SELECT
+  url_id,
+  date_bin('15 minutes', clicked_at, TIMESTAMPTZ '2000-01-01 00:00:00+00') AS bucket,
+  source,
+  COUNT(*)
+FROM url_visits
+WHERE clicked_at >= $1::timestamptz
+  AND clicked_at < $2::timestamptz
+GROUP BY 1, 2, 3
+ON CONFLICT (url_id, bucket, source) DO UPDATE
+SET clicks = EXCLUDED.clicks
+`
+
+type RollupVisitSourcesParams struct {
+	FromTime time.Time `json:"from_time"`
+	ToTime   time.Time `json:"to_time"`
+}
+
+func (q *Queries) RollupVisitSources(ctx context.Context, arg RollupVisitSourcesParams) error {
+	_, err := q.db.ExecContext(ctx, rollupVisitSources, arg.FromTime, arg.ToTime)
+	return err
+}
+
+const rollupVisits = `-- name: RollupVisits :exec
+INSERT INTO url_visit_rollups (url_id, bucket, clicks)
+SELECT
+  url_id,
+  date_bin('15 minutes', clicked_at, TIMESTAMPTZ '2000-01-01 00:00:00+00') AS bucket,
+  COUNT(*)
+FROM url_visits
+WHERE clicked_at >= $1::timestamptz
+  AND clicked_at < $2::timestamptz
+GROUP BY 1, 2
+ON CONFLICT (url_id, bucket) DO UPDATE
+SET clicks = EXCLUDED.clicks
+`
+
+type RollupVisitsParams struct {
+	FromTime time.Time `json:"from_time"`
+	ToTime   time.Time `json:"to_time"`
+}
+
+func (q *Queries) RollupVisits(ctx context.Context, arg RollupVisitsParams) error {
+	_, err := q.db.ExecContext(ctx, rollupVisits, arg.FromTime, arg.ToTime)
+	return err
+}
+
+const setMissingURLTitle = `-- name: SetMissingURLTitle :exec
+UPDATE urls
+SET title = $2
+WHERE id = $1 AND title IS NULL
+`
+
+type SetMissingURLTitleParams struct {
+	ID    int32          `json:"id"`
+	Title sql.NullString `json:"title"`
+}
+
+func (q *Queries) SetMissingURLTitle(ctx context.Context, arg SetMissingURLTitleParams) error {
+	_, err := q.db.ExecContext(ctx, setMissingURLTitle, arg.ID, arg.Title)
+	return err
+}
+
+const setNewURLThumbnail = `-- name: SetNewURLThumbnail :execrows
+UPDATE urls
+SET thumbnail = $2, thumbnail_checked_at = now()
+WHERE id = $1 AND thumbnail IS NULL
+`
+
+type SetNewURLThumbnailParams struct {
+	ID        int32          `json:"id"`
+	Thumbnail sql.NullString `json:"thumbnail"`
+}
+
+func (q *Queries) SetNewURLThumbnail(ctx context.Context, arg SetNewURLThumbnailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setNewURLThumbnail, arg.ID, arg.Thumbnail)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setRollupWatermark = `-- name: SetRollupWatermark :exec
+INSERT INTO rollup_state (name, watermark)
+VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET watermark = EXCLUDED.watermark
+`
+
+type SetRollupWatermarkParams struct {
+	Name      string    `json:"name"`
+	Watermark time.Time `json:"watermark"`
+}
+
+func (q *Queries) SetRollupWatermark(ctx context.Context, arg SetRollupWatermarkParams) error {
+	_, err := q.db.ExecContext(ctx, setRollupWatermark, arg.Name, arg.Watermark)
+	return err
+}
+
+const setURLHealth = `-- name: SetURLHealth :exec
+UPDATE urls
+SET health_status = $2, health_streak = $3, health_checked_at = now()
+WHERE id = $1
+`
+
+type SetURLHealthParams struct {
+	ID           int32          `json:"id"`
+	HealthStatus sql.NullString `json:"health_status"`
+	HealthStreak int32          `json:"health_streak"`
+}
+
+func (q *Queries) SetURLHealth(ctx context.Context, arg SetURLHealthParams) error {
+	_, err := q.db.ExecContext(ctx, setURLHealth, arg.ID, arg.HealthStatus, arg.HealthStreak)
+	return err
+}
+
+const setURLSafety = `-- name: SetURLSafety :exec
+UPDATE urls
+SET safety_status = $2, safety_reason = $3, safety_checked_at = now()
+WHERE id = $1
+`
+
+type SetURLSafetyParams struct {
+	ID           int32          `json:"id"`
+	SafetyStatus sql.NullString `json:"safety_status"`
+	SafetyReason sql.NullString `json:"safety_reason"`
+}
+
+func (q *Queries) SetURLSafety(ctx context.Context, arg SetURLSafetyParams) error {
+	_, err := q.db.ExecContext(ctx, setURLSafety, arg.ID, arg.SafetyStatus, arg.SafetyReason)
+	return err
+}
+
+const setURLThumbnail = `-- name: SetURLThumbnail :exec
+UPDATE urls
+SET thumbnail = $2, thumbnail_checked_at = now()
+WHERE id = $1
+`
+
+type SetURLThumbnailParams struct {
+	ID        int32          `json:"id"`
+	Thumbnail sql.NullString `json:"thumbnail"`
+}
+
+func (q *Queries) SetURLThumbnail(ctx context.Context, arg SetURLThumbnailParams) error {
+	_, err := q.db.ExecContext(ctx, setURLThumbnail, arg.ID, arg.Thumbnail)
+	return err
+}
+
+const startLinkFailover = `-- name: StartLinkFailover :exec
+INSERT INTO link_failovers (url_id)
+VALUES ($1)
+ON CONFLICT (url_id) WHERE ended_at IS NULL DO NOTHING
+`
+
+func (q *Queries) StartLinkFailover(ctx context.Context, urlID int32) error {
+	_, err := q.db.ExecContext(ctx, startLinkFailover, urlID)
+	return err
+}
+
+const updateLinkPayload = `-- name: UpdateLinkPayload :one
+UPDATE urls
+SET original_url = $2, payload_type = $3, payload = $4
+WHERE id = $1
+RETURNING id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id, health_status, health_checked_at, backup_url, safety_status, safety_reason, safety_checked_at, always_preview, preview_count, og_title, og_description, og_image, thumbnail_checked_at, payload_type, payload, health_streak
+`
+
+type UpdateLinkPayloadParams struct {
+	ID          int32           `json:"id"`
+	OriginalUrl string          `json:"original_url"`
+	PayloadType sql.NullString  `json:"payload_type"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+func (q *Queries) UpdateLinkPayload(ctx context.Context, arg UpdateLinkPayloadParams) (Url, error) {
+	row := q.db.QueryRowContext(ctx, updateLinkPayload,
+		arg.ID,
+		arg.OriginalUrl,
+		arg.PayloadType,
+		arg.Payload,
+	)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.Title,
+		&i.ShortCode,
+		&i.Thumbnail,
+		&i.ClickCount,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.ExpireAt,
+		&i.UserID,
+		&i.HealthStatus,
+		&i.HealthCheckedAt,
+		&i.BackupUrl,
+		&i.SafetyStatus,
+		&i.SafetyReason,
+		&i.SafetyCheckedAt,
+		&i.AlwaysPreview,
+		&i.PreviewCount,
+		&i.OgTitle,
+		&i.OgDescription,
+		&i.OgImage,
+		&i.ThumbnailCheckedAt,
+		&i.PayloadType,
+		&i.Payload,
+		&i.HealthStreak,
+	)
+	return i, err
+}
+
+const updateQRCode = `-- name: UpdateQRCode :one
+UPDATE qr_codes
+SET
+  qr_code = $2,
+  fg_color = $3,
+  bg_color = $4,
+  logo_url = $5,
+  size = $6,
+  format = $7,
+  error_correction = $8,
+  expire_at = $9,
+  logo_id = $10,
+  style = $11,
+  quiet_zone = $12,
+  updated_at = now()
+WHERE id = $1
+RETURNING id, url_id, qr_code, fg_color, bg_color, expire_at, created_at, logo_url, size, format, error_correction, updated_at, logo_id, style, quiet_zone
+`
+
+type UpdateQRCodeParams struct {
+	ID              int32           `json:"id"`
+	QrCode          string          `json:"qr_code"`
+	FgColor         sql.NullString  `json:"fg_color"`
+	BgColor         sql.NullString  `json:"bg_color"`
+	LogoUrl         sql.NullString  `json:"logo_url"`
+	Size            int32           `json:"size"`
+	Format          string          `json:"format"`
+	ErrorCorrection string          `json:"error_correction"`
+	ExpireAt        sql.NullTime    `json:"expire_at"`
+	LogoID          sql.NullInt32   `json:"logo_id"`
+	Style           json.RawMessage `json:"style"`
+	QuietZone       int32           `json:"quiet_zone"`
+}
+
+func (q *Queries) UpdateQRCode(ctx context.Context, arg UpdateQRCodeParams) (QrCode, error) {
+	row := q.db.QueryRowContext(ctx, updateQRCode,
+		arg.ID,
+		arg.QrCode,
+		arg.FgColor,
+		arg.BgColor,
+		arg.LogoUrl,
+		arg.Size,
+		arg.Format,
+		arg.ErrorCorrection,
+		arg.ExpireAt,
+		arg.LogoID,
+		arg.Style,
+		arg.QuietZone,
+	)
+	var i QrCode
+	err := row.Scan(
+		&i.ID,
+		&i.UrlID,
+		&i.QrCode,
+		&i.FgColor,
+		&i.BgColor,
+		&i.ExpireAt,
+		&i.CreatedAt,
+		&i.LogoUrl,
+		&i.Size,
+		&i.Format,
+		&i.ErrorCorrection,
+		&i.UpdatedAt,
+		&i.LogoID,
+		&i.Style,
+		&i.QuietZone,
+	)
+	return i, err
+}
+
 const updateShortURL = `-- name: UpdateShortURL :one
 UPDATE urls
 SET 
   original_url = $1,
   title = $2,
   expire_at = $3,
-  password_hash = $4
+  password_hash = $4,
+  backup_url = $6,
+  safety_status = $7,
+  safety_reason = $8,
+  safety_checked_at = now(),
+  always_preview = $9,
+  og_title = $10,
+  og_description = $11,
+  og_image = $12,
+  health_status = CASE WHEN original_url = $1 THEN health_status END,
+  health_checked_at = CASE WHEN original_url = $1 THEN health_checked_at END,
+  health_streak = CASE WHEN original_url = $1 THEN health_streak ELSE 0 END,
+  thumbnail_checked_at = CASE WHEN original_url = $1 THEN thumbnail_checked_at END
 WHERE short_code = $5
-RETURNING id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id
+RETURNING id, original_url, title, short_code, thumbnail, click_count, password_hash, created_at, expire_at, user_id, health_status, health_checked_at, backup_url, safety_status, safety_reason, safety_checked_at, always_preview, preview_count, og_title, og_description, og_image, thumbnail_checked_at, payload_type, payload, health_streak
 `
 
 type UpdateShortURLParams struct {
-	OriginalUrl  string         `json:"original_url"`
-	Title        sql.NullString `json:"title"`
-	ExpireAt     sql.NullTime   `json:"expire_at"`
-	PasswordHash sql.NullString `json:"password_hash"`
-	ShortCode    string         `json:"short_code"`
+	OriginalUrl   string         `json:"original_url"`
+	Title         sql.NullString `json:"title"`
+	ExpireAt      sql.NullTime   `json:"expire_at"`
+	PasswordHash  sql.NullString `json:"password_hash"`
+	ShortCode     string         `json:"short_code"`
+	BackupUrl     sql.NullString `json:"backup_url"`
+	SafetyStatus  sql.NullString `json:"safety_status"`
+	SafetyReason  sql.NullString `json:"safety_reason"`
+	AlwaysPreview bool           `json:"always_preview"`
+	OgTitle       sql.NullString `json:"og_title"`
+	OgDescription sql.NullString `json:"og_description"`
+	OgImage       sql.NullString `json:"og_image"`
 }
 
 func (q *Queries) UpdateShortURL(ctx context.Context, arg UpdateShortURLParams) (Url, error) {
@@ -1162,6 +3884,13 @@ func (q *Queries) UpdateShortURL(ctx context.Context, arg UpdateShortURLParams)
 		arg.ExpireAt,
 		arg.PasswordHash,
 		arg.ShortCode,
+		arg.BackupUrl,
+		arg.SafetyStatus,
+		arg.SafetyReason,
+		arg.AlwaysPreview,
+		arg.OgTitle,
+		arg.OgDescription,
+		arg.OgImage,
 	)
 	var i Url
 	err := row.Scan(
@@ -1175,6 +3904,21 @@ func (q *Queries) UpdateShortURL(ctx context.Context, arg UpdateShortURLParams)
 		&i.CreatedAt,
 		&i.ExpireAt,
 		&i.UserID,
+		&i.HealthStatus,
+		&i.HealthCheckedAt,
+		&i.BackupUrl,
+		&i.SafetyStatus,
+		&i.SafetyReason,
+		&i.SafetyCheckedAt,
+		&i.AlwaysPreview,
+		&i.PreviewCount,
+		&i.OgTitle,
+		&i.OgDescription,
+		&i.OgImage,
+		&i.ThumbnailCheckedAt,
+		&i.PayloadType,
+		&i.Payload,
+		&i.HealthStreak,
 	)
 	return i, err
 }
@@ -1196,3 +3940,108 @@ func (q *Queries) UpdateTransactionPayment(ctx context.Context, arg UpdateTransa
 	_, err := q.db.ExecContext(ctx, updateTransactionPayment, arg.RazorpayOrderID, arg.Status, arg.RazorpayPaymentID)
 	return err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET password_hash = $2,
+    session_version = session_version + 1,
+    updated_at = now()
+WHERE id = $1
+RETURNING id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at, verification_sent_at, session_version
+`
+
+type UpdateUserPasswordParams struct {
+	ID           int32  `json:"id"`
+	PasswordHash string `json:"password_hash"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.IpAddress,
+		&i.Provider,
+		&i.ProviderID,
+		&i.Image,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.VerificationSentAt,
+		&i.SessionVersion,
+	)
+	return i, err
+}
+
+const updateWebhookEndpoint = `-- name: UpdateWebhookEndpoint :one
+UPDATE webhook_endpoints
+SET url = $3, events = $4, active = $5
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, url, secret, events, active, created_at
+`
+
+type UpdateWebhookEndpointParams struct {
+	ID     int32    `json:"id"`
+	UserID int32    `json:"user_id"`
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+}
+
+func (q *Queries) UpdateWebhookEndpoint(ctx context.Context, arg UpdateWebhookEndpointParams) (WebhookEndpoint, error) {
+	row := q.db.QueryRowContext(ctx, updateWebhookEndpoint,
+		arg.ID,
+		arg.UserID,
+		arg.Url,
+		pq.Array(arg.Events),
+		arg.Active,
+	)
+	var i WebhookEndpoint
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		pq.Array(&i.Events),
+		&i.Active,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now()),
+    updated_at = now()
+WHERE id = $1 AND email = $2
+RETURNING id, username, email, password_hash, ip_address, provider, provider_id, image, created_at, updated_at, email_verified_at, verification_sent_at, session_version
+`
+
+type VerifyUserEmailParams struct {
+	ID    int32  `json:"id"`
+	Email string `json:"email"`
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.IpAddress,
+		&i.Provider,
+		&i.ProviderID,
+		&i.Image,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.VerificationSentAt,
+		&i.SessionVersion,
+	)
+	return i, err
+}
@@ -0,0 +1,96 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Visit is a single ingested click, as streamed to live dashboards.
+type Visit struct {
+	UserID         int32     `json:"-"`
+	Shortcode      string    `json:"shortcode"`
+	Country        string    `json:"country"`
+	Device         string    `json:"device"`
+	Referrer       string    `json:"referrer"`
+	ReferrerSource string    `json:"referrer_source"`
+	Source         string    `json:"source"`
+	ClickedAt      time.Time `json:"clicked_at"`
+}
+
+// Subscription receives the visits of one user, optionally restricted to a
+// set of shortcodes. Read from C until the subscription is closed.
+type Subscription struct {
+	C <-chan Visit
+
+	ch         chan Visit
+	userID     int32
+	shortcodes map[string]bool
+}
+
+func (s *Subscription) wants(v Visit) bool {
+	return len(s.shortcodes) == 0 || s.shortcodes[v.Shortcode]
+}
+
+// Hub fans visits out to every subscription of the owning user. Publishing
+// never blocks the redirect path: a subscriber that falls behind loses
+// events instead.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[int32]map[*Subscription]struct{}
+}
+
+const subscriptionBuffer = 64
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[int32]map[*Subscription]struct{})}
+}
+
+func (h *Hub) Subscribe(userID int32, shortcodes []string) *Subscription {
+	ch := make(chan Visit, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, userID: userID}
+	if len(shortcodes) > 0 {
+		sub.shortcodes = make(map[string]bool, len(shortcodes))
+		for _, code := range shortcodes {
+			sub.shortcodes[code] = true
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	userSubs, ok := h.subs[sub.userID]
+	if !ok {
+		return
+	}
+	if _, ok := userSubs[sub]; !ok {
+		return
+	}
+	delete(userSubs, sub)
+	if len(userSubs) == 0 {
+		delete(h.subs, sub.userID)
+	}
+	close(sub.ch)
+}
+
+func (h *Hub) Publish(v Visit) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs[v.UserID] {
+		if !sub.wants(v) {
+			continue
+		}
+		select {
+		case sub.ch <- v:
+		default:
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"api/internal/db"
+
+	"github.com/mssola/useragent"
+)
+
+// Kind is the value type of an export column. Writers use it to pick the
+// CSV formatting and the Parquet physical type.
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindInt
+	KindTime
+)
+
+// Record is one exported visit plus the fields parsed from its raw columns.
+type Record struct {
+	db.ListVisitsForExportRow
+	Browser string
+	OS      string
+}
+
+// NewRecord parses the browser and operating system out of the user agent.
+func NewRecord(row db.ListVisitsForExportRow) Record {
+	r := Record{ListVisitsForExportRow: row}
+	if row.UserAgent.Valid && row.UserAgent.String != "" {
+		ua := useragent.New(row.UserAgent.String)
+		r.Browser, _ = ua.Browser()
+		r.OS = ua.OSInfo().Name
+	}
+	return r
+}
+
+// Column is a selectable export field. Value returns nil for NULL, otherwise
+// an int64, a time.Time or a string depending on Kind.
+type Column struct {
+	Name  string
+	Kind  Kind
+	Value func(r Record) any
+}
+
+// Columns lists every exportable field in default output order.
+var Columns = []Column{
+	{"id", KindInt, func(r Record) any { return int64(r.ID) }},
+	{"shortcode", KindString, func(r Record) any { return r.ShortCode }},
+	{"clicked_at", KindTime, func(r Record) any {
+		if !r.ClickedAt.Valid {
+			return nil
+		}
+		return r.ClickedAt.Time
+	}},
+	{"ip_address", KindString, func(r Record) any { return nullString(r.IpAddress) }},
+	{"user_agent", KindString, func(r Record) any { return nullString(r.UserAgent) }},
+	{"browser", KindString, func(r Record) any { return emptyToNil(r.Browser) }},
+	{"os", KindString, func(r Record) any { return emptyToNil(r.OS) }},
+	{"device_type", KindString, func(r Record) any { return nullString(r.DeviceType) }},
+	{"referrer", KindString, func(r Record) any { return nullString(r.Referrer) }},
+	{"referrer_domain", KindString, func(r Record) any { return nullString(r.ReferrerDomain) }},
+	{"referrer_source", KindString, func(r Record) any { return nullString(r.ReferrerSource) }},
+	{"country", KindString, func(r Record) any { return nullString(r.Country) }},
+	{"region", KindString, func(r Record) any { return nullString(r.Region) }},
+	{"city", KindString, func(r Record) any { return nullString(r.City) }},
+	{"destination", KindString, func(r Record) any { return nullString(r.Destination) }},
+	{"source", KindString, func(r Record) any { return r.Source }},
+}
+
+// ParseColumns resolves column names in the order given. An empty list
+// selects every column.
+func ParseColumns(names []string) ([]Column, error) {
+	if len(names) == 0 {
+		return Columns, nil
+	}
+
+	selected := make([]Column, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		col, ok := lookupColumn(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		seen[name] = true
+		selected = append(selected, col)
+	}
+	if len(selected) == 0 {
+		return Columns, nil
+	}
+	return selected, nil
+}
+
+// ColumnNames returns the names of cols, as stored on export jobs.
+func ColumnNames(cols []Column) []string {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.Name
+	}
+	return names
+}
+
+func lookupColumn(name string) (Column, bool) {
+	for _, col := range Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return Column{}, false
+}
+
+func nullString(ns sql.NullString) any {
+	if !ns.Valid {
+		return nil
+	}
+	return ns.String
+}
+
+func emptyToNil(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
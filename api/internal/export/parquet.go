@@ -0,0 +1,330 @@
+package export
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// A minimal Parquet writer: flat schema, every column OPTIONAL, one
+// uncompressed PLAIN data page per column chunk and row groups of
+// parquetRowGroupSize rows. That is enough for pandas, Spark, DuckDB and
+// BigQuery to load the export.
+
+const (
+	parquetMagic        = "PAR1"
+	parquetRowGroupSize = 50000
+
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	repetitionOptional = 1
+	encodingPlain      = 0
+	encodingRLE        = 3
+	pageTypeData       = 0
+	codecUncompressed  = 0
+)
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int64
+	size   int64
+}
+
+type parquetWriter struct {
+	out     *countingWriter
+	columns []Column
+	values  [][]any
+	rows    int
+	groups  []parquetRowGroup
+	total   int64
+}
+
+func newParquetWriter(w io.Writer, columns []Column) (*parquetWriter, error) {
+	pw := &parquetWriter{
+		out:     &countingWriter{w: w},
+		columns: columns,
+		values:  make([][]any, len(columns)),
+	}
+	if _, err := io.WriteString(pw.out, parquetMagic); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (w *parquetWriter) WriteRecord(r Record) error {
+	for i, col := range w.columns {
+		w.values[i] = append(w.values[i], col.Value(r))
+	}
+	w.rows++
+	if w.rows == parquetRowGroupSize {
+		return w.flushRowGroup()
+	}
+	return nil
+}
+
+// Flush is a no-op: row groups are written as soon as they fill up.
+func (w *parquetWriter) Flush() error {
+	return nil
+}
+
+func (w *parquetWriter) Close() error {
+	if w.rows > 0 {
+		if err := w.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	footer := w.fileMetaData()
+	if _, err := w.out.Write(footer); err != nil {
+		return err
+	}
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	if _, err := w.out.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.out, parquetMagic)
+	return err
+}
+
+func (w *parquetWriter) flushRowGroup() error {
+	group := parquetRowGroup{rows: int64(w.rows)}
+	for i, col := range w.columns {
+		page := encodePage(col.Kind, w.values[i])
+		header := pageHeader(len(page), w.rows)
+
+		chunk := parquetChunk{offset: w.out.n, size: int64(len(header) + len(page))}
+		if _, err := w.out.Write(header); err != nil {
+			return err
+		}
+		if _, err := w.out.Write(page); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.size
+		w.values[i] = w.values[i][:0]
+	}
+	w.groups = append(w.groups, group)
+	w.total += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+// encodePage builds a v1 data page body: RLE definition levels prefixed by
+// their byte length, followed by the PLAIN encoded non-null values.
+func encodePage(kind Kind, values []any) []byte {
+	var levels []byte
+	for i := 0; i < len(values); {
+		defined := values[i] != nil
+		run := 1
+		for i+run < len(values) && (values[i+run] != nil) == defined {
+			run++
+		}
+		levels = binary.AppendUvarint(levels, uint64(run)<<1)
+		if defined {
+			levels = append(levels, 1)
+		} else {
+			levels = append(levels, 0)
+		}
+		i += run
+	}
+
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	page = append(page, levels...)
+	for _, v := range values {
+		switch v := v.(type) {
+		case int64:
+			page = binary.LittleEndian.AppendUint64(page, uint64(v))
+		case time.Time:
+			page = binary.LittleEndian.AppendUint64(page, uint64(v.UnixMilli()))
+		case string:
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(v)))
+			page = append(page, v...)
+		}
+	}
+	return page
+}
+
+func pageHeader(size, rows int) []byte {
+	c := newCompactWriter()
+	c.i32(1, pageTypeData)
+	c.i32(2, int32(size))
+	c.i32(3, int32(size))
+	c.beginStruct(5)
+	c.i32(1, int32(rows))
+	c.i32(2, encodingPlain)
+	c.i32(3, encodingRLE)
+	c.i32(4, encodingRLE)
+	c.endStruct()
+	return c.finish()
+}
+
+func (w *parquetWriter) fileMetaData() []byte {
+	c := newCompactWriter()
+	c.i32(1, 1)
+
+	c.listHeader(2, compactStruct, len(w.columns)+1)
+	c.beginElem()
+	c.str(4, "schema")
+	c.i32(5, int32(len(w.columns)))
+	c.endStruct()
+	for _, col := range w.columns {
+		physical, converted := parquetType(col.Kind)
+		c.beginElem()
+		c.i32(1, physical)
+		c.i32(3, repetitionOptional)
+		c.str(4, col.Name)
+		if converted >= 0 {
+			c.i32(6, converted)
+		}
+		c.endStruct()
+	}
+
+	c.i64(3, w.total)
+
+	c.listHeader(4, compactStruct, len(w.groups))
+	for _, group := range w.groups {
+		c.beginElem()
+		c.listHeader(1, compactStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			physical, _ := parquetType(w.columns[i].Kind)
+			c.beginElem()
+			c.i64(2, chunk.offset)
+			c.beginStruct(3)
+			c.i32(1, physical)
+			c.listHeader(2, compactI32, 2)
+			c.elemI32(encodingPlain)
+			c.elemI32(encodingRLE)
+			c.listHeader(3, compactBinary, 1)
+			c.elemStr(w.columns[i].Name)
+			c.i32(4, codecUncompressed)
+			c.i64(5, group.rows)
+			c.i64(6, chunk.size)
+			c.i64(7, chunk.size)
+			c.i64(9, chunk.offset)
+			c.endStruct()
+			c.endStruct()
+		}
+		c.i64(2, group.size)
+		c.i64(3, group.rows)
+		c.endStruct()
+	}
+
+	c.str(6, "url_shortner export")
+	return c.finish()
+}
+
+func parquetType(kind Kind) (physical int32, converted int32) {
+	switch kind {
+	case KindInt:
+		return parquetInt64, -1
+	case KindTime:
+		return parquetInt64, convertedTimestampMillis
+	default:
+		return parquetByteArray, convertedUTF8
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Thrift compact protocol, limited to what the Parquet metadata needs.
+
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+type compactWriter struct {
+	buf  []byte
+	last []int16
+}
+
+func newCompactWriter() *compactWriter {
+	return &compactWriter{last: []int16{0}}
+}
+
+func (c *compactWriter) field(id int16, typ byte) {
+	top := len(c.last) - 1
+	if delta := id - c.last[top]; delta > 0 && delta <= 15 {
+		c.buf = append(c.buf, byte(delta)<<4|typ)
+	} else {
+		c.buf = append(c.buf, typ)
+		c.buf = binary.AppendVarint(c.buf, int64(id))
+	}
+	c.last[top] = id
+}
+
+func (c *compactWriter) i32(id int16, v int32) {
+	c.field(id, compactI32)
+	c.buf = binary.AppendVarint(c.buf, int64(v))
+}
+
+func (c *compactWriter) i64(id int16, v int64) {
+	c.field(id, compactI64)
+	c.buf = binary.AppendVarint(c.buf, v)
+}
+
+func (c *compactWriter) str(id int16, s string) {
+	c.field(id, compactBinary)
+	c.elemStr(s)
+}
+
+func (c *compactWriter) beginStruct(id int16) {
+	c.field(id, compactStruct)
+	c.beginElem()
+}
+
+// beginElem starts a struct that is a list element and so has no field header.
+func (c *compactWriter) beginElem() {
+	c.last = append(c.last, 0)
+}
+
+func (c *compactWriter) endStruct() {
+	c.buf = append(c.buf, 0)
+	c.last = c.last[:len(c.last)-1]
+}
+
+func (c *compactWriter) listHeader(id int16, elem byte, size int) {
+	c.field(id, compactList)
+	if size < 15 {
+		c.buf = append(c.buf, byte(size)<<4|elem)
+	} else {
+		c.buf = append(c.buf, 0xf0|elem)
+		c.buf = binary.AppendUvarint(c.buf, uint64(size))
+	}
+}
+
+func (c *compactWriter) elemI32(v int32) {
+	c.buf = binary.AppendVarint(c.buf, int64(v))
+}
+
+func (c *compactWriter) elemStr(s string) {
+	c.buf = binary.AppendUvarint(c.buf, uint64(len(s)))
+	c.buf = append(c.buf, s...)
+}
+
+// finish terminates the top-level struct and returns the encoded bytes.
+func (c *compactWriter) finish() []byte {
+	return append(c.buf, 0)
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"api/internal/db"
+)
+
+const pageSize = 1000
+
+// Query selects the visits to export. An empty ShortCodes exports every link
+// owned by UserID; callers must check ownership of the listed shortcodes.
+type Query struct {
+	UserID     int32
+	ShortCodes []string
+	From       time.Time
+	To         time.Time
+	Location   *time.Location
+}
+
+// Stream writes every visit matching q to w, paging through url_visits by
+// id so memory stays flat however large the range. It returns the number of
+// rows written; w is flushed after each page but not closed.
+func Stream(ctx context.Context, store *db.Queries, q Query, w Writer) (int64, error) {
+	shortCodes := q.ShortCodes
+	if shortCodes == nil {
+		shortCodes = []string{}
+	}
+
+	var written int64
+	var afterID int32
+	for {
+		rows, err := store.ListVisitsForExport(ctx, db.ListVisitsForExportParams{
+			UserID:     sql.NullInt32{Int32: q.UserID, Valid: true},
+			ShortCodes: shortCodes,
+			FromTime:   q.From,
+			ToTime:     q.To,
+			AfterID:    afterID,
+			RowLimit:   pageSize,
+		})
+		if err != nil {
+			return written, err
+		}
+
+		for _, row := range rows {
+			if row.ClickedAt.Valid && q.Location != nil {
+				row.ClickedAt.Time = row.ClickedAt.Time.In(q.Location)
+			}
+			if err := w.WriteRecord(NewRecord(row)); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if err := w.Flush(); err != nil {
+			return written, err
+		}
+
+		if len(rows) < pageSize {
+			return written, nil
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+}
@@ -0,0 +1,171 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"api/internal/db"
+)
+
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+const (
+	// FileTTL is how long a finished job and its file are kept.
+	FileTTL = 7 * 24 * time.Hour
+
+	// cleanupInterval is how often expired jobs are deleted.
+	cleanupInterval = time.Hour
+)
+
+// Worker turns pending export jobs into files under dir.
+type Worker struct {
+	store    *db.Queries
+	dir      string
+	interval time.Duration
+}
+
+func NewWorker(store *db.Queries, dir string) *Worker {
+	return &Worker{store: store, dir: dir, interval: 10 * time.Second}
+}
+
+// Run polls for pending jobs until ctx is cancelled. Jobs left running by a
+// previous process are requeued on start, and jobs finished more than
+// FileTTL ago are deleted along with their files.
+func (w *Worker) Run(ctx context.Context) {
+	if err := os.MkdirAll(w.dir, 0o755); err != nil {
+		log.Printf("export worker: %v", err)
+		return
+	}
+	if err := w.store.RequeueRunningExportJobs(ctx); err != nil {
+		log.Printf("export worker: requeue failed: %v", err)
+	}
+
+	w.cleanup(ctx)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+	for {
+		w.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-cleanup.C:
+			w.cleanup(ctx)
+		}
+	}
+}
+
+// cleanup deletes expired jobs, then their files. A file that cannot be
+// removed is only logged, since its job is already gone.
+func (w *Worker) cleanup(ctx context.Context) {
+	paths, err := w.store.DeleteExpiredExportJobs(ctx, time.Now().Add(-FileTTL))
+	if err != nil {
+		log.Printf("export worker: cleanup failed: %v", err)
+		return
+	}
+	for _, path := range paths {
+		if !path.Valid {
+			continue
+		}
+		if err := os.Remove(path.String); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("export worker: %v", err)
+		}
+	}
+}
+
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.store.ClaimExportJob(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return
+		}
+		if err != nil {
+			log.Printf("export worker: claim failed: %v", err)
+			return
+		}
+		w.process(ctx, job)
+	}
+}
+
+func (w *Worker) process(ctx context.Context, job db.ExportJob) {
+	path := filepath.Join(w.dir, FileName(job))
+	rows, err := w.writeFile(ctx, job, path)
+	if err != nil {
+		log.Printf("export job %d failed: %v", job.ID, err)
+		_ = os.Remove(path)
+		_ = w.store.FailExportJob(ctx, db.FailExportJobParams{
+			ID:    job.ID,
+			Error: sql.NullString{String: err.Error(), Valid: true},
+		})
+		return
+	}
+
+	if err := w.store.CompleteExportJob(ctx, db.CompleteExportJobParams{
+		ID:       job.ID,
+		FilePath: sql.NullString{String: path, Valid: true},
+		RowCount: rows,
+	}); err != nil {
+		log.Printf("export job %d: %v", job.ID, err)
+	}
+}
+
+func (w *Worker) writeFile(ctx context.Context, job db.ExportJob, path string) (int64, error) {
+	columns, err := ParseColumns(job.Columns)
+	if err != nil {
+		return 0, err
+	}
+	loc, err := time.LoadLocation(job.Timezone)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	buf := bufio.NewWriter(f)
+	out, err := NewWriter(job.Format, buf, columns)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := Stream(ctx, w.store, Query{
+		UserID:     job.UserID,
+		ShortCodes: job.ShortCodes,
+		From:       job.FromTime,
+		To:         job.ToTime,
+		Location:   loc,
+	}, out)
+	if err != nil {
+		return 0, err
+	}
+	if err := out.Close(); err != nil {
+		return 0, err
+	}
+	if err := buf.Flush(); err != nil {
+		return 0, err
+	}
+	return rows, f.Close()
+}
+
+// FileName is the download name of a finished job.
+func FileName(job db.ExportJob) string {
+	return fmt.Sprintf("visits-%d-%s-%s.%s", job.ID,
+		job.FromTime.Format("20060102"), job.ToTime.Format("20060102"), job.Format)
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"api/internal/db"
+	"api/internal/dbtest"
+)
+
+func TestWorkerCleanupDeletesExpiredExports(t *testing.T) {
+	conn, store := dbtest.Open(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	user, err := store.CreateUser(ctx, db.CreateUserParams{Username: "ada", Email: "ada@example.com", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish := func(age time.Duration) (db.ExportJob, string) {
+		t.Helper()
+		job, err := store.CreateExportJob(ctx, db.CreateExportJobParams{
+			UserID:     user.ID,
+			Format:     "csv",
+			Columns:    []string{"clicked_at"},
+			ShortCodes: []string{},
+			FromTime:   time.Now().Add(-24 * time.Hour),
+			ToTime:     time.Now(),
+			Timezone:   "UTC",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, FileName(job))
+		if err := os.WriteFile(path, []byte("clicked_at\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.CompleteExportJob(ctx, db.CompleteExportJobParams{
+			ID:       job.ID,
+			FilePath: sql.NullString{String: path, Valid: true},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.ExecContext(ctx, "UPDATE export_jobs SET completed_at = $1 WHERE id = $2", time.Now().Add(-age), job.ID); err != nil {
+			t.Fatal(err)
+		}
+		return job, path
+	}
+	expired, expiredPath := finish(FileTTL + time.Hour)
+	kept, keptPath := finish(time.Hour)
+
+	NewWorker(store, dir).cleanup(ctx)
+
+	if _, err := store.GetExportJob(ctx, db.GetExportJobParams{ID: expired.ID, UserID: user.ID}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expired job: err = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := os.Stat(expiredPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expired file: err = %v, want it removed", err)
+	}
+	if _, err := store.GetExportJob(ctx, db.GetExportJobParams{ID: kept.ID, UserID: user.ID}); err != nil {
+		t.Errorf("recent job: %v", err)
+	}
+	if _, err := os.Stat(keptPath); err != nil {
+		t.Errorf("recent file: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// Writer encodes records in one export format. Close writes any trailing
+// data (the Parquet footer) but does not close the underlying io.Writer.
+type Writer interface {
+	WriteRecord(r Record) error
+	Flush() error
+	Close() error
+}
+
+// NewWriter returns a Writer for format that emits the given columns.
+func NewWriter(format string, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatNDJSON:
+		return &ndjsonWriter{out: w, buf: bufio.NewWriter(w), columns: columns}, nil
+	case FormatParquet:
+		return newParquetWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("format must be one of %s, %s or %s", FormatCSV, FormatNDJSON, FormatParquet)
+	}
+}
+
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatCSV, FormatNDJSON, FormatParquet:
+		return true
+	}
+	return false
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// flushOutput pushes buffered bytes to the client when w is a streaming
+// response (gin.ResponseWriter, http.Flusher).
+func flushOutput(w io.Writer) {
+	if f, ok := w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+type csvWriter struct {
+	out     io.Writer
+	csv     *csv.Writer
+	columns []Column
+	row     []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	cw := &csvWriter{out: w, csv: csv.NewWriter(w), columns: columns, row: make([]string, len(columns))}
+	if err := cw.csv.Write(ColumnNames(columns)); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (w *csvWriter) WriteRecord(r Record) error {
+	for i, col := range w.columns {
+		w.row[i] = formatValue(col.Value(r))
+	}
+	return w.csv.Write(w.row)
+}
+
+func (w *csvWriter) Flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	flushOutput(w.out)
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	return w.Flush()
+}
+
+type ndjsonWriter struct {
+	out     io.Writer
+	buf     *bufio.Writer
+	columns []Column
+}
+
+// WriteRecord writes one JSON object per line, keeping the column order.
+func (w *ndjsonWriter) WriteRecord(r Record) error {
+	w.buf.WriteByte('{')
+	for i, col := range w.columns {
+		if i > 0 {
+			w.buf.WriteByte(',')
+		}
+		w.buf.WriteString(strconv.Quote(col.Name))
+		w.buf.WriteByte(':')
+
+		value := col.Value(r)
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339)
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		w.buf.Write(b)
+	}
+	w.buf.WriteByte('}')
+	_, err := w.buf.WriteString("\n")
+	return err
+}
+
+func (w *ndjsonWriter) Flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	flushOutput(w.out)
+	return nil
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.Flush()
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"api/internal/safehttp"
+)
+
+const (
+	StatusHealthy   = "healthy"
+	StatusUnhealthy = "unhealthy"
+
+	maxRedirects = 10
+)
+
+// Result is the outcome of probing a destination once.
+type Result struct {
+	Status        string
+	StatusCode    int
+	Method        string
+	Latency       time.Duration
+	RedirectChain []string
+	FinalURL      string
+	Err           error
+}
+
+// Checker probes link destinations. A destination is unhealthy when it
+// answers with a 4xx or 5xx status, or cannot be reached within timeout.
+type Checker struct {
+	transport http.RoundTripper
+	timeout   time.Duration
+}
+
+func NewChecker() *Checker {
+	timeout := 10 * time.Second
+	return &Checker{transport: safehttp.NewTransport(timeout), timeout: timeout}
+}
+
+// Check requests rawURL with HEAD and falls back to GET when that fails or
+// is refused, since plenty of servers don't implement HEAD properly.
+func (c *Checker) Check(ctx context.Context, rawURL string) Result {
+	result := c.probe(ctx, http.MethodHead, rawURL)
+	if result.Status == StatusHealthy || ctx.Err() != nil {
+		return result
+	}
+	return c.probe(ctx, http.MethodGet, rawURL)
+}
+
+func (c *Checker) probe(ctx context.Context, method, rawURL string) Result {
+	result := Result{Status: StatusUnhealthy, Method: method, RedirectChain: []string{}}
+
+	req, err := safehttp.NewRequest(ctx, method, rawURL, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	req.Header.Set("User-Agent", "url-shortner-health/1.0")
+
+	client := safehttp.NewClient(safehttp.Options{
+		Timeout:      c.timeout,
+		MaxRedirects: maxRedirects,
+		Transport:    c.transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			result.RedirectChain = append(result.RedirectChain, req.URL.String())
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	})
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.FinalURL = resp.Request.URL.String()
+	if resp.StatusCode < 400 {
+		result.Status = StatusHealthy
+	} else {
+		result.Err = fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return result
+}
@@ -0,0 +1,23 @@
+package health
+
+import "api/internal/db"
+
+const (
+	DestinationPrimary = "primary"
+	DestinationBackup  = "backup"
+)
+
+// Destination picks where a visit to url goes. Visitors are sent to the
+// backup URL while the health worker has the original URL marked
+// unhealthy, and back to the original once it recovers.
+func Destination(url db.Url) (target, kind string) {
+	if FailingOver(url) {
+		return url.BackupUrl.String, DestinationBackup
+	}
+	return url.OriginalUrl, DestinationPrimary
+}
+
+// FailingOver reports whether visits to url currently go to its backup.
+func FailingOver(url db.Url) bool {
+	return url.BackupUrl.Valid && url.BackupUrl.String != "" && url.HealthStatus.String == StatusUnhealthy
+}
@@ -0,0 +1,214 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"api/internal/db"
+	"api/internal/webhook"
+)
+
+// Event is the data of the link.unhealthy and link.recovered events.
+type Event struct {
+	Link       webhook.Link `json:"link"`
+	Status     string       `json:"status"`
+	StatusCode int          `json:"status_code,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	Failover   bool         `json:"failover"`
+	CheckedAt  time.Time    `json:"checked_at"`
+}
+
+const (
+	// unhealthyAfter and healthyAfter are how many checks in a row must
+	// fail or pass before a link is marked unhealthy or recovers.
+	unhealthyAfter = 3
+	healthyAfter   = 2
+
+	// checkRetention is how long recorded checks are kept.
+	checkRetention = 30 * 24 * time.Hour
+	pruneInterval  = time.Hour
+)
+
+// Worker checks the destination of every active link once per recheck
+// period, records the result and notifies the owner when a link turns
+// unhealthy or recovers. Unhealthy links, and links whose latest checks
+// disagree with their status, are checked every unhealthyRecheck so a
+// link fails over soon after its destination goes down and returns to it
+// soon after it recovers.
+type Worker struct {
+	store            *db.Queries
+	checker          *Checker
+	webhooks         *webhook.Dispatcher
+	interval         time.Duration
+	recheck          time.Duration
+	unhealthyRecheck time.Duration
+	batch            int32
+	concurrency      int
+}
+
+func NewWorker(store *db.Queries, webhooks *webhook.Dispatcher) *Worker {
+	return &Worker{
+		store:            store,
+		checker:          NewChecker(),
+		webhooks:         webhooks,
+		interval:         time.Minute,
+		recheck:          30 * time.Minute,
+		unhealthyRecheck: 2 * time.Minute,
+		batch:            100,
+		concurrency:      8,
+	}
+}
+
+// Run checks due links every interval until ctx is cancelled, and deletes
+// checks older than checkRetention every pruneInterval.
+func (w *Worker) Run(ctx context.Context) {
+	w.prune(ctx)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+	for {
+		if err := w.RunOnce(ctx); err != nil {
+			log.Printf("health: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-prune.C:
+			w.prune(ctx)
+		}
+	}
+}
+
+func (w *Worker) prune(ctx context.Context) {
+	if err := w.store.DeleteLinkHealthChecksBefore(ctx, time.Now().Add(-checkRetention)); err != nil {
+		log.Printf("health: pruning checks: %v", err)
+	}
+}
+
+// RunOnce checks every link not checked within the recheck period, least
+// recently checked first.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	for ctx.Err() == nil {
+		links, err := w.store.ListLinksDueForHealthCheck(ctx, db.ListLinksDueForHealthCheckParams{
+			CheckedBefore:          time.Now().Add(-w.recheck),
+			UnhealthyCheckedBefore: time.Now().Add(-w.unhealthyRecheck),
+			MaxLinks:               w.batch,
+		})
+		if err != nil {
+			return err
+		}
+
+		sem := make(chan struct{}, w.concurrency)
+		var wg sync.WaitGroup
+		for _, link := range links {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(link db.Url) {
+				defer func() { <-sem; wg.Done() }()
+				w.check(ctx, link)
+			}(link)
+		}
+		wg.Wait()
+
+		if len(links) < int(w.batch) {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (w *Worker) check(ctx context.Context, link db.Url) {
+	result := w.checker.Check(ctx, link.OriginalUrl)
+	if ctx.Err() != nil {
+		return
+	}
+
+	check := db.CreateLinkHealthCheckParams{
+		UrlID:         link.ID,
+		Status:        result.Status,
+		StatusCode:    sql.NullInt32{Int32: int32(result.StatusCode), Valid: result.StatusCode != 0},
+		Method:        result.Method,
+		LatencyMs:     int32(result.Latency.Milliseconds()),
+		RedirectChain: result.RedirectChain,
+		FinalUrl:      sql.NullString{String: result.FinalURL, Valid: result.FinalURL != ""},
+	}
+	if result.Err != nil {
+		check.Error = sql.NullString{String: result.Err.Error(), Valid: true}
+	}
+	if err := w.store.CreateLinkHealthCheck(ctx, check); err != nil {
+		log.Printf("health: recording check of %s: %v", link.ShortCode, err)
+		return
+	}
+	previous := link.HealthStatus.String
+	status, streak := nextHealth(previous, link.HealthStreak, result.Status)
+	if err := w.store.SetURLHealth(ctx, db.SetURLHealthParams{
+		ID:           link.ID,
+		HealthStatus: sql.NullString{String: status, Valid: status != ""},
+		HealthStreak: streak,
+	}); err != nil {
+		log.Printf("health: updating %s: %v", link.ShortCode, err)
+		return
+	}
+
+	link.HealthStatus = sql.NullString{String: status, Valid: status != ""}
+	link.HealthStreak = streak
+	w.recordFailover(ctx, link)
+
+	var event string
+	switch {
+	case status == StatusUnhealthy && previous != StatusUnhealthy:
+		event = webhook.EventLinkUnhealthy
+	case status == StatusHealthy && previous == StatusUnhealthy:
+		event = webhook.EventLinkRecovered
+	}
+	if event == "" || !link.UserID.Valid {
+		return
+	}
+	w.webhooks.Emit(ctx, link.UserID.Int32, event, Event{
+		Link:       webhook.NewLink(link),
+		Status:     result.Status,
+		StatusCode: result.StatusCode,
+		Error:      check.Error.String,
+		Failover:   FailingOver(link),
+		CheckedAt:  time.Now().UTC(),
+	})
+}
+
+// nextHealth returns a link's status and streak after a check with the
+// given result. streak counts the checks in a row that disagree with
+// status; the status flips once it reaches unhealthyAfter or healthyAfter.
+// A link never checked before is healthy from its first passing check.
+func nextHealth(status string, streak int32, result string) (string, int32) {
+	if result == status || (status == "" && result == StatusHealthy) {
+		return result, 0
+	}
+	streak++
+	needed := int32(healthyAfter)
+	if result == StatusUnhealthy {
+		needed = unhealthyAfter
+	}
+	if streak >= needed {
+		return result, 0
+	}
+	return status, streak
+}
+
+// recordFailover opens a failover period when link starts redirecting to
+// its backup and closes it once it stops.
+func (w *Worker) recordFailover(ctx context.Context, link db.Url) {
+	var err error
+	if FailingOver(link) {
+		err = w.store.StartLinkFailover(ctx, link.ID)
+	} else {
+		err = w.store.EndLinkFailover(ctx, link.ID)
+	}
+	if err != nil {
+		log.Printf("health: recording failover of %s: %v", link.ShortCode, err)
+	}
+}
@@ -0,0 +1,51 @@
+package health
+
+import "testing"
+
+func TestNextHealth(t *testing.T) {
+	const (
+		up   = StatusHealthy
+		down = StatusUnhealthy
+	)
+	tests := []struct {
+		name    string
+		status  string
+		results []string
+		want    []string
+	}{
+		{
+			"new link passing",
+			"", []string{up, up},
+			[]string{up, up},
+		},
+		{
+			"new link failing",
+			"", []string{down, down, down},
+			[]string{"", "", down},
+		},
+		{
+			"blips do not fail over",
+			up, []string{down, down, up, down, down, up},
+			[]string{up, up, up, up, up, up},
+		},
+		{
+			"consecutive failures fail over",
+			up, []string{down, down, down, down},
+			[]string{up, up, down, down},
+		},
+		{
+			"one pass does not recover",
+			down, []string{up, down, up, up},
+			[]string{down, down, down, up},
+		},
+	}
+	for _, tt := range tests {
+		status, streak := tt.status, int32(0)
+		for i, result := range tt.results {
+			status, streak = nextHealth(status, streak, result)
+			if status != tt.want[i] {
+				t.Errorf("%s: after check %d (%s) status = %q, want %q", tt.name, i+1, result, status, tt.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package mail renders the emails the API sends, such as address
+// verification, and delivers them over SMTP.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"api/configs"
+)
+
+// Message is an email with a plain text and an HTML body.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Mailer renders templated messages and hands them to a Sender.
+type Mailer struct {
+	sender Sender
+	from   string
+}
+
+func NewMailer(sender Sender, from string) *Mailer {
+	return &Mailer{sender: sender, from: from}
+}
+
+// Load returns a mailer for the sender selected by MAIL_DRIVER.
+func Load() (*Mailer, error) {
+	from := configs.GetMailFrom()
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM %q: %w", from, err)
+	}
+	switch driver := configs.GetMailDriver(); driver {
+	case "log":
+		return NewMailer(LogSender{}, from), nil
+	case "smtp":
+		sender, err := NewSMTP(SMTPConfig{
+			Host:     configs.GetSMTPHost(),
+			Port:     configs.GetSMTPPort(),
+			Username: configs.GetSMTPUsername(),
+			Password: configs.GetSMTPPassword(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewMailer(sender, from), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+	}
+}
+
+// Send renders the named template with data and sends it to to.
+func (m *Mailer) Send(ctx context.Context, to, template string, data any) error {
+	msg, err := Render(template, data)
+	if err != nil {
+		return err
+	}
+	msg.From, msg.To = m.from, to
+	return m.sender.Send(ctx, msg)
+}
+
+// LogSender prints messages instead of sending them, for development.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// Bytes encodes msg as a multipart/alternative MIME message with CRLF line
+// endings, ready for SMTP DATA.
+func (msg Message) Bytes() ([]byte, error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", msg.From, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(crlf(part.content))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	header := func(name, value string) {
+		out.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from.Address))
+	header("MIME-Version", "1.0")
+	header("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": parts.Boundary()}))
+	out.WriteString("\r\n")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -0,0 +1,225 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP accepts one message over plain SMTP, without STARTTLS or auth,
+// and returns the envelope and the DATA as sent, dot-stuffing and all.
+type fakeSMTP struct {
+	addr string
+	done chan smtpSession
+}
+
+type smtpSession struct {
+	from, to string
+	data     string
+	err      error
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &fakeSMTP{addr: ln.Addr().String(), done: make(chan smtpSession, 1)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.done <- smtpSession{err: err}
+			return
+		}
+		defer conn.Close()
+		s.done <- serveSMTP(conn)
+	}()
+	return s
+}
+
+func serveSMTP(conn net.Conn) smtpSession {
+	var session smtpSession
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		io.WriteString(conn, line+"\r\n")
+	}
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			session.err = err
+			return session
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch verb := strings.ToUpper(strings.Fields(cmd + " ")[0]); verb {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "MAIL":
+			session.from = cmd
+			reply("250 OK")
+		case "RCPT":
+			session.to = cmd
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					session.err = err
+					return session
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			session.data = data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return session
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPSendsMultipartMessage(t *testing.T) {
+	server := newFakeSMTP(t)
+	host, port, _ := net.SplitHostPort(server.addr)
+	sender, err := NewSMTP(SMTPConfig{Host: host, Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer := NewMailer(sender, "Shortener <no-reply@example.com>")
+
+	link := "https://example.com/verify-email?token=" + strings.Repeat("x", 120)
+	err = mailer.Send(context.Background(), "Zoë <zoe@example.com>", "verify_email", map[string]string{
+		"Username":  "zoë",
+		"Link":      link,
+		"ExpiresIn": "24 hours",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := <-server.done
+	if session.err != nil {
+		t.Fatal(session.err)
+	}
+
+	// The server offers 8BITMIME, so the client adds BODY=8BITMIME.
+	if session.from != "MAIL FROM:<no-reply@example.com> BODY=8BITMIME" {
+		t.Errorf("envelope sender = %q", session.from)
+	}
+	if session.to != "RCPT TO:<zoe@example.com>" {
+		t.Errorf("envelope recipient = %q", session.to)
+	}
+	if bare := strings.ReplaceAll(session.data, "\r\n", ""); strings.ContainsAny(bare, "\r\n") {
+		t.Error("message has bare CR or LF line endings")
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(session.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("From"); got != `"Shortener" <no-reply@example.com>` {
+		t.Errorf("From = %q", got)
+	}
+	if to, err := msg.Header.AddressList("To"); err != nil || to[0].Name != "Zoë" || to[0].Address != "zoe@example.com" {
+		t.Errorf("To = %v, %v", to, err)
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); err != nil || subject != "Verify your email address" {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+	for _, name := range []string{"Date", "Message-ID"} {
+		if msg.Header.Get(name) == "" {
+			t.Errorf("missing %s header", name)
+		}
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID = %q, want the sender's domain", msg.Header.Get("Message-ID"))
+	}
+	if msg.Header.Get("MIME-Version") != "1.0" {
+		t.Errorf("MIME-Version = %q", msg.Header.Get("MIME-Version"))
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for _, want := range []string{"text/plain; charset=utf-8", "text/html; charset=utf-8"} {
+		// NextRawPart leaves the transfer encoding for the test to check.
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("reading the %s part: %v", want, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != want {
+			t.Errorf("part Content-Type = %q, want %q", got, want)
+		}
+		if got := part.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+			t.Errorf("%s Content-Transfer-Encoding = %q", want, got)
+		}
+		raw, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(string(raw), "\r\n") {
+			if len(line) > 76 {
+				t.Errorf("%s has a %d character line", want, len(line))
+			}
+		}
+		decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(string(raw))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, string(decoded))
+	}
+	if _, err := parts.NextRawPart(); err != io.EOF {
+		t.Errorf("more than two parts: %v", err)
+	}
+
+	text, html := bodies[0], bodies[1]
+	if !strings.Contains(text, "Hi zoë,\r\n") || !strings.Contains(text, link) {
+		t.Errorf("text part = %q", text)
+	}
+	if strings.Contains(strings.ReplaceAll(text, "\r\n", ""), "\n") {
+		t.Error("text part has bare LF line endings")
+	}
+	if !strings.Contains(html, `href="`+link+`"`) || !strings.Contains(html, "zoë") {
+		t.Errorf("html part = %q", html)
+	}
+}
+
+func TestSMTPReportsUnreachableServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close()
+
+	sender, _ := NewSMTP(SMTPConfig{Host: host, Port: port})
+	err = sender.Send(context.Background(), Message{From: "a@example.com", To: "b@example.com", Subject: "s", Text: "t", HTML: "h"})
+	if err == nil {
+		t.Error("Send to a closed port succeeded")
+	}
+}
+
+func TestNewSMTPRequiresHost(t *testing.T) {
+	if _, err := NewSMTP(SMTPConfig{Port: "25"}); err == nil {
+		t.Error("NewSMTP without a host succeeded")
+	}
+}
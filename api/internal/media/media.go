@@ -0,0 +1,87 @@
+// Package media stores uploaded images under configs.GetMediaDir, which the
+// router serves at /media.
+package media
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"api/configs"
+)
+
+// MaxImageBytes is the largest image accepted, after decoding.
+const MaxImageBytes = 5 << 20
+
+var ErrInvalidImage = errors.New("image must be a base64 data URL of a PNG, JPEG, GIF or WebP image")
+
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// SaveDataURL decodes a "data:image/...;base64," URL and stores the image
+// under folder with a random name. It returns the name relative to the
+// media directory.
+func SaveDataURL(folder, dataURL string) (string, error) {
+	header, encoded, found := strings.Cut(dataURL, ",")
+	if !found || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return "", ErrInvalidImage
+	}
+	if base64.StdEncoding.DecodedLen(len(encoded)) > MaxImageBytes+3 {
+		return "", fmt.Errorf("image is larger than %d MB", MaxImageBytes>>20)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidImage
+	}
+	if len(data) > MaxImageBytes {
+		return "", fmt.Errorf("image is larger than %d MB", MaxImageBytes>>20)
+	}
+
+	// The declared type is ignored; only the content decides.
+	ext, ok := imageExtensions[http.DetectContentType(data)]
+	if !ok {
+		return "", ErrInvalidImage
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	name := folder + "/" + hex.EncodeToString(id) + ext
+
+	path := filepath.Join(configs.GetMediaDir(), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Remove deletes a stored image. Missing files are ignored.
+func Remove(name string) error {
+	if name == "" || strings.Contains(name, "..") {
+		return nil
+	}
+	err := os.Remove(filepath.Join(configs.GetMediaDir(), filepath.FromSlash(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// URL is the public address of a stored image.
+func URL(name string) string {
+	return configs.GetAPIURL() + "/media/" + name
+}
@@ -0,0 +1,68 @@
+package metadata
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache(3, time.Hour)
+	for i := 0; i < 3; i++ {
+		c.Set(strconv.Itoa(i), Metadata{Title: strconv.Itoa(i)})
+	}
+	// Reading "0" makes "1" the least recently used.
+	if _, ok := c.Get("0"); !ok {
+		t.Fatal("Get(0) missed")
+	}
+	c.Set("3", Metadata{Title: "3"})
+
+	if c.Len() != 3 {
+		t.Errorf("Len = %d, want 3", c.Len())
+	}
+	if _, ok := c.Get("1"); ok {
+		t.Error("Get(1) hit after it was evicted")
+	}
+	for _, key := range []string{"0", "2", "3"} {
+		if meta, ok := c.Get(key); !ok || meta.Title != key {
+			t.Errorf("Get(%s) = %+v, %v", key, meta, ok)
+		}
+	}
+}
+
+func TestLRUCacheBoundedByManyURLs(t *testing.T) {
+	c := newLRUCache(100, time.Hour)
+	for i := 0; i < 10000; i++ {
+		c.Set("https://example.com/"+strconv.Itoa(i), Metadata{})
+	}
+	if c.Len() != 100 || len(c.items) != 100 {
+		t.Errorf("Len = %d with %d indexed, want 100", c.Len(), len(c.items))
+	}
+}
+
+func TestLRUCacheExpires(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newLRUCache(10, time.Hour)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", Metadata{Title: "first"})
+	now = now.Add(59 * time.Minute)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Get(a) missed before the TTL")
+	}
+
+	// Setting again restarts the TTL.
+	c.Set("a", Metadata{Title: "second"})
+	now = now.Add(59 * time.Minute)
+	if meta, ok := c.Get("a"); !ok || meta.Title != "second" {
+		t.Fatalf("Get(a) = %+v, %v, want the second value", meta, ok)
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get(a) hit after the TTL")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len = %d, want the expired entry dropped", c.Len())
+	}
+}
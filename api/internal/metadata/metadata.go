@@ -0,0 +1,214 @@
+// Package metadata extracts link details from web pages for the dashboard
+// and for filling in new links.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"api/internal/safehttp"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// maxBodyBytes caps how much of a page is read; metadata lives in the
+	// head, so this is plenty.
+	maxBodyBytes = 1 << 20
+	timeout      = 8 * time.Second
+	cacheTTL     = time.Hour
+	// cacheSize caps the number of pages kept in the cache.
+	cacheSize = 5000
+)
+
+var ErrNotHTML = errors.New("page is not HTML")
+
+// Metadata describes a page. URL is the address after redirects.
+type Metadata struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Canonical   string `json:"canonical_url"`
+	Image       string `json:"image"`
+	Favicon     string `json:"favicon"`
+	SiteName    string `json:"site_name"`
+	Language    string `json:"language"`
+}
+
+// Fetcher downloads pages and caches the extracted metadata by URL.
+type Fetcher struct {
+	client *http.Client
+	cache  *lruCache
+}
+
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		client: safehttp.NewClient(safehttp.Options{Timeout: timeout}),
+		cache:  newLRUCache(cacheSize, cacheTTL),
+	}
+}
+
+// Fetch returns the metadata of the page at rawURL, from the cache when it
+// was fetched within the last hour.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Metadata, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if cached, ok := f.cache.Get(rawURL); ok {
+		return cached, nil
+	}
+
+	req, err := safehttp.NewRequest(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; url-shortner-metadata/1.0)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return Metadata{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Metadata{}, ErrNotHTML
+	}
+
+	// charset.NewReader decodes to UTF-8 using the header or, failing that,
+	// the page's <meta charset>.
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxBodyBytes), contentType)
+	if err != nil {
+		return Metadata{}, err
+	}
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	meta := extract(doc, resp.Request.URL)
+	f.cache.Set(rawURL, meta)
+	return meta, nil
+}
+
+func extract(doc *goquery.Document, base *url.URL) Metadata {
+	meta := Metadata{URL: base.String()}
+
+	meta.Title = first(
+		metaContent(doc, "og:title"),
+		clean(doc.Find("title").First().Text()),
+		metaContent(doc, "twitter:title"),
+	)
+	meta.Description = first(
+		metaContent(doc, "description"),
+		metaContent(doc, "og:description"),
+		metaContent(doc, "twitter:description"),
+	)
+	meta.Canonical = resolve(base, first(
+		linkHref(doc, "canonical"),
+		metaContent(doc, "og:url"),
+	))
+	meta.Image = resolve(base, first(
+		metaContent(doc, "og:image:secure_url"),
+		metaContent(doc, "og:image"),
+		metaContent(doc, "twitter:image"),
+	))
+	meta.Favicon = resolve(base, first(
+		linkHref(doc, "icon"),
+		linkHref(doc, "shortcut icon"),
+		linkHref(doc, "apple-touch-icon"),
+		"/favicon.ico",
+	))
+	meta.SiteName = first(
+		metaContent(doc, "og:site_name"),
+		metaContent(doc, "application-name"),
+		strings.TrimPrefix(base.Hostname(), "www."),
+	)
+
+	lang, _ := doc.Find("html").First().Attr("lang")
+	meta.Language = first(
+		clean(lang),
+		httpEquiv(doc, "content-language"),
+		strings.ReplaceAll(metaContent(doc, "og:locale"), "_", "-"),
+	)
+	return meta
+}
+
+// metaContent reads a <meta> tag by name or property, case-insensitively.
+func metaContent(doc *goquery.Document, key string) string {
+	var value string
+	doc.Find("meta").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		name, _ := s.Attr("name")
+		property, _ := s.Attr("property")
+		if strings.EqualFold(name, key) || strings.EqualFold(property, key) {
+			content, _ := s.Attr("content")
+			value = clean(content)
+			return value == ""
+		}
+		return true
+	})
+	return value
+}
+
+func httpEquiv(doc *goquery.Document, key string) string {
+	var value string
+	doc.Find("meta[http-equiv]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if equiv, _ := s.Attr("http-equiv"); strings.EqualFold(equiv, key) {
+			content, _ := s.Attr("content")
+			value = clean(content)
+			return false
+		}
+		return true
+	})
+	return value
+}
+
+// linkHref reads the href of the first <link> whose rel is exactly rel.
+func linkHref(doc *goquery.Document, rel string) string {
+	var value string
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if r, _ := s.Attr("rel"); strings.EqualFold(clean(r), rel) {
+			href, _ := s.Attr("href")
+			value = strings.TrimSpace(href)
+			return value == ""
+		}
+		return true
+	})
+	return value
+}
+
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clean collapses runs of whitespace, as found in multi-line titles.
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
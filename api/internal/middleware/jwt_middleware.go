@@ -1,12 +1,15 @@
 package middleware
 
 import (
+	"errors"
+
+	"api/internal/db"
 	"api/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func JWTAuthMiddleware() gin.HandlerFunc {
+func JWTAuthMiddleware(store *db.Queries) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		if ctx.Request.URL.Path == "/api/logout" {
 			ctx.Next()
@@ -25,6 +28,15 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			ctx.Abort()
 			return
 		}
+		if err := utils.CheckSession(ctx, store, claims); err != nil {
+			if errors.Is(err, utils.ErrSessionRevoked) {
+				ctx.JSON(401, gin.H{"error": "Session has ended, log in again"})
+			} else {
+				ctx.JSON(500, gin.H{"error": "Failed to check session"})
+			}
+			ctx.Abort()
+			return
+		}
 		ctx.Set("username", claims.Username)
 		ctx.Set("user_id", claims.UserID)
 		ctx.Next()
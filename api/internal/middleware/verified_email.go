@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"api/configs"
+	"api/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifiedEmail lets a request through only once the user has verified
+// their email, unless UNVERIFIED_EMAIL_POLICY is "allow".
+func VerifiedEmail(store *db.Queries) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if configs.GetUnverifiedEmailPolicy() == "allow" {
+			ctx.Next()
+			return
+		}
+
+		verified, err := store.IsUserEmailVerified(ctx, int32(ctx.GetInt64("user_id")))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not check email verification"})
+			return
+		}
+		if !verified {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "verify your email address first"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -28,3 +28,52 @@ type PieChart struct {
 	Title     string `json:"title"`
 	Shortcode string `json:"shortcode"`
 }
+
+// ClicksAndLinksPoint is one gap-filled bucket of the dashboard line chart.
+// Date is the bucket start in the requested time zone.
+type ClicksAndLinksPoint struct {
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+	Links  int64  `json:"links"`
+}
+
+type ClicksPoint struct {
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+}
+
+// BarChartPoint keeps the month/click_count keys the bar charts read; Month
+// holds the bucket start for whatever granularity was requested.
+type BarChartPoint struct {
+	Month      string `json:"month"`
+	ClickCount int64  `json:"click_count"`
+}
+
+// HeatmapResponse holds clicks bucketed by weekday (rows, Monday first) and
+// hour of day (columns) in the requested time zone. Uniques counts each
+// visitor once per cell over the whole range.
+type HeatmapResponse struct {
+	Timezone string       `json:"timezone"`
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Days     []string     `json:"days"`
+	Clicks   [7][24]int64 `json:"clicks"`
+	Uniques  [7][24]int64 `json:"uniques"`
+}
+
+// VisitSources splits clicks by how visitors reached the link.
+type VisitSources struct {
+	Link int64 `json:"link"`
+	QR   int64 `json:"qr"`
+	API  int64 `json:"api"`
+}
+
+type DashboardSummary struct {
+	TotalLinks   int64        `json:"total_links"`
+	TotalClicks  int64        `json:"total_clicks"`
+	ActiveLinks  int64        `json:"active_links"`
+	ExpiredLinks int64        `json:"expired_links"`
+	PeriodLinks  int64        `json:"period_links"`
+	PeriodClicks int64        `json:"period_clicks"`
+	Sources      VisitSources `json:"sources"`
+}
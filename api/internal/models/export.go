@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type ExportJobResponse struct {
+	ID          int32     `json:"id"`
+	Status      string    `json:"status"`
+	Format      string    `json:"format"`
+	Columns     []string  `json:"columns"`
+	Shortcodes  []string  `json:"shortcodes"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Timezone    string    `json:"timezone"`
+	RowCount    int64     `json:"row_count"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   string    `json:"created_at"`
+	CompletedAt string    `json:"completed_at,omitempty"`
+	ExpiresAt   string    `json:"expires_at,omitempty"`
+	DownloadURL string    `json:"download_url,omitempty"`
+}
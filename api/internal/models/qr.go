@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type QRCodeRequest struct {
 	OriginalURL string `json:"original_url" binding:"required"`
 	ExpiryDays  int    `json:"expiry_days"`
@@ -10,7 +12,6 @@ type QRCodeResponse struct {
 	QRcode   string `json:"qrcode"`
 }
 
-// #TODO: Add user logo link struct ****premium feature****
 type QRCodeWithLogoRequest struct {
 	OriginalURL string `json:"original_url,omitempty"`
 	LogoURL     string `json:"logo_url,omitempty"`
@@ -19,3 +20,169 @@ type QRCodeWithLogoRequest struct {
 	Format      string `json:"format,omitempty"` // e.g., "png", "jpeg"
 	ExpireAt    int    `json:"expire_at,omitempty"`
 }
+
+// QRDesign is a saved QR code's look. Empty fields take the defaults:
+// black on white, 512 pixels, PNG, the highest error correction and a
+// quiet zone of 4 modules.
+type QRDesign struct {
+	FgColor         string     `json:"fg_color" binding:"omitempty,len=7,hexcolor"`
+	BgColor         string     `json:"bg_color" binding:"omitempty,len=7,hexcolor"`
+	LogoURL         string     `json:"logo_url" binding:"omitempty,url"`
+	LogoID          *int32     `json:"logo_id"`
+	Size            int        `json:"size" binding:"omitempty,min=64,max=2048"`
+	Format          string     `json:"format" binding:"omitempty,oneof=png jpeg svg pdf"`
+	ErrorCorrection string     `json:"error_correction" binding:"omitempty,oneof=low medium high highest"`
+	QuietZone       *int       `json:"quiet_zone" binding:"omitempty,min=0,max=16"`
+	ExpireAt        *time.Time `json:"expire_at"`
+	Style           QRStyle    `json:"style"`
+}
+
+// QRRenderQuery sets the size of a code rendered on the fly.
+type QRRenderQuery struct {
+	Size            int    `form:"size" binding:"omitempty,min=64,max=2048"`
+	ErrorCorrection string `form:"error_correction" binding:"omitempty,oneof=low medium high highest"`
+	QuietZone       *int   `form:"quiet_zone" binding:"omitempty,min=0,max=16"`
+}
+
+// QRScanReport is the result of reading a rendered code back. Codes that
+// are not readable are rejected; warnings point at designs close to it.
+type QRScanReport struct {
+	Readable        bool     `json:"readable"`
+	Problem         string   `json:"problem,omitempty"`
+	ContrastRatio   float64  `json:"contrast_ratio"`
+	ErrorBudgetUsed float64  `json:"error_budget_used"`
+	ModulePixels    float64  `json:"module_pixels"`
+	Warnings        []string `json:"warnings,omitempty"`
+}
+
+// QRStyle decorates a QR code beyond plain black squares. Eye and frame
+// colours default to fg_color; a gradient replaces fg_color on the data
+// modules. Frame text is drawn in bg_color.
+type QRStyle struct {
+	ModuleShape   string  `json:"module_shape,omitempty" form:"module_shape" binding:"omitempty,oneof=square rounded dot"`
+	EyeShape      string  `json:"eye_shape,omitempty" form:"eye_shape" binding:"omitempty,oneof=square rounded circle"`
+	EyeColor      string  `json:"eye_color,omitempty" form:"eye_color" binding:"omitempty,len=7,hexcolor"`
+	Gradient      string  `json:"gradient,omitempty" form:"gradient" binding:"omitempty,oneof=linear radial"`
+	GradientStart string  `json:"gradient_start,omitempty" form:"gradient_start" binding:"required_with=Gradient,omitempty,len=7,hexcolor"`
+	GradientEnd   string  `json:"gradient_end,omitempty" form:"gradient_end" binding:"required_with=Gradient,omitempty,len=7,hexcolor"`
+	GradientAngle float64 `json:"gradient_angle,omitempty" form:"gradient_angle" binding:"min=0,max=360"`
+	Transparent   bool    `json:"transparent,omitempty" form:"transparent"`
+	FrameText     string  `json:"frame_text,omitempty" form:"frame_text" binding:"max=32"`
+	FrameColor    string  `json:"frame_color,omitempty" form:"frame_color" binding:"omitempty,len=7,hexcolor"`
+}
+
+type CreateQRDesignRequest struct {
+	Shortcode string `json:"shortcode" binding:"required"`
+	QRDesign
+}
+
+// QRBatchRequest renders one design for many of the caller's links.
+// NameBy picks the file names in the archive: the shortcode, or the link
+// title where there is one.
+type QRBatchRequest struct {
+	Shortcodes []string `json:"shortcodes" binding:"required,min=1,max=500,dive,required,max=20"`
+	NameBy     string   `json:"name_by" binding:"omitempty,oneof=shortcode title"`
+	Design     QRDesign `json:"design"`
+}
+
+type QRDesignResponse struct {
+	ID              int32   `json:"id"`
+	Shortcode       string  `json:"shortcode"`
+	ShortURL        string  `json:"short_url"`
+	ImageURL        string  `json:"image_url"`
+	FgColor         string  `json:"fg_color"`
+	BgColor         string  `json:"bg_color"`
+	LogoURL         string  `json:"logo_url,omitempty"`
+	LogoID          int32   `json:"logo_id,omitempty"`
+	Size            int32   `json:"size"`
+	Format          string  `json:"format"`
+	ErrorCorrection string  `json:"error_correction"`
+	QuietZone       int32   `json:"quiet_zone"`
+	ExpireAt        string  `json:"expire_at,omitempty"`
+	Style           QRStyle `json:"style"`
+	CreatedAt       string  `json:"created_at"`
+	UpdatedAt       string  `json:"updated_at"`
+	// Scan is only set when the design was just rendered.
+	Scan *QRScanReport `json:"scan,omitempty"`
+}
+
+// QRLogoResponse is an uploaded logo in the caller's library. Premium
+// accounts can upload logos and use them in QR codes by ID.
+type QRLogoResponse struct {
+	ID          int32  `json:"id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Width       int32  `json:"width"`
+	Height      int32  `json:"height"`
+	SizeBytes   int32  `json:"size_bytes"`
+	ImageURL    string `json:"image_url"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// QRPayload is structured QR content. Type picks which of the other fields
+// is encoded; vcard and mecard both use Contact.
+type QRPayload struct {
+	Type    string     `json:"type" binding:"required,oneof=vcard mecard wifi email sms tel geo"`
+	Contact *QRContact `json:"contact,omitempty" binding:"required_if=Type vcard,required_if=Type mecard"`
+	WiFi    *QRWiFi    `json:"wifi,omitempty" binding:"required_if=Type wifi"`
+	Email   *QREmail   `json:"email,omitempty" binding:"required_if=Type email"`
+	SMS     *QRSMS     `json:"sms,omitempty" binding:"required_if=Type sms"`
+	Tel     *QRTel     `json:"tel,omitempty" binding:"required_if=Type tel"`
+	Geo     *QRGeo     `json:"geo,omitempty" binding:"required_if=Type geo"`
+}
+
+// Phone numbers are in E.164 form: + and the country code, no spaces.
+type QRContact struct {
+	FirstName    string `json:"first_name,omitempty" binding:"required_without=LastName,max=50"`
+	LastName     string `json:"last_name,omitempty" binding:"max=50"`
+	Organization string `json:"organization,omitempty" binding:"max=100"`
+	Title        string `json:"title,omitempty" binding:"max=100"`
+	Phone        string `json:"phone,omitempty" binding:"omitempty,e164"`
+	Email        string `json:"email,omitempty" binding:"omitempty,email,max=254"`
+	URL          string `json:"url,omitempty" binding:"omitempty,url,max=2048"`
+	Address      string `json:"address,omitempty" binding:"max=200"`
+	Note         string `json:"note,omitempty" binding:"max=500"`
+}
+
+type QRWiFi struct {
+	SSID     string `json:"ssid" binding:"required,max=32"`
+	Password string `json:"password,omitempty" binding:"max=63"`
+	Security string `json:"security,omitempty" binding:"omitempty,oneof=WPA WEP nopass"`
+	Hidden   bool   `json:"hidden,omitempty"`
+}
+
+type QREmail struct {
+	To      string `json:"to" binding:"required,email,max=254"`
+	Subject string `json:"subject,omitempty" binding:"max=200"`
+	Body    string `json:"body,omitempty" binding:"max=1000"`
+}
+
+type QRSMS struct {
+	Phone string `json:"phone" binding:"required,e164"`
+	Body  string `json:"body,omitempty" binding:"max=500"`
+}
+
+type QRTel struct {
+	Phone string `json:"phone" binding:"required,e164"`
+}
+
+type QRGeo struct {
+	Latitude  *float64 `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required,min=-180,max=180"`
+}
+
+// QRPayloadRequest renders a payload with a design. Dynamic codes encode a
+// short link to the payload instead of the payload itself, so it can be
+// changed after printing; Wi-Fi codes cannot be dynamic.
+type QRPayloadRequest struct {
+	QRPayload
+	Dynamic bool     `json:"dynamic"`
+	Design  QRDesign `json:"design"`
+}
+
+// QRPayloadResponse is the payload behind a dynamic code.
+type QRPayloadResponse struct {
+	Shortcode string `json:"shortcode"`
+	ShortURL  string `json:"short_url"`
+	QRPayload
+}
@@ -3,11 +3,16 @@ package models
 import "time"
 
 type ShortURLRequest struct {
-	OriginalURL string     `json:"original_url" binding:"required,url"`
-	Shortcode   string     `json:"shortcode,omitempty"`
-	Title       string     `json:"title,omitempty"`
-	Password    string     `json:"password,omitempty"`
-	ExpireAt    *time.Time `json:"expire_at,omitempty"`
+	OriginalURL   string     `json:"original_url" binding:"required,url"`
+	Shortcode     string     `json:"shortcode,omitempty"`
+	Title         string     `json:"title,omitempty"`
+	Password      string     `json:"password,omitempty"`
+	ExpireAt      *time.Time `json:"expire_at,omitempty"`
+	BackupURL     string     `json:"backup_url,omitempty" binding:"omitempty,url"`
+	AlwaysPreview bool       `json:"always_preview,omitempty"`
+	OGTitle       string     `json:"og_title,omitempty" binding:"max=200"`
+	OGDescription string     `json:"og_description,omitempty" binding:"max=500"`
+	OGImage       string     `json:"og_image,omitempty"`
 }
 type ShortURLResponse struct {
 	ShortURL  string `json:"short_url"`
@@ -20,6 +25,18 @@ type EditURLRequest struct {
 	OriginalURL string `json:"original_url"`
 	ExpireAt    string `json:"expire_at"`
 	Password    string `json:"password"`
+	// BackupURL replaces the failover destination. Leave it out to keep
+	// the current one, or send "" to remove it.
+	BackupURL *string `json:"backup_url" binding:"omitempty,url"`
+	// AlwaysPreview is left unchanged when it is left out.
+	AlwaysPreview *bool `json:"always_preview"`
+	// OGTitle and OGDescription replace the share card text. Leave them
+	// out to keep the current text, or send "" to remove it.
+	OGTitle       *string `json:"og_title" binding:"omitempty,max=200"`
+	OGDescription *string `json:"og_description" binding:"omitempty,max=500"`
+	// OGImage is a base64 data URL replacing the share image. Leave it out
+	// to keep the current image, or send "" to remove it.
+	OGImage *string `json:"og_image"`
 }
 type LinkResponse struct {
 	ID          int64  `json:"id"`
@@ -33,4 +50,33 @@ type LinkResponse struct {
 	ExpireAt    string `json:"expire_at,omitempty"`
 	IsExpired   bool   `json:"is_expired"`
 	Password    bool   `json:"password,omitempty"`
+	Health      string `json:"health,omitempty"`
+	CheckedAt   string `json:"health_checked_at,omitempty"`
+	BackupURL   string `json:"backup_url,omitempty"`
+	Failover    bool   `json:"failover"`
+	Safety      string `json:"safety,omitempty"`
+	SafetyNote  string `json:"safety_reason,omitempty"`
+	Preview     bool   `json:"always_preview"`
+	Previews    int    `json:"previews"`
+	OGTitle     string `json:"og_title,omitempty"`
+	OGDesc      string `json:"og_description,omitempty"`
+	OGImage     string `json:"og_image,omitempty"`
+	// PayloadType is set on links behind dynamic QR payloads.
+	PayloadType string `json:"payload_type,omitempty"`
+}
+
+type LinkFailoverResponse struct {
+	StartedAt string `json:"started_at"`
+	EndedAt   string `json:"ended_at,omitempty"`
+}
+
+type LinkHealthCheckResponse struct {
+	Status        string   `json:"status"`
+	StatusCode    int32    `json:"status_code,omitempty"`
+	Method        string   `json:"method"`
+	LatencyMs     int32    `json:"latency_ms"`
+	RedirectChain []string `json:"redirect_chain"`
+	FinalURL      string   `json:"final_url,omitempty"`
+	Error         string   `json:"error,omitempty"`
+	CheckedAt     string   `json:"checked_at"`
 }
@@ -0,0 +1,32 @@
+package models
+
+import "encoding/json"
+
+type WebhookEndpointRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+type WebhookEndpointResponse struct {
+	ID        int32    `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Active    bool     `json:"active"`
+	CreatedAt string   `json:"created_at"`
+	Secret    string   `json:"secret,omitempty"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID            int32           `json:"id"`
+	Event         string          `json:"event"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	ResponseCode  int32           `json:"response_code,omitempty"`
+	ResponseBody  string          `json:"response_body,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     string          `json:"created_at"`
+	NextAttemptAt string          `json:"next_attempt_at,omitempty"`
+	DeliveredAt   string          `json:"delivered_at,omitempty"`
+}
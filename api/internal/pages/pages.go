@@ -0,0 +1,15 @@
+// Package pages holds the HTML pages the API serves to browsers, such as on
+// short link visits.
+package pages
+
+import (
+	"embed"
+	"html/template"
+)
+
+//go:embed templates/*.html
+var files embed.FS
+
+// Templates is set as the router's HTML renderer; pages are rendered by file
+// name with ctx.HTML.
+var Templates = template.Must(template.ParseFS(files, "templates/*.html"))
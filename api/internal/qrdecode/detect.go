@@ -0,0 +1,254 @@
+package qrdecode
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// bitmap is a thresholded image, true for dark pixels.
+type bitmap struct {
+	width, height int
+	dark          []bool
+}
+
+func (b *bitmap) at(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return false
+	}
+	return b.dark[y*b.width+x]
+}
+
+// binarize splits img into dark and light with Otsu's threshold, which
+// copes with gradients and coloured codes. Transparent pixels are light.
+func binarize(img image.Image, invert bool) *bitmap {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	luma := make([]uint8, w*h)
+	var histogram [256]int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			l := (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+			// Blend over white by alpha.
+			l = (l*int(c.A) + 255*(255-int(c.A))) / 255
+			luma[y*w+x] = uint8(l)
+			histogram[l]++
+		}
+	}
+
+	threshold := otsu(histogram, w*h)
+	b := &bitmap{width: w, height: h, dark: make([]bool, w*h)}
+	for i, l := range luma {
+		b.dark[i] = (int(l) <= threshold) != invert
+	}
+	return b
+}
+
+func otsu(histogram [256]int, total int) int {
+	var sum float64
+	for i, n := range histogram {
+		sum += float64(i * n)
+	}
+	var sumBelow, best float64
+	var below, threshold int
+	for i, n := range histogram {
+		below += n
+		if below == 0 {
+			continue
+		}
+		above := total - below
+		if above == 0 {
+			break
+		}
+		sumBelow += float64(i * n)
+		meanBelow := sumBelow / float64(below)
+		meanAbove := (sum - sumBelow) / float64(above)
+		variance := float64(below) * float64(above) * (meanBelow - meanAbove) * (meanBelow - meanAbove)
+		if variance > best {
+			best, threshold = variance, i
+		}
+	}
+	return threshold
+}
+
+// finder is a candidate finder pattern centre.
+type finder struct {
+	x, y, moduleSize float64
+	count            int
+}
+
+// findFinders returns the centres of the three finder patterns ordered top
+// left, top right and bottom left.
+func findFinders(b *bitmap) ([3]finder, bool) {
+	var candidates []finder
+	for y := 0; y < b.height; y++ {
+		runs := rowRuns(b, y)
+		for i := 0; i+5 <= len(runs); i++ {
+			if !runs[i].dark {
+				continue
+			}
+			counts := [5]int{runs[i].length, runs[i+1].length, runs[i+2].length, runs[i+3].length, runs[i+4].length}
+			if !finderRatio(counts) {
+				continue
+			}
+			total := sum(counts)
+			cx := float64(runs[i+2].start) + float64(runs[i+2].length)/2
+			cy, height, ok := crossCheck(b, int(cx), y, total, false)
+			if !ok {
+				continue
+			}
+			cx, width, ok := crossCheck(b, int(cx), int(cy), height, true)
+			if !ok {
+				continue
+			}
+			// Both cross checks pass through the centre, where round eyes
+			// are as wide as square ones; the row itself may cut a circle
+			// off centre, short of seven modules.
+			candidates = addCandidate(candidates, finder{x: cx, y: cy, moduleSize: float64(width+height) / 14, count: 1})
+		}
+	}
+	if len(candidates) < 3 {
+		return [3]finder{}, false
+	}
+
+	// The real patterns are seen on every row through their centre ball,
+	// so they have the most votes.
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].count > candidates[j].count })
+	return orderFinders(candidates[0], candidates[1], candidates[2]), true
+}
+
+type run struct {
+	start, length int
+	dark          bool
+}
+
+func rowRuns(b *bitmap, y int) []run {
+	var runs []run
+	for x := 0; x < b.width; {
+		start, dark := x, b.at(x, y)
+		for x < b.width && b.at(x, y) == dark {
+			x++
+		}
+		runs = append(runs, run{start: start, length: x - start, dark: dark})
+	}
+	return runs
+}
+
+// finderRatio reports whether counts are dark, light, dark, light, dark
+// runs in the 1:1:3:1:1 ratio of a finder pattern.
+func finderRatio(counts [5]int) bool {
+	total := sum(counts)
+	if total < 7 {
+		return false
+	}
+	module := float64(total) / 7
+	variance := module / 2
+	return math.Abs(module-float64(counts[0])) < variance &&
+		math.Abs(module-float64(counts[1])) < variance &&
+		math.Abs(3*module-float64(counts[2])) < 3*variance &&
+		math.Abs(module-float64(counts[3])) < variance &&
+		math.Abs(module-float64(counts[4])) < variance
+}
+
+// crossCheck measures the pattern through (x, y) along the other axis and
+// returns the centre and size on that axis. total is the size along the
+// first axis, which a pattern must roughly match.
+func crossCheck(b *bitmap, x, y, total int, horizontal bool) (float64, int, bool) {
+	at := func(i int) bool {
+		if horizontal {
+			return b.at(i, y)
+		}
+		return b.at(x, i)
+	}
+	centre, limit := y, b.height
+	if horizontal {
+		centre, limit = x, b.width
+	}
+	if !at(centre) {
+		return 0, 0, false
+	}
+
+	var counts [5]int
+	i := centre
+	for state := 2; state >= 0; state-- {
+		for i >= 0 && at(i) == (state%2 == 0) {
+			counts[state]++
+			i--
+		}
+		if counts[state] == 0 {
+			return 0, 0, false
+		}
+	}
+	start := i + 1
+	i = centre + 1
+	for state := 2; state <= 4; state++ {
+		for i < limit && at(i) == (state%2 == 0) {
+			counts[state]++
+			i++
+		}
+		if counts[state] == 0 {
+			return 0, 0, false
+		}
+	}
+
+	// A pattern is as tall as it is wide.
+	found := sum(counts)
+	if !finderRatio(counts) || 5*abs(found-total) >= 2*total {
+		return 0, 0, false
+	}
+	return float64(start+counts[0]+counts[1]) + float64(counts[2])/2, found, true
+}
+
+func addCandidate(candidates []finder, f finder) []finder {
+	for i, c := range candidates {
+		if math.Abs(c.x-f.x) <= c.moduleSize && math.Abs(c.y-f.y) <= c.moduleSize &&
+			math.Abs(c.moduleSize-f.moduleSize) <= math.Max(1, c.moduleSize/2) {
+			n := float64(c.count)
+			candidates[i] = finder{
+				x:          (c.x*n + f.x) / (n + 1),
+				y:          (c.y*n + f.y) / (n + 1),
+				moduleSize: (c.moduleSize*n + f.moduleSize) / (n + 1),
+				count:      c.count + 1,
+			}
+			return candidates
+		}
+	}
+	return append(candidates, f)
+}
+
+// orderFinders puts the corner pattern first: the one opposite the
+// longest side.
+func orderFinders(a, b, c finder) [3]finder {
+	ab, bc, ac := distance(a, b), distance(b, c), distance(a, c)
+	var corner, p, q finder
+	switch {
+	case bc >= ab && bc >= ac:
+		corner, p, q = a, b, c
+	case ac >= ab && ac >= bc:
+		corner, p, q = b, a, c
+	default:
+		corner, p, q = c, a, b
+	}
+	// With y pointing down, top right to bottom left turns clockwise.
+	if (p.x-corner.x)*(q.y-corner.y)-(p.y-corner.y)*(q.x-corner.x) < 0 {
+		p, q = q, p
+	}
+	return [3]finder{corner, p, q}
+}
+
+func distance(a, b finder) float64 {
+	return math.Hypot(a.x-b.x, a.y-b.y)
+}
+
+func sum(counts [5]int) int {
+	return counts[0] + counts[1] + counts[2] + counts[3] + counts[4]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
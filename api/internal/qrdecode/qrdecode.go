@@ -0,0 +1,363 @@
+// Package qrdecode reads QR codes back from rendered images, to check that
+// generated designs still scan. It expects an upright, undistorted code as
+// this service draws them, not a photo.
+package qrdecode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrNotFound = errors.New("no QR code found")
+	ErrFormat   = errors.New("unreadable format information")
+)
+
+// Result is a decoded code.
+type Result struct {
+	Text    string
+	Version int
+	Level   Level
+	// Damage is the share of the error correction budget used up by the
+	// worst block: 0 for a perfect read, 1 when one more bad codeword
+	// would have made it unreadable.
+	Damage float64
+	// Inverted is set for light modules on a dark background.
+	Inverted bool
+}
+
+// Decode finds and reads the QR code in img, trying light on dark when
+// dark on light fails.
+func Decode(img image.Image) (Result, error) {
+	result, err := decode(binarize(img, false))
+	if err == nil {
+		return result, nil
+	}
+	if inverted, invErr := decode(binarize(img, true)); invErr == nil {
+		inverted.Inverted = true
+		return inverted, nil
+	}
+	return Result{}, err
+}
+
+func decode(b *bitmap) (Result, error) {
+	finders, ok := findFinders(b)
+	if !ok {
+		return Result{}, ErrNotFound
+	}
+	tl, tr, bl := finders[0], finders[1], finders[2]
+	moduleSize := (tl.moduleSize + tr.moduleSize + bl.moduleSize) / 3
+
+	// The finders only give the size roughly, as their width is whole
+	// pixels and scaled modules alternate between two widths, so versions
+	// near the estimate are tried nearest first. Only the right one passes
+	// error correction.
+	estimate := (distance(tl, tr)+distance(tl, bl))/2/moduleSize + 7
+	err := ErrNotFound
+	for i, version := range nearbyVersions(estimate) {
+		result, readErr := readGrid(sampleGrid(b, finders, version), version)
+		if readErr == nil {
+			return result, nil
+		}
+		if i == 0 {
+			err = readErr
+		}
+	}
+	return Result{}, err
+}
+
+// nearbyVersions returns the versions whose size is within 6% of estimate
+// modules, nearest first.
+func nearbyVersions(estimate float64) []int {
+	tolerance := math.Max(4, estimate*0.06)
+	var versions []int
+	for version := 1; version <= 40; version++ {
+		if math.Abs(float64(17+4*version)-estimate) <= tolerance {
+			versions = append(versions, version)
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return math.Abs(float64(17+4*versions[i])-estimate) < math.Abs(float64(17+4*versions[j])-estimate)
+	})
+	return versions
+}
+
+// sampleGrid reads the modules of a code of version whose finder centres
+// are finders.
+func sampleGrid(b *bitmap, finders [3]finder, version int) [][]bool {
+	tl, tr, bl := finders[0], finders[1], finders[2]
+	dim := 17 + 4*version
+	// Finder centres sit at the middle of modules 3 and dim-4, so the grid
+	// follows from them.
+	ux, uy := (tr.x-tl.x)/float64(dim-7), (tr.y-tl.y)/float64(dim-7)
+	vx, vy := (bl.x-tl.x)/float64(dim-7), (bl.y-tl.y)/float64(dim-7)
+	grid := make([][]bool, dim)
+	for y := range grid {
+		grid[y] = make([]bool, dim)
+		for x := range grid[y] {
+			px := tl.x + float64(x-3)*ux + float64(y-3)*vx
+			py := tl.y + float64(x-3)*uy + float64(y-3)*vy
+			grid[y][x] = b.at(int(math.Floor(px)), int(math.Floor(py)))
+		}
+	}
+	return grid
+}
+
+func readGrid(grid [][]bool, version int) (Result, error) {
+	level, mask, err := readFormat(grid)
+	if err != nil {
+		return Result{}, err
+	}
+
+	codewords := readCodewords(grid, version, mask)
+	data, damage, err := correctBlocks(codewords, version, level)
+	if err != nil {
+		return Result{}, err
+	}
+	text, err := readSegments(data, version)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Text: text, Version: version, Level: level, Damage: damage}, nil
+}
+
+// readFormat reads both copies of the format information and takes the
+// closest valid code.
+func readFormat(grid [][]bool) (Level, int, error) {
+	dim := len(grid)
+	var first, second int
+	bit := func(code *int, x, y int) {
+		*code <<= 1
+		if grid[y][x] {
+			*code |= 1
+		}
+	}
+	for x := 0; x < 6; x++ {
+		bit(&first, x, 8)
+	}
+	bit(&first, 7, 8)
+	bit(&first, 8, 8)
+	bit(&first, 8, 7)
+	for y := 5; y >= 0; y-- {
+		bit(&first, 8, y)
+	}
+	for y := dim - 1; y >= dim-7; y-- {
+		bit(&second, 8, y)
+	}
+	for x := dim - 8; x < dim; x++ {
+		bit(&second, x, 8)
+	}
+
+	best, bestDistance := 0, 16
+	for data, code := range formatCodes {
+		for _, read := range []int{first, second} {
+			if d := bits.OnesCount(uint(code ^ read)); d < bestDistance {
+				best, bestDistance = data, d
+			}
+		}
+	}
+	if bestDistance > 3 {
+		return 0, 0, ErrFormat
+	}
+	return levelBits[best>>3], best & 7, nil
+}
+
+// readCodewords unmasks the data modules and reads them in the zigzag
+// order of two module wide columns, right to left.
+func readCodewords(grid [][]bool, version, mask int) []byte {
+	dim := len(grid)
+	function := functionPatterns(version)
+	codewords := make([]byte, 0, totalCodewords(version))
+	var current byte
+	var count int
+	up := true
+	for right := dim - 1; right > 0; right -= 2 {
+		// The vertical timing pattern takes a whole column.
+		if right == 6 {
+			right--
+		}
+		for n := 0; n < dim; n++ {
+			y := n
+			if up {
+				y = dim - 1 - n
+			}
+			for col := 0; col < 2; col++ {
+				x := right - col
+				if function[y][x] {
+					continue
+				}
+				current <<= 1
+				if grid[y][x] != masked(mask, y, x) {
+					current |= 1
+				}
+				if count++; count == 8 {
+					codewords = append(codewords, current)
+					current, count = 0, 0
+				}
+			}
+		}
+		up = !up
+	}
+	return codewords
+}
+
+// correctBlocks de-interleaves the codewords into blocks, corrects each
+// and returns the data codewords with the worst block's damage.
+func correctBlocks(codewords []byte, version int, level Level) ([]byte, float64, error) {
+	total := totalCodewords(version)
+	if len(codewords) < total {
+		return nil, 0, ErrFormat
+	}
+	numBlocks, ecLen := ecBlocks[level][version], ecCodewordsPerBlock[level][version]
+	shortLen := total / numBlocks
+	numShort := numBlocks - total%numBlocks
+
+	blocks := make([][]byte, numBlocks)
+	dataLens := make([]int, numBlocks)
+	for i := range blocks {
+		dataLens[i] = shortLen - ecLen
+		if i >= numShort {
+			dataLens[i]++
+		}
+		blocks[i] = make([]byte, 0, dataLens[i]+ecLen)
+	}
+
+	next := 0
+	for i := 0; i < shortLen-ecLen+1; i++ {
+		for j := range blocks {
+			if i < dataLens[j] {
+				blocks[j] = append(blocks[j], codewords[next])
+				next++
+			}
+		}
+	}
+	for i := 0; i < ecLen; i++ {
+		for j := range blocks {
+			blocks[j] = append(blocks[j], codewords[next])
+			next++
+		}
+	}
+
+	var data []byte
+	var damage float64
+	for j, block := range blocks {
+		errs, err := correct(block, ecLen)
+		if err != nil {
+			return nil, 0, err
+		}
+		damage = math.Max(damage, float64(2*errs)/float64(ecLen))
+		data = append(data, block[:dataLens[j]]...)
+	}
+	return data, damage, nil
+}
+
+const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// readSegments decodes numeric, alphanumeric and byte segments. Byte
+// segments are taken as UTF-8, which is what encoders use for URLs.
+func readSegments(data []byte, version int) (string, error) {
+	r := &bitReader{data: data}
+	sizeClass := 0
+	if version >= 27 {
+		sizeClass = 2
+	} else if version >= 10 {
+		sizeClass = 1
+	}
+
+	var text strings.Builder
+	for r.left() >= 4 {
+		mode := r.read(4)
+		switch mode {
+		case 0: // terminator
+			return finish(text.String())
+		case 1: // numeric
+			n := r.read([3]int{10, 12, 14}[sizeClass])
+			for ; n >= 3; n -= 3 {
+				fmt.Fprintf(&text, "%03d", r.read(10))
+			}
+			if n == 2 {
+				fmt.Fprintf(&text, "%02d", r.read(7))
+			} else if n == 1 {
+				fmt.Fprintf(&text, "%d", r.read(4))
+			}
+		case 2: // alphanumeric
+			n := r.read([3]int{9, 11, 13}[sizeClass])
+			for ; n >= 2; n -= 2 {
+				pair := r.read(11)
+				if pair/45 >= len(alphanumeric) {
+					return "", ErrFormat
+				}
+				text.WriteByte(alphanumeric[pair/45])
+				text.WriteByte(alphanumeric[pair%45])
+			}
+			if n == 1 {
+				c := r.read(6)
+				if c >= len(alphanumeric) {
+					return "", ErrFormat
+				}
+				text.WriteByte(alphanumeric[c])
+			}
+		case 4: // byte
+			n := r.read([3]int{8, 16, 16}[sizeClass])
+			if r.left() < 8*n {
+				return "", ErrFormat
+			}
+			for ; n > 0; n-- {
+				text.WriteByte(byte(r.read(8)))
+			}
+		case 7: // ECI designator, assumed UTF-8
+			switch {
+			case r.read(1) == 0:
+				r.read(7)
+			case r.read(1) == 0:
+				r.read(14)
+			default:
+				r.read(22)
+			}
+		default:
+			return "", fmt.Errorf("unsupported segment mode %d", mode)
+		}
+		if r.overrun {
+			return "", ErrFormat
+		}
+	}
+	return finish(text.String())
+}
+
+func finish(text string) (string, error) {
+	if !utf8.ValidString(text) {
+		return "", errors.New("decoded text is not UTF-8")
+	}
+	return text, nil
+}
+
+type bitReader struct {
+	data    []byte
+	offset  int
+	overrun bool
+}
+
+func (r *bitReader) left() int {
+	return 8*len(r.data) - r.offset
+}
+
+func (r *bitReader) read(n int) int {
+	if n > r.left() {
+		r.overrun = true
+		r.offset = 8 * len(r.data)
+		return 0
+	}
+	v := 0
+	for ; n > 0; n-- {
+		byteIndex, bit := r.offset/8, 7-r.offset%8
+		v = v<<1 | int(r.data[byteIndex]>>bit&1)
+		r.offset++
+	}
+	return v
+}
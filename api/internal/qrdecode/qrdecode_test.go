@@ -0,0 +1,94 @@
+package qrdecode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+func TestDecodeRoundTrip(t *testing.T) {
+	levels := map[qrcode.RecoveryLevel]Level{
+		qrcode.Low:     Low,
+		qrcode.Medium:  Medium,
+		qrcode.High:    Quartile,
+		qrcode.Highest: High,
+	}
+	contents := []string{
+		"https://example.com/abc",
+		"HELLO WORLD 123",
+		"0123456789012345678901234567890123456789",
+		"héllo, wörld",
+		"https://example.com/" + strings.Repeat("a", 220),
+		strings.Repeat("https://example.com/long/path?", 20),
+	}
+	for level, want := range levels {
+		for _, content := range contents {
+			// Sizes that are and aren't a whole number of pixels per module.
+			for _, size := range []int{-4, 257, 600} {
+				qr, err := qrcode.New(content, level)
+				if err != nil {
+					t.Fatal(err)
+				}
+				name := fmt.Sprintf("level %s, version %d, %d chars, size %d", want, qr.VersionNumber, len(content), size)
+				result, err := Decode(qr.Image(size))
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+					continue
+				}
+				if result.Text != content || result.Version != qr.VersionNumber || result.Level != want || result.Damage != 0 || result.Inverted {
+					t.Errorf("%s: got %+v", name, result)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeInverted(t *testing.T) {
+	qr, err := qrcode.New("https://example.com/inverted", qrcode.Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qr.ForegroundColor, qr.BackgroundColor = color.White, color.Black
+	result, err := Decode(qr.Image(300))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "https://example.com/inverted" || !result.Inverted {
+		t.Errorf("got %+v, want the text read as inverted", result)
+	}
+}
+
+func TestDecodeReportsDamage(t *testing.T) {
+	qr, err := qrcode.New("https://example.com/damaged", qrcode.Highest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := qr.Image(-10)
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, image.Point{}, draw.Src)
+	// Blot out a few modules in the middle, as a logo would.
+	c := rgba.Bounds().Dx() / 2
+	draw.Draw(rgba, image.Rect(c-20, c-20, c+20, c+20), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	result, err := Decode(rgba)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "https://example.com/damaged" || result.Damage <= 0 || result.Damage > 1 {
+		t.Errorf("got %+v, want the text with some damage", result)
+	}
+}
+
+func TestDecodeNotFound(t *testing.T) {
+	blank := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	draw.Draw(blank, blank.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if _, err := Decode(blank); !errors.Is(err, ErrNotFound) {
+		t.Errorf("blank image: err = %v, want ErrNotFound", err)
+	}
+}
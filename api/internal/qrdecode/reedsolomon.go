@@ -0,0 +1,136 @@
+package qrdecode
+
+import "errors"
+
+var errTooManyErrors = errors.New("too many errors to correct")
+
+// GF(256) with the QR code polynomial x^8 + x^4 + x^3 + x^2 + 1.
+var gfExp, gfLog = func() (exp [512]byte, log [256]byte) {
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[(int(gfLog[a])+255-int(gfLog[b]))%255]
+}
+
+// gfPow returns α^n.
+func gfPow(n int) byte {
+	return gfExp[((n%255)+255)%255]
+}
+
+// evalPoly evaluates a polynomial stored lowest degree first.
+func evalPoly(poly []byte, x byte) byte {
+	var y byte
+	for i := len(poly) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ poly[i]
+	}
+	return y
+}
+
+// correct fixes block, data followed by ecLen error correction codewords,
+// in place and returns the number of codewords it changed.
+func correct(block []byte, ecLen int) (int, error) {
+	n := len(block)
+	// block[k] is the coefficient of x^(n-1-k).
+	syndromes := make([]byte, ecLen)
+	clean := true
+	for j := range syndromes {
+		var s byte
+		x := gfPow(j)
+		for _, c := range block {
+			s = gfMul(s, x) ^ c
+		}
+		syndromes[j] = s
+		clean = clean && s == 0
+	}
+	if clean {
+		return 0, nil
+	}
+
+	// Berlekamp-Massey finds the error locator Λ, lowest degree first.
+	locator, prev := []byte{1}, []byte{1}
+	errs, shift, prevDiscrepancy := 0, 1, byte(1)
+	for i := 0; i < ecLen; i++ {
+		d := syndromes[i]
+		for j := 1; j <= errs && j < len(locator); j++ {
+			d ^= gfMul(locator[j], syndromes[i-j])
+		}
+		if d == 0 {
+			shift++
+			continue
+		}
+		scale := gfDiv(d, prevDiscrepancy)
+		next := make([]byte, max(len(locator), len(prev)+shift))
+		copy(next, locator)
+		for j, c := range prev {
+			next[j+shift] ^= gfMul(scale, c)
+		}
+		if 2*errs <= i {
+			prev, errs, prevDiscrepancy, shift = locator, i+1-errs, d, 1
+		} else {
+			shift++
+		}
+		locator = next
+	}
+	for len(locator) > 1 && locator[len(locator)-1] == 0 {
+		locator = locator[:len(locator)-1]
+	}
+	if errs != len(locator)-1 || 2*errs > ecLen {
+		return 0, errTooManyErrors
+	}
+
+	// Chien search: an error at degree p makes α^-p a root of Λ.
+	var positions []int
+	for p := 0; p < n; p++ {
+		if evalPoly(locator, gfPow(-p)) == 0 {
+			positions = append(positions, p)
+		}
+	}
+	if len(positions) != errs {
+		return 0, errTooManyErrors
+	}
+
+	// Forney: Ω = S·Λ mod x^ecLen, and with the first root at α^0 the
+	// error at X is X·Ω(X⁻¹)/Λ'(X⁻¹).
+	omega := make([]byte, ecLen)
+	for i := range omega {
+		for j := 0; j <= i && j < len(locator); j++ {
+			omega[i] ^= gfMul(locator[j], syndromes[i-j])
+		}
+	}
+	derivative := make([]byte, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+	for _, p := range positions {
+		x, xInv := gfPow(p), gfPow(-p)
+		denominator := evalPoly(derivative, xInv)
+		if denominator == 0 {
+			return 0, errTooManyErrors
+		}
+		block[n-1-p] ^= gfMul(x, gfDiv(evalPoly(omega, xInv), denominator))
+	}
+	return errs, nil
+}
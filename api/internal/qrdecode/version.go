@@ -0,0 +1,145 @@
+package qrdecode
+
+// Level is an error correction level.
+type Level int
+
+const (
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+func (l Level) String() string {
+	return [...]string{"low", "medium", "quartile", "high"}[l]
+}
+
+// levelBits maps the two level bits of the format information to a level.
+var levelBits = [4]Level{Medium, Low, High, Quartile}
+
+// ecCodewordsPerBlock and ecBlocks are indexed by level and version, from
+// table 9 of ISO/IEC 18004.
+var ecCodewordsPerBlock = [4][41]int{
+	{0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+var ecBlocks = [4][41]int{
+	{0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// totalCodewords is the number of codewords a version holds: every module
+// not taken by a function pattern, in whole bytes.
+func totalCodewords(version int) int {
+	modules := (16*version+128)*version + 64
+	if version >= 2 {
+		n := version/7 + 2
+		modules -= (25*n-10)*n - 55
+		if version >= 7 {
+			modules -= 36
+		}
+	}
+	return modules / 8
+}
+
+// alignmentPositions returns the row and column centres of a version's
+// alignment patterns.
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	n := version/7 + 2
+	step := (version*8 + n*3 + 5) / (n*4 - 4) * 2
+	positions := make([]int, n)
+	positions[0] = 6
+	for i, pos := n-1, 4*version+10; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+// functionPatterns marks the modules of a version that carry no data.
+func functionPatterns(version int) [][]bool {
+	dim := 4*version + 17
+	grid := make([][]bool, dim)
+	for i := range grid {
+		grid[i] = make([]bool, dim)
+	}
+	region := func(x, y, w, h int) {
+		for j := y; j < y+h; j++ {
+			for i := x; i < x+w; i++ {
+				grid[j][i] = true
+			}
+		}
+	}
+
+	// Finder patterns with separators and format information.
+	region(0, 0, 9, 9)
+	region(dim-8, 0, 8, 9)
+	region(0, dim-8, 9, 8)
+
+	positions := alignmentPositions(version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			region(x-2, y-2, 5, 5)
+		}
+	}
+
+	// Timing patterns.
+	region(6, 9, 1, dim-17)
+	region(9, 6, dim-17, 1)
+
+	// Version information.
+	if version >= 7 {
+		region(dim-11, 0, 3, 6)
+		region(0, dim-11, 6, 3)
+	}
+	return grid
+}
+
+// formatCodes are the 32 masked format information codes, indexed by
+// their five data bits.
+var formatCodes = func() (codes [32]int) {
+	for data := range codes {
+		remainder := data << 10
+		for bit := 14; bit >= 10; bit-- {
+			if remainder&(1<<bit) != 0 {
+				remainder ^= 0x537 << (bit - 10)
+			}
+		}
+		codes[data] = (data<<10 | remainder) ^ 0x5412
+	}
+	return codes
+}()
+
+// masked reports whether data mask pattern flips the module in row i,
+// column j.
+func masked(pattern, i, j int) bool {
+	switch pattern {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return i*j%2+i*j%3 == 0
+	case 6:
+		return (i*j%2+i*j%3)%2 == 0
+	default:
+		return ((i+j)%2+i*j%3)%2 == 0
+	}
+}
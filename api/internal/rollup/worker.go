@@ -0,0 +1,117 @@
+package rollup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"api/internal/db"
+)
+
+const (
+	// BucketSize is the span of one rollup row. Quarter hours, unlike
+	// hours, regroup exactly into local hours and days in every time zone.
+	BucketSize = 15 * time.Minute
+
+	// watermarkName is the rollup_state row tracking url_visits.
+	watermarkName = "url_visits"
+
+	// lateness is how far behind the watermark each run starts again, so
+	// visits logged after their bucket was first rolled up are still counted.
+	lateness = time.Hour
+
+	// chunk bounds the range of a single rollup statement during backfill.
+	chunk = 24 * time.Hour
+)
+
+// Worker keeps url_visit_rollups, url_visit_rollup_dims and
+// url_visit_rollup_sources in step with url_visits. Every run recomputes
+// whole buckets, so it is safe to rerun.
+type Worker struct {
+	store    *db.Queries
+	interval time.Duration
+}
+
+func NewWorker(store *db.Queries) *Worker {
+	return &Worker{store: store, interval: time.Minute}
+}
+
+// Run rolls up new visits every interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.RunOnce(ctx); err != nil {
+			log.Printf("rollup: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce rolls up every bucket from the stored watermark (or the first
+// visit) through the current, still open bucket, advancing the watermark
+// after each chunk so an interrupted backfill resumes where it stopped.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	from, err := w.start(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	current := time.Now().UTC().Truncate(BucketSize)
+	end := current.Add(BucketSize)
+	for start := from; start.Before(end); start = start.Add(chunk) {
+		stop := start.Add(chunk)
+		if stop.After(end) {
+			stop = end
+		}
+		if err := w.store.RollupVisits(ctx, db.RollupVisitsParams{FromTime: start, ToTime: stop}); err != nil {
+			return err
+		}
+		if err := w.store.RollupVisitDims(ctx, db.RollupVisitDimsParams{FromTime: start, ToTime: stop}); err != nil {
+			return err
+		}
+		if err := w.store.RollupVisitSources(ctx, db.RollupVisitSourcesParams{FromTime: start, ToTime: stop}); err != nil {
+			return err
+		}
+
+		mark := stop
+		if mark.After(current) {
+			mark = current
+		}
+		if err := w.store.SetRollupWatermark(ctx, db.SetRollupWatermarkParams{
+			Name:      watermarkName,
+			Watermark: mark,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Worker) start(ctx context.Context) (time.Time, error) {
+	watermark, err := w.store.GetRollupWatermark(ctx, watermarkName)
+	if err == nil {
+		return watermark.UTC().Truncate(BucketSize).Add(-lateness), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, err
+	}
+
+	first, err := w.store.GetFirstVisitTime(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !first.Valid {
+		return time.Time{}, sql.ErrNoRows
+	}
+	return first.Time.UTC().Truncate(BucketSize), nil
+}
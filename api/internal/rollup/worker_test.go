@@ -0,0 +1,181 @@
+package rollup
+
+import (
+	"context"
+	"database/sql"
+	"maps"
+	"testing"
+	"time"
+
+	"api/internal/db"
+	"api/internal/dbtest"
+)
+
+// Zones a whole number of hours off UTC hide bucketing mistakes, so the
+// cases include half and quarter hour offsets.
+var testZones = []string{"UTC", "Asia/Kolkata", "Asia/Kathmandu", "America/St_Johns"}
+
+type testVisit struct {
+	at time.Time
+	ip string
+}
+
+func TestRollupsRegroupIntoLocalBuckets(t *testing.T) {
+	conn, store := dbtest.Open(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, db.CreateUserParams{Username: "rollup", Email: "rollup@example.com", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := sql.NullInt32{Int32: user.ID, Valid: true}
+	link, err := store.CreateShortURL(ctx, db.CreateShortURLParams{OriginalUrl: "https://example.com", ShortCode: "rollup", UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Visits either side of :30 and :45 past UTC hours, where local hours
+	// start in +05:30, +05:45 and -03:30, and the same visitor a week apart.
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -10)
+	var visits []testVisit
+	for _, v := range []struct {
+		offset time.Duration
+		ip     string
+	}{
+		{10*time.Hour + 10*time.Minute, "10.0.0.1"},
+		{10*time.Hour + 20*time.Minute, "10.0.0.1"},
+		{10*time.Hour + 40*time.Minute, "10.0.0.1"},
+		{10*time.Hour + 50*time.Minute, "10.0.0.2"},
+		{11*time.Hour + 10*time.Minute, "10.0.0.1"},
+		{18*time.Hour + 20*time.Minute, "10.0.0.2"},
+		{18*time.Hour + 40*time.Minute, "10.0.0.2"},
+		{7*24*time.Hour + 10*time.Hour + 40*time.Minute, "10.0.0.1"},
+	} {
+		visit := testVisit{at: day.Add(v.offset), ip: v.ip}
+		if _, err := conn.ExecContext(ctx,
+			"INSERT INTO url_visits (url_id, user_id, ip_address, clicked_at) VALUES ($1, $2, $3, $4)",
+			link.ID, user.ID, visit.ip, visit.at,
+		); err != nil {
+			t.Fatal(err)
+		}
+		visits = append(visits, visit)
+	}
+
+	if err := NewWorker(store).RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	from, to := day.AddDate(0, 0, -1), day.AddDate(0, 0, 9)
+	for _, name := range testZones {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, granularity := range []string{"hour", "day"} {
+			rows, err := store.GetClickSeriesScoped(ctx, db.GetClickSeriesScopedParams{
+				Granularity: granularity,
+				Tz:          name,
+				ShortCode:   link.ShortCode,
+				UserID:      userID,
+				FromTime:    from,
+				ToTime:      to,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]int64{}
+			for _, row := range rows {
+				got[row.Bucket.Format("2006-01-02 15:04")] = row.Clicks
+			}
+			want := map[string]int64{}
+			for _, v := range visits {
+				want[localBucket(v.at.In(loc), granularity)]++
+			}
+			if !maps.Equal(got, want) {
+				t.Errorf("%s by %s: got %v, want %v", name, granularity, got, want)
+			}
+		}
+
+		rows, err := store.GetClickHeatmapScoped(ctx, db.GetClickHeatmapScopedParams{
+			Tz:        name,
+			ShortCode: link.ShortCode,
+			UserID:    userID,
+			FromTime:  from,
+			ToTime:    to,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		type cell struct{ weekday, hour int32 }
+		wantClicks := map[cell]int64{}
+		wantIPs := map[cell]map[string]bool{}
+		for _, v := range visits {
+			local := v.at.In(loc)
+			c := cell{int32(local.Weekday()+6)%7 + 1, int32(local.Hour())}
+			wantClicks[c]++
+			if wantIPs[c] == nil {
+				wantIPs[c] = map[string]bool{}
+			}
+			wantIPs[c][v.ip] = true
+		}
+		if len(rows) != len(wantClicks) {
+			t.Errorf("%s heatmap: got %d cells, want %d", name, len(rows), len(wantClicks))
+		}
+		for _, row := range rows {
+			c := cell{row.Weekday, row.Hour}
+			if row.Clicks != wantClicks[c] || row.Uniques != int64(len(wantIPs[c])) {
+				t.Errorf("%s heatmap %v: got %d clicks, %d uniques, want %d, %d",
+					name, c, row.Clicks, row.Uniques, wantClicks[c], len(wantIPs[c]))
+			}
+		}
+	}
+}
+
+func TestRunOnceIsRepeatable(t *testing.T) {
+	conn, store := dbtest.Open(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, db.CreateUserParams{Username: "rerun", Email: "rerun@example.com", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := sql.NullInt32{Int32: user.ID, Valid: true}
+	link, err := store.CreateShortURL(ctx, db.CreateShortURLParams{OriginalUrl: "https://example.com", ShortCode: "rerun", UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := conn.ExecContext(ctx,
+			"INSERT INTO url_visits (url_id, ip_address, clicked_at) VALUES ($1, '10.0.0.1', $2)",
+			link.ID, time.Now().Add(-time.Duration(i)*20*time.Minute),
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	worker := NewWorker(store)
+	for i := 0; i < 2; i++ {
+		if err := worker.RunOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	summary, err := store.GetDashboardSummaryByUser(ctx, db.GetDashboardSummaryByUserParams{
+		UserID:   userID,
+		FromTime: time.Now().Add(-24 * time.Hour),
+		ToTime:   time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.PeriodClicks != 3 {
+		t.Errorf("period clicks = %d after two runs, want 3", summary.PeriodClicks)
+	}
+}
+
+func localBucket(t time.Time, granularity string) string {
+	if granularity == "hour" {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.UTC).Format("2006-01-02 15:04")
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Format("2006-01-02 15:04")
+}
@@ -3,17 +3,27 @@ package routes
 import (
 	"database/sql"
 
+	"api/configs"
 	"api/internal/controllers"
 	"api/internal/db"
 	"api/internal/errors"
+	"api/internal/events"
+	"api/internal/mail"
+	"api/internal/metadata"
 	"api/internal/middleware"
+	"api/internal/pages"
+	"api/internal/safety"
+	"api/internal/storage"
+	"api/internal/thumbnail"
+	"api/internal/webhook"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(store *db.Queries, conn *sql.DB) *gin.Engine {
+func SetupRouter(store *db.Queries, conn *sql.DB, webhooks *webhook.Dispatcher, scanner *safety.Scanner, files storage.Storage, fetcher *metadata.Fetcher, thumbnails *thumbnail.Service, mailer *mail.Mailer) *gin.Engine {
 	r := gin.Default()
+	r.SetHTMLTemplate(pages.Templates)
 
 	r.Use(errors.GlobalErrorHandler())
 
@@ -27,13 +37,20 @@ func SetupRouter(store *db.Queries, conn *sql.DB) *gin.Engine {
 		AllowOrigins:     []string{"http://localhost:3000", "https://uhxnpmnnw4r7.share.zrok.io"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Disposition"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "X-QR-Contrast-Ratio", "X-QR-Warnings", "X-Short-URL"},
 		AllowCredentials: true,
 	}))
 
-	authController := controllers.NewAuthController(store, conn)
-	URLController := controllers.NewURLController(store)
-	titleController := controllers.NewTitleController()
+	hub := events.NewHub()
+
+	authController := controllers.NewAuthController(store, conn, mailer)
+
+	URLController := controllers.NewURLController(store, hub, webhooks, scanner, fetcher, thumbnails, files)
+	titleController := controllers.NewTitleController(fetcher)
+	thumbnailController := controllers.NewThumbnailController(files)
+	qrController := controllers.NewQRController(store, files, webhooks)
+	exportController := controllers.NewExportController(store)
+	webhookController := controllers.NewWebhookController(store, webhooks)
 	transactionController := controllers.NewTransactionController(store, conn)
 	router := r.Group("/")
 
@@ -44,19 +61,38 @@ func SetupRouter(store *db.Queries, conn *sql.DB) *gin.Engine {
 	routerAPI.GET("/logout", authController.Logout)
 	routerAPI.GET("/check-username", authController.CheckUsername)
 	routerAPI.GET("/check-email", authController.CheckEmail)
+	routerAPI.GET("/verify-email", authController.VerifyEmail)
+	routerAPI.POST("/verify-email/resend", authController.ResendVerificationEmail)
+	routerAPI.POST("/password/forgot", authController.ForgotPassword)
+	routerAPI.POST("/password/reset", authController.ResetPassword)
+	routerAPI.GET("/resolve/:shortcode", URLController.ResolveShortURL)
 
 	routerAPI.GET("/auth/:provider/callback", authController.ProviderCallback)
 	routerAPI.GET("/auth/:provider", authController.ProviderRedirect)
 
 	protected := routerAPI.Group("/protected")
 
-	protected.Use(middleware.JWTAuthMiddleware())
+	protected.Use(middleware.JWTAuthMiddleware(store))
+
+	verified := middleware.VerifiedEmail(store)
 
 	protected.GET("/shorten/qr/:shortcode", URLController.GetQRCode)
 	protected.GET("/links", URLController.GetUserURLs)
 	protected.GET("/title", titleController.GetPageTitle)
 	protected.DELETE("/links/:shortcode", URLController.DeleteShortURL)
+	protected.GET("/links/:shortcode/health", URLController.GetLinkHealth)
+	protected.GET("/links/:shortcode/failovers", URLController.GetLinkFailovers)
 	protected.GET("/shorten/qr-with-logo", URLController.FetchQRCodeWithLogo)
+	protected.POST("/qr-codes", verified, qrController.CreateQRCode)
+	protected.GET("/qr-codes", qrController.ListQRCodes)
+	protected.POST("/qr-codes/batch", verified, qrController.BatchQRCodes)
+	protected.GET("/qr-codes/:id", qrController.GetQRCode)
+	protected.PUT("/qr-codes/:id", verified, qrController.UpdateQRCode)
+	protected.DELETE("/qr-codes/:id", qrController.DeleteQRCode)
+	protected.GET("/qr-codes/:id/image", qrController.GetQRCodeImage)
+	protected.POST("/qr-payloads", verified, qrController.CreatePayloadQRCode)
+	protected.GET("/qr-payloads/:shortcode", qrController.GetPayload)
+	protected.PUT("/qr-payloads/:shortcode", verified, qrController.UpdatePayload)
 
 	protected.GET("/titles", URLController.GetTitleAndUrlByUser)
 
@@ -65,6 +101,14 @@ func SetupRouter(store *db.Queries, conn *sql.DB) *gin.Engine {
 	protected.GET("/analytics/line", URLController.GetLineChartStats)
 	protected.GET("/analytics/bar", URLController.GetMonthlyClicks)
 	protected.GET("/analytics/worldmap", URLController.GetWorldMapData)
+	protected.GET("/analytics/referrers", URLController.GetReferrerStats)
+	protected.GET("/analytics/heatmap", URLController.GetClickHeatmap)
+	protected.GET("/analytics/live", URLController.StreamVisits)
+	protected.GET("/analytics/export", exportController.ExportVisits)
+	protected.POST("/analytics/exports", exportController.CreateExportJob)
+	protected.GET("/analytics/exports", exportController.ListExportJobs)
+	protected.GET("/analytics/exports/:id", exportController.GetExportJob)
+	protected.GET("/analytics/exports/:id/download", exportController.DownloadExport)
 
 	premiumOnly := middleware.PremiumOnly(transactionController)
 
@@ -72,9 +116,24 @@ func SetupRouter(store *db.Queries, conn *sql.DB) *gin.Engine {
 	protected.GET("/analytics/linechart/:shortcode", premiumOnly, URLController.LineChartStatsByShortcode)
 	protected.GET("/analytics/worldchart/:shortcode", premiumOnly, URLController.GetWorldMapStatsByShortcode)
 	protected.GET("/analytics/barchart/:shortcode", premiumOnly, URLController.GetBarChartStatsByShortcode)
+	protected.GET("/analytics/referrers/:shortcode", premiumOnly, URLController.GetReferrerStatsByShortcode)
+	protected.GET("/analytics/sources/:shortcode", premiumOnly, URLController.GetVisitSourcesByShortcode)
+	protected.GET("/analytics/heatmap/:shortcode", premiumOnly, URLController.GetClickHeatmapByShortcode)
+
+	protected.POST("/qr-logos", premiumOnly, verified, qrController.UploadLogo)
+	protected.GET("/qr-logos", premiumOnly, qrController.ListLogos)
+	protected.GET("/qr-logos/:id/image", premiumOnly, qrController.GetLogoImage)
+	protected.DELETE("/qr-logos/:id", premiumOnly, qrController.DeleteLogo)
+
+	protected.POST("/webhooks", verified, webhookController.CreateEndpoint)
+	protected.GET("/webhooks", webhookController.ListEndpoints)
+	protected.PUT("/webhooks/:id", verified, webhookController.UpdateEndpoint)
+	protected.DELETE("/webhooks/:id", webhookController.DeleteEndpoint)
+	protected.GET("/webhooks/:id/deliveries", webhookController.ListDeliveries)
+	protected.POST("/webhooks/:id/test", webhookController.SendTestEvent)
 
-	protected.POST("/shorten", URLController.CreateShortURL)
-	protected.POST("edit/:shortcode", URLController.UpdateShortURL)
+	protected.POST("/shorten", verified, URLController.CreateShortURL)
+	protected.POST("edit/:shortcode", verified, URLController.UpdateShortURL)
 
 	protected.GET("/premium", transactionController.CheckPremiumStatus)
 	routerAPI.POST("/razorpay/webhook", transactionController.RazorpayWebhook)
@@ -83,6 +142,7 @@ func SetupRouter(store *db.Queries, conn *sql.DB) *gin.Engine {
 	// protected.GET("/transactions", transactionController.GetUserTransactions)
 
 	protected.GET("/account", transactionController.GetUserAccount)
+	protected.PUT("/password", authController.ChangePassword)
 	protected.GET("/bills", transactionController.GetUserBills)
 
 	protected.GET("/me", func(ctx *gin.Context) {
@@ -94,7 +154,12 @@ func SetupRouter(store *db.Queries, conn *sql.DB) *gin.Engine {
 		})
 	})
 	router.GET("/s/:shortcode", URLController.RedirectToOriginalURL)
+	router.GET("/q/:shortcode", URLController.RedirectQRScan)
+	router.Static("/media", configs.GetMediaDir())
+	router.GET("/thumbnails/:name", thumbnailController.GetThumbnail)
+	router.GET("/s/:shortcode/preview", URLController.PreviewShortURL)
 	router.POST("/s/:shortcode/unlock", URLController.VerifyAndRedirect)
+	router.GET("/reset-password", authController.ResetPasswordPage)
 
 	return r
 }
@@ -0,0 +1,215 @@
+// Package safehttp is the HTTP client for every request to a user supplied
+// URL. It refuses to connect to loopback, private, link-local and other
+// non-public addresses. The check runs on the address actually dialled, so
+// it also covers redirects and DNS names that resolve, or re-resolve, to
+// internal hosts.
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"api/configs"
+)
+
+const (
+	defaultTimeout      = 10 * time.Second
+	defaultMaxRedirects = 5
+)
+
+var (
+	ErrBlockedAddress = errors.New("destination address is not allowed")
+	ErrInvalidURL     = errors.New("only absolute http and https URLs can be fetched")
+	ErrTooLarge       = errors.New("response body is too large")
+	ErrContentType    = errors.New("unexpected content type")
+)
+
+// blocked lists the ranges that are not reachable public unicast addresses.
+var blocked = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local, cloud metadata
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, broadcast
+	netip.MustParsePrefix("::/128"),          // unspecified
+	netip.MustParsePrefix("::1/128"),         // loopback
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, may embed any IPv4 address
+	netip.MustParsePrefix("100::/64"),        // discard
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("fc00::/7"),        // unique local
+	netip.MustParsePrefix("fe80::/10"),       // link-local
+	netip.MustParsePrefix("ff00::/8"),        // multicast
+}
+
+// Allowed reports whether addr may be connected to.
+func Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	if !addr.IsValid() {
+		return false
+	}
+	for _, prefix := range blocked {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Options tune a client. Zero values pick the defaults.
+type Options struct {
+	// Timeout bounds the whole request, including redirects and reading
+	// the body.
+	Timeout time.Duration
+
+	// MaxRedirects is how many redirects are followed. Negative values
+	// disable following; the redirect response itself is returned.
+	MaxRedirects int
+
+	// CheckRedirect, if set, is called for every redirect that passed the
+	// client's own checks.
+	CheckRedirect func(req *http.Request, via []*http.Request) error
+
+	// Transport lets several clients share connections. It should come
+	// from NewTransport; nil creates a new one.
+	Transport http.RoundTripper
+}
+
+// NewClient returns an http.Client that only connects to public addresses
+// and only follows redirects to http and https URLs.
+func NewClient(opts Options) *http.Client {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxRedirects == 0 {
+		opts.MaxRedirects = defaultMaxRedirects
+	}
+	transport := opts.Transport
+	if transport == nil {
+		transport = NewTransport(opts.Timeout)
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if opts.MaxRedirects < 0 {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > opts.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", opts.MaxRedirects)
+			}
+			if err := ValidateURL(req.URL); err != nil {
+				return err
+			}
+			if opts.CheckRedirect != nil {
+				return opts.CheckRedirect(req, via)
+			}
+			return nil
+		},
+	}
+}
+
+// NewTransport returns a transport whose dialer rejects addresses that are
+// not Allowed. Proxies are not used, so the check sees the real destination.
+func NewTransport(timeout time.Duration) *http.Transport {
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	allowPrivate := configs.GetAllowPrivateFetch()
+
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !Allowed(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+			}
+			return nil
+		},
+	}
+
+	return &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		ResponseHeaderTimeout: timeout,
+	}
+}
+
+// ValidateURL checks that u is an absolute http or https URL.
+func ValidateURL(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// NewRequest parses and validates rawURL before building the request.
+func NewRequest(ctx context.Context, method, rawURL string, body io.Reader) (*http.Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	if err := ValidateURL(u); err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// ReadBody reads at most limit bytes of resp's body, failing with
+// ErrTooLarge when there is more.
+func ReadBody(resp *http.Response, limit int64) ([]byte, error) {
+	if resp.ContentLength > limit {
+		return nil, ErrTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// CheckContentType fails with ErrContentType unless resp's media type is
+// one of allowed. An entry ending in "/" allows every subtype, as in
+// "image/".
+func CheckContentType(resp *http.Response, allowed ...string) error {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrContentType, resp.Header.Get("Content-Type"))
+	}
+	for _, a := range allowed {
+		if mediaType == a || (a[len(a)-1] == '/' && len(mediaType) > len(a) && mediaType[:len(a)] == a) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrContentType, mediaType)
+}
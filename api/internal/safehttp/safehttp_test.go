@@ -0,0 +1,238 @@
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::1", false},
+		{"::", false},
+		{"fc00::1", false},
+		{"fd12:3456::1", false},
+		{"ff02::1", false},
+		// IPv4-mapped IPv6 addresses are checked as the IPv4 address.
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:8.8.8.8", true},
+		// NAT64 can reach any IPv4 address, public or not.
+		{"64:ff9b::7f00:1", false},
+		{"64:ff9b::a9fe:a9fe", false},
+		{"64:ff9b::808:808", false},
+		// Link-local, including the cloud metadata address.
+		{"169.254.169.254", false},
+		{"169.254.0.1", false},
+		{"fe80::1", false},
+		{"fe80::1%eth0", false},
+		{"febf::1", false},
+	}
+	for _, tt := range tests {
+		if got := Allowed(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+	if Allowed(netip.Addr{}) {
+		t.Error("Allowed(zero Addr) = true")
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	t.Setenv("ALLOW_PRIVATE_FETCH", "")
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(Options{}).Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("err = %v, want ErrBlockedAddress", err)
+	}
+	if reached {
+		t.Error("the loopback server was reached")
+	}
+}
+
+// publicTransport sends requests for public.example to the test server,
+// standing in for a public host, and everything else through the guarded
+// transport.
+type publicTransport struct {
+	server *httptest.Server
+	safe   http.RoundTripper
+}
+
+func (p publicTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Hostname() == "public.example" {
+		req = req.Clone(req.Context())
+		req.URL.Host = strings.TrimPrefix(p.server.URL, "http://")
+		return p.server.Client().Transport.RoundTrip(req)
+	}
+	return p.safe.RoundTrip(req)
+}
+
+func TestClientRefusesRedirectToPrivateAddress(t *testing.T) {
+	t.Setenv("ALLOW_PRIVATE_FETCH", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		to   string
+		want error
+	}{
+		{"http://169.254.169.254/latest/meta-data/", ErrBlockedAddress},
+		{"http://127.0.0.1:1/", ErrBlockedAddress},
+		{"http://10.0.0.1/", ErrBlockedAddress},
+		{"http://[::1]/", ErrBlockedAddress},
+		{"http://[::ffff:127.0.0.1]/", ErrBlockedAddress},
+		{"file:///etc/passwd", ErrInvalidURL},
+	}
+	for _, tt := range tests {
+		client := NewClient(Options{Transport: publicTransport{server: server, safe: NewTransport(0)}})
+		req, err := NewRequest(context.Background(), http.MethodGet, "http://public.example/?to="+tt.to, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("redirect to %s: err = %v, want %v", tt.to, err, tt.want)
+		}
+	}
+}
+
+func TestClientRedirectLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://public.example/next", http.StatusFound)
+	}))
+	defer server.Close()
+	transport := publicTransport{server: server, safe: NewTransport(0)}
+
+	resp, err := NewClient(Options{Transport: transport, MaxRedirects: 2}).Get("http://public.example/")
+	if err == nil {
+		resp.Body.Close()
+		t.Error("endless redirects were followed")
+	}
+
+	resp, err = NewClient(Options{Transport: transport, MaxRedirects: -1}).Get("http://public.example/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("status = %d, want the redirect itself", resp.StatusCode)
+	}
+}
+
+func TestNewRequestRejectsNonHTTP(t *testing.T) {
+	for _, raw := range []string{"file:///etc/passwd", "gopher://example.com", "/relative", "http://", "javascript:alert(1)", "%zz"} {
+		if _, err := NewRequest(context.Background(), http.MethodGet, raw, nil); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("NewRequest(%q): err = %v, want ErrInvalidURL", raw, err)
+		}
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		limit         int64
+		wantErr       error
+	}{
+		{"under the limit", "hello", 5, 10, nil},
+		{"at the limit", "0123456789", 10, 10, nil},
+		{"declared too large", "", 11, 10, ErrTooLarge},
+		{"unknown length over the limit", "0123456789a", -1, 10, ErrTooLarge},
+		{"understated length", "0123456789a", 5, 10, ErrTooLarge},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Body: io.NopCloser(strings.NewReader(tt.body)), ContentLength: tt.contentLength}
+		data, err := ReadBody(resp, tt.limit)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr == nil && string(data) != tt.body {
+			t.Errorf("%s: data = %q, want %q", tt.name, data, tt.body)
+		}
+	}
+}
+
+func TestReadBodyFromServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Flushing first sends the body chunked, without a Content-Length.
+		w.(http.Flusher).Flush()
+		w.Write([]byte(strings.Repeat("x", 2048)))
+	}))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := ReadBody(resp, 1024); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("err = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestCheckContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		allowed     []string
+		ok          bool
+	}{
+		{"image/png", []string{"image/"}, true},
+		{"image/svg+xml; charset=utf-8", []string{"image/"}, true},
+		{"IMAGE/PNG", []string{"image/png"}, true},
+		{"text/html; charset=utf-8", []string{"text/html", "application/xhtml+xml"}, true},
+		{"application/xhtml+xml", []string{"text/html", "application/xhtml+xml"}, true},
+		{"text/html", []string{"image/"}, false},
+		{"imagex/png", []string{"image/"}, false},
+		{"image", []string{"image/"}, false},
+		{"application/json", []string{"application/js"}, false},
+		{"", []string{"image/"}, false},
+		{"not a media type", []string{"image/"}, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.contentType != "" {
+			resp.Header.Set("Content-Type", tt.contentType)
+		}
+		err := CheckContentType(resp, tt.allowed...)
+		if tt.ok && err != nil {
+			t.Errorf("CheckContentType(%q, %v) = %v, want nil", tt.contentType, tt.allowed, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrContentType) {
+			t.Errorf("CheckContentType(%q, %v) = %v, want ErrContentType", tt.contentType, tt.allowed, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package safety
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Blocklist flags URLs whose host is, or is a subdomain of, a listed
+// domain.
+type Blocklist struct {
+	domains map[string]bool
+}
+
+// LoadBlocklist reads one domain per line from path, ignoring blank lines
+// and # comments. It returns nil when the file does not exist.
+func LoadBlocklist(path string) (*Blocklist, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := &Blocklist{domains: make(map[string]bool)}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		b.domains[strings.TrimPrefix(strings.ToLower(line), ".")] = true
+	}
+	return b, scanner.Err()
+}
+
+func (b *Blocklist) Name() string {
+	return "blocklist"
+}
+
+func (b *Blocklist) Check(_ context.Context, rawURL string) (Result, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Result{}, err
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for host != "" {
+		if b.domains[host] {
+			return Result{Verdict: VerdictMalicious, Reason: "domain " + host + " is blocklisted"}, nil
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+	return Result{Verdict: VerdictSafe}, nil
+}
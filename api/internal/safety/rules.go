@@ -0,0 +1,69 @@
+package safety
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type rule struct {
+	verdict string
+	pattern *regexp.Regexp
+}
+
+// Rules flags URLs matching regular expressions.
+type Rules struct {
+	rules []rule
+}
+
+// LoadRules reads "<verdict> <regexp>" lines from path, where verdict is
+// suspicious or malicious, ignoring blank lines and # comments. It returns
+// nil when the file does not exist.
+func LoadRules(path string) (*Rules, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := &Rules{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		verdict, expr, _ := strings.Cut(line, " ")
+		if verdict != VerdictSuspicious && verdict != VerdictMalicious {
+			return nil, fmt.Errorf("%s:%d: unknown verdict %q", path, n, verdict)
+		}
+		pattern, err := regexp.Compile(strings.TrimSpace(expr))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		r.rules = append(r.rules, rule{verdict: verdict, pattern: pattern})
+	}
+	return r, scanner.Err()
+}
+
+func (r *Rules) Name() string {
+	return "rules"
+}
+
+// Check returns the verdict of the most severe matching rule.
+func (r *Rules) Check(_ context.Context, rawURL string) (Result, error) {
+	result := Result{Verdict: VerdictSafe}
+	for _, rule := range r.rules {
+		if severity(rule.verdict) > severity(result.Verdict) && rule.pattern.MatchString(rawURL) {
+			result = Result{Verdict: rule.verdict, Reason: "matches rule " + rule.pattern.String()}
+		}
+	}
+	return result, nil
+}
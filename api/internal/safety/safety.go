@@ -0,0 +1,126 @@
+package safety
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"api/configs"
+)
+
+// Verdicts, from least to most severe. Suspicious links are quarantined
+// behind a warning page; malicious ones are refused outright.
+const (
+	VerdictSafe       = "safe"
+	VerdictSuspicious = "suspicious"
+	VerdictMalicious  = "malicious"
+)
+
+// Result is the verdict on a URL and, when flagged, which checker flagged
+// it and why.
+type Result struct {
+	Verdict string
+	Checker string
+	Reason  string
+}
+
+func (r Result) Flagged() bool {
+	return r.Verdict != VerdictSafe
+}
+
+// Checker is one source of URL reputation.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context, rawURL string) (Result, error)
+}
+
+// Scanner runs every configured checker and keeps the most severe verdict.
+type Scanner struct {
+	checkers []Checker
+}
+
+func NewScanner(checkers ...Checker) *Scanner {
+	return &Scanner{checkers: checkers}
+}
+
+// LoadScanner builds a Scanner from the blocklist and rules files and the
+// Safe Browsing settings. Missing files are skipped.
+func LoadScanner() (*Scanner, error) {
+	var checkers []Checker
+
+	blocklist, err := LoadBlocklist(configs.GetSafetyBlocklistFile())
+	if err != nil {
+		return nil, err
+	}
+	if blocklist != nil {
+		checkers = append(checkers, blocklist)
+	}
+
+	rules, err := LoadRules(configs.GetSafetyRulesFile())
+	if err != nil {
+		return nil, err
+	}
+	if rules != nil {
+		checkers = append(checkers, rules)
+	}
+
+	switch mode := configs.GetSafeBrowsingMode(); mode {
+	case "":
+	case "mock":
+		checkers = append(checkers, NewMockSafeBrowsing())
+	case "api":
+		if configs.GetSafeBrowsingAPIKey() == "" {
+			return nil, fmt.Errorf("SAFE_BROWSING_API_KEY is required when SAFE_BROWSING_MODE is api")
+		}
+		checkers = append(checkers, NewSafeBrowsing(configs.GetSafeBrowsingURL(), configs.GetSafeBrowsingAPIKey()))
+	default:
+		return nil, fmt.Errorf("unknown SAFE_BROWSING_MODE %q", mode)
+	}
+
+	return NewScanner(checkers...), nil
+}
+
+// Scan checks rawURL against every checker. A checker that fails is logged
+// and skipped, so an unreachable reputation API never blocks link creation.
+func (s *Scanner) Scan(ctx context.Context, rawURL string) Result {
+	worst := Result{Verdict: VerdictSafe}
+	for _, checker := range s.checkers {
+		result, err := checker.Check(ctx, rawURL)
+		if err != nil {
+			log.Printf("safety: %s: %v", checker.Name(), err)
+			continue
+		}
+		if severity(result.Verdict) > severity(worst.Verdict) {
+			result.Checker = checker.Name()
+			worst = result
+		}
+		if worst.Verdict == VerdictMalicious {
+			break
+		}
+	}
+	return worst
+}
+
+// ScanAll scans each non-empty URL and returns the most severe result.
+func (s *Scanner) ScanAll(ctx context.Context, rawURLs ...string) Result {
+	worst := Result{Verdict: VerdictSafe}
+	for _, rawURL := range rawURLs {
+		if rawURL == "" {
+			continue
+		}
+		if result := s.Scan(ctx, rawURL); severity(result.Verdict) > severity(worst.Verdict) {
+			worst = result
+		}
+	}
+	return worst
+}
+
+func severity(verdict string) int {
+	switch verdict {
+	case VerdictMalicious:
+		return 2
+	case VerdictSuspicious:
+		return 1
+	}
+	return 0
+}
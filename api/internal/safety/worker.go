@@ -0,0 +1,100 @@
+package safety
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"api/internal/db"
+	"api/internal/webhook"
+)
+
+// Event is the data of the link.flagged event.
+type Event struct {
+	Link    webhook.Link `json:"link"`
+	Verdict string       `json:"verdict"`
+	Reason  string       `json:"reason"`
+}
+
+// Worker rescans the destination and backup URL of every active link once
+// per rescan period, so links that turn bad after creation get quarantined or blocked.
+type Worker struct {
+	store    *db.Queries
+	scanner  *Scanner
+	webhooks *webhook.Dispatcher
+	interval time.Duration
+	rescan   time.Duration
+	batch    int32
+}
+
+func NewWorker(store *db.Queries, scanner *Scanner, webhooks *webhook.Dispatcher) *Worker {
+	return &Worker{
+		store:    store,
+		scanner:  scanner,
+		webhooks: webhooks,
+		interval: 5 * time.Minute,
+		rescan:   24 * time.Hour,
+		batch:    100,
+	}
+}
+
+// Run rescans due links every interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.RunOnce(ctx); err != nil {
+			log.Printf("safety: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce scans every link not scanned within the rescan period.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	for ctx.Err() == nil {
+		links, err := w.store.ListLinksDueForSafetyScan(ctx, db.ListLinksDueForSafetyScanParams{
+			CheckedBefore: time.Now().Add(-w.rescan),
+			MaxLinks:      w.batch,
+		})
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			w.scan(ctx, link)
+		}
+		if len(links) < int(w.batch) {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (w *Worker) scan(ctx context.Context, link db.Url) {
+	result := w.scanner.ScanAll(ctx, link.OriginalUrl, link.BackupUrl.String)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err := w.store.SetURLSafety(ctx, db.SetURLSafetyParams{
+		ID:           link.ID,
+		SafetyStatus: sql.NullString{String: result.Verdict, Valid: true},
+		SafetyReason: sql.NullString{String: result.Reason, Valid: result.Reason != ""},
+	}); err != nil {
+		log.Printf("safety: updating %s: %v", link.ShortCode, err)
+		return
+	}
+
+	if result.Flagged() && result.Verdict != link.SafetyStatus.String && link.UserID.Valid {
+		w.webhooks.Emit(ctx, link.UserID.Int32, webhook.EventLinkFlagged, Event{
+			Link:    webhook.NewLink(link),
+			Verdict: result.Verdict,
+			Reason:  result.Reason,
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package storage keeps generated files, such as link thumbnails, on the
+// local filesystem or in an S3 compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"api/configs"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object is a stored file. Body must be closed by the caller.
+type Object struct {
+	Body         io.ReadCloser
+	ContentType  string
+	Size         int64
+	LastModified time.Time
+}
+
+// Storage stores objects under slash separated keys, such as
+// "thumbnails/3f2a.png".
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Load returns the storage selected by STORAGE_DRIVER.
+func Load() (Storage, error) {
+	switch driver := configs.GetStorageDriver(); driver {
+	case "local":
+		return NewLocal(configs.GetStorageDir()), nil
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  configs.GetS3Endpoint(),
+			Region:    configs.GetS3Region(),
+			Bucket:    configs.GetS3Bucket(),
+			AccessKey: configs.GetS3AccessKey(),
+			SecretKey: configs.GetS3SecretKey(),
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+}
+
+// ValidKey reports whether key is a clean relative path that cannot escape
+// the storage root.
+func ValidKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "/") && path.Clean(key) == key &&
+		key != ".." && !strings.HasPrefix(key, "../") && !strings.Contains(key, "\\")
+}
@@ -0,0 +1,150 @@
+// Package thumbnail generates link thumbnails from the destination's
+// favicon or share image and keeps them in our own storage, so dashboards
+// don't hotlink third party services.
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"api/configs"
+	"api/internal/metadata"
+	"api/internal/safehttp"
+	"api/internal/storage"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// Prefix is the storage folder of thumbnails; they are served from the
+	// route of the same name.
+	Prefix = "thumbnails/"
+
+	size          = 128
+	maxImageBytes = 5 << 20
+)
+
+var ErrNoImage = errors.New("no usable image found")
+
+// Service fetches, resizes and stores thumbnails.
+type Service struct {
+	storage storage.Storage
+	fetcher *metadata.Fetcher
+	client  *http.Client
+}
+
+func NewService(store storage.Storage, fetcher *metadata.Fetcher) *Service {
+	return &Service{
+		storage: store,
+		fetcher: fetcher,
+		client:  safehttp.NewClient(safehttp.Options{Timeout: 10 * time.Second}),
+	}
+}
+
+// Generate stores a thumbnail for the page at pageURL under a new key and
+// returns it. The page's icon is preferred, then its share image, then
+// /favicon.ico when the page itself can't be read.
+func (s *Service) Generate(ctx context.Context, pageURL string) (string, error) {
+	var candidates []string
+	if meta, err := s.fetcher.Fetch(ctx, pageURL); err == nil {
+		candidates = append(candidates, meta.Favicon, meta.Image)
+	} else if u, err := url.Parse(pageURL); err == nil && u.Host != "" {
+		candidates = append(candidates, u.Scheme+"://"+u.Host+"/favicon.ico")
+	}
+
+	var img image.Image
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if decoded, err := s.download(ctx, candidate); err == nil {
+			img = decoded
+			break
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+	}
+	if img == nil {
+		return "", ErrNoImage
+	}
+
+	var buf bytes.Buffer
+	thumb := imaging.Fill(img, size, size, imaging.Center, imaging.Lanczos)
+	if err := png.Encode(&buf, thumb); err != nil {
+		return "", err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	key := Prefix + hex.EncodeToString(id) + ".png"
+	if err := s.storage.Put(ctx, key, buf.Bytes(), "image/png"); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Remove deletes a thumbnail generated by Generate. Anything else, such as
+// an external URL stored by older versions, is left alone.
+func (s *Service) Remove(ctx context.Context, thumbnail string) error {
+	if !Owned(thumbnail) {
+		return nil
+	}
+	return s.storage.Delete(ctx, thumbnail)
+}
+
+func (s *Service) download(ctx context.Context, imageURL string) (image.Image, error) {
+	req, err := safehttp.NewRequest(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; url-shortner-thumbnail/1.0)")
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := safehttp.CheckContentType(resp, "image/"); err != nil {
+		return nil, err
+	}
+	data, err := safehttp.ReadBody(resp, maxImageBytes)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Owned reports whether thumbnail is a key in our storage rather than an
+// external URL.
+func Owned(thumbnail string) bool {
+	return strings.HasPrefix(thumbnail, Prefix)
+}
+
+// URL is the public address of a stored thumbnail value.
+func URL(thumbnail string) string {
+	if !Owned(thumbnail) {
+		return thumbnail
+	}
+	return configs.GetAPIURL() + "/" + thumbnail
+}
@@ -0,0 +1,111 @@
+package thumbnail
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"api/internal/db"
+)
+
+// Worker generates thumbnails for links that have none yet and refreshes
+// the rest once per refresh period, so a changed icon shows up eventually.
+type Worker struct {
+	store       *db.Queries
+	service     *Service
+	interval    time.Duration
+	refresh     time.Duration
+	batch       int32
+	concurrency int
+}
+
+func NewWorker(store *db.Queries, service *Service) *Worker {
+	return &Worker{
+		store:       store,
+		service:     service,
+		interval:    10 * time.Minute,
+		refresh:     7 * 24 * time.Hour,
+		batch:       50,
+		concurrency: 4,
+	}
+}
+
+// Run refreshes due thumbnails every interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.RunOnce(ctx); err != nil {
+			log.Printf("thumbnail: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce refreshes every link not refreshed within the refresh period.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	for ctx.Err() == nil {
+		links, err := w.store.ListLinksDueForThumbnail(ctx, db.ListLinksDueForThumbnailParams{
+			CheckedBefore: time.Now().Add(-w.refresh),
+			MaxLinks:      w.batch,
+		})
+		if err != nil {
+			return err
+		}
+
+		sem := make(chan struct{}, w.concurrency)
+		var wg sync.WaitGroup
+		for _, link := range links {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(link db.Url) {
+				defer func() { <-sem; wg.Done() }()
+				w.refreshLink(ctx, link)
+			}(link)
+		}
+		wg.Wait()
+
+		if len(links) < int(w.batch) {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// refreshLink replaces the link's thumbnail. When no new one can be made
+// it keeps a thumbnail of ours, but drops external ones from before
+// thumbnails were self-hosted.
+func (w *Worker) refreshLink(ctx context.Context, link db.Url) {
+	current := link.Thumbnail.String
+	next := ""
+	if Owned(current) {
+		next = current
+	}
+	key, err := w.service.Generate(ctx, link.OriginalUrl)
+	if ctx.Err() != nil {
+		return
+	}
+	if err == nil {
+		next = key
+	}
+
+	if err := w.store.SetURLThumbnail(ctx, db.SetURLThumbnailParams{
+		ID:        link.ID,
+		Thumbnail: sql.NullString{String: next, Valid: next != ""},
+	}); err != nil {
+		log.Printf("thumbnail: updating %s: %v", link.ShortCode, err)
+		_ = w.service.Remove(ctx, key)
+		return
+	}
+	if next != current {
+		if err := w.service.Remove(ctx, current); err != nil {
+			log.Printf("thumbnail: removing %s: %v", current, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+
+	// MaxAnalyticsBuckets caps the length of a gap-filled series.
+	MaxAnalyticsBuckets = 1000
+)
+
+// AnalyticsRange is the window shared by every /analytics route: a half-open
+// [From, To) interval, the series granularity and the caller's time zone.
+type AnalyticsRange struct {
+	From        time.Time
+	To          time.Time
+	Granularity string
+	Location    *time.Location
+}
+
+// ParseAnalyticsRange reads from, to, granularity and tz from the query
+// string. Plain dates are interpreted in tz and "to" is inclusive of that day.
+// Without from/to the range ends with the current bucket and spans
+// defaultBuckets buckets; defaultBuckets <= 0 means all time. The legacy days
+// parameter is still honoured when from is absent.
+func ParseAnalyticsRange(ctx *gin.Context, defaultGranularity string, defaultBuckets int) (AnalyticsRange, error) {
+	r := AnalyticsRange{Granularity: ctx.DefaultQuery("granularity", defaultGranularity)}
+	if !validGranularity(r.Granularity) {
+		return r, errors.New("granularity must be one of hour, day, week or month")
+	}
+
+	loc, err := time.LoadLocation(ctx.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		return r, errors.New("invalid tz parameter")
+	}
+	r.Location = loc
+
+	if to := ctx.Query("to"); to != "" {
+		t, dateOnly, err := parseRangeValue(to, loc)
+		if err != nil {
+			return r, errors.New("invalid to parameter")
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		r.To = t
+	} else {
+		r.To = r.next(r.truncate(time.Now().In(loc)))
+	}
+
+	if from := ctx.Query("from"); from != "" {
+		t, _, err := parseRangeValue(from, loc)
+		if err != nil {
+			return r, errors.New("invalid from parameter")
+		}
+		r.From = t
+	} else if days := ctx.Query("days"); days != "" {
+		r.From = r.To.AddDate(0, 0, -ParseDaysParam(days))
+	} else if defaultBuckets > 0 {
+		r.From = r.To
+		for i := 0; i < defaultBuckets; i++ {
+			r.From = r.prev(r.From)
+		}
+	} else {
+		r.From = time.Unix(0, 0).In(loc)
+	}
+
+	if !r.From.Before(r.To) {
+		return r, errors.New("from must be before to")
+	}
+	return r, nil
+}
+
+// TZ returns the IANA name passed to Postgres for AT TIME ZONE.
+func (r AnalyticsRange) TZ() string {
+	return r.Location.String()
+}
+
+// Buckets lists the start of every bucket in the range, in r.Location.
+func (r AnalyticsRange) Buckets() ([]time.Time, error) {
+	var buckets []time.Time
+	for t := r.truncate(r.From.In(r.Location)); t.Before(r.To); t = r.next(t) {
+		if len(buckets) == MaxAnalyticsBuckets {
+			return nil, errors.New("range too large for granularity, max " + strconv.Itoa(MaxAnalyticsBuckets) + " buckets")
+		}
+		buckets = append(buckets, t)
+	}
+	return buckets, nil
+}
+
+// BucketKey identifies a bucket by its wall-clock start. Postgres returns
+// buckets as timestamps without time zone, so both sides compare wall time.
+func BucketKey(t time.Time) string {
+	return t.Format("2006-01-02 15:04")
+}
+
+func (r AnalyticsRange) truncate(t time.Time) time.Time {
+	switch r.Granularity {
+	case GranularityHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case GranularityWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+func (r AnalyticsRange) next(t time.Time) time.Time {
+	switch r.Granularity {
+	case GranularityHour:
+		return t.Add(time.Hour)
+	case GranularityWeek:
+		return t.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+func (r AnalyticsRange) prev(t time.Time) time.Time {
+	switch r.Granularity {
+	case GranularityHour:
+		return t.Add(-time.Hour)
+	case GranularityWeek:
+		return t.AddDate(0, 0, -7)
+	case GranularityMonth:
+		return t.AddDate(0, -1, 0)
+	default:
+		return t.AddDate(0, 0, -1)
+	}
+}
+
+func validGranularity(g string) bool {
+	switch g {
+	case GranularityHour, GranularityDay, GranularityWeek, GranularityMonth:
+		return true
+	}
+	return false
+}
+
+func parseRangeValue(value string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"api/internal/rollup"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rollups are only exact for ranges made of whole rollup buckets, which
+// every local bucket boundary must be, also in zones off UTC by a fraction
+// of an hour.
+func TestAnalyticsRangeAlignsToRollupBuckets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	zones := []string{"UTC", "Asia/Kolkata", "Asia/Kathmandu", "America/St_Johns", "Australia/Eucla"}
+	queries := []string{
+		"",
+		"&from=2024-03-01&to=2024-03-31",
+		"&from=2024-10-20&to=2024-11-10",
+	}
+	for _, zone := range zones {
+		for _, granularity := range []string{GranularityHour, GranularityDay, GranularityWeek, GranularityMonth} {
+			for _, query := range queries {
+				ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+				ctx.Request = httptest.NewRequest("GET", "/?tz="+zone+"&granularity="+granularity+query, nil)
+
+				rng, err := ParseAnalyticsRange(ctx, GranularityDay, 30)
+				if err != nil {
+					t.Fatalf("%s %s %q: %v", zone, granularity, query, err)
+				}
+				buckets, err := rng.Buckets()
+				if err != nil {
+					t.Fatalf("%s %s %q: %v", zone, granularity, query, err)
+				}
+				for _, b := range append(buckets, rng.From, rng.To) {
+					if !b.Truncate(rollup.BucketSize).Equal(b) {
+						t.Errorf("%s %s %q: boundary %s is not a multiple of %s", zone, granularity, query, b, rollup.BucketSize)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAnalyticsRangeLocalDays(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest("GET", "/?tz=Asia/Kolkata&from=2024-03-01&to=2024-03-01", nil)
+
+	rng, err := ParseAnalyticsRange(ctx, GranularityDay, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rng.From.UTC().Format("2006-01-02 15:04"), "2024-02-29 18:30"; got != want {
+		t.Errorf("From = %s UTC, want %s", got, want)
+	}
+	if got, want := rng.To.UTC().Format("2006-01-02 15:04"), "2024-03-01 18:30"; got != want {
+		t.Errorf("To = %s UTC, want %s", got, want)
+	}
+	buckets, err := rng.Buckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 1 || BucketKey(buckets[0]) != "2024-03-01 00:00" {
+		t.Errorf("Buckets = %v, want just 2024-03-01", buckets)
+	}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"api/configs"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// EmailVerificationTTL is how long a verification link works.
+const EmailVerificationTTL = 24 * time.Hour
+
+// emailVerificationAudience keeps verification tokens and session tokens,
+// which share a signing key, from standing in for one another.
+const emailVerificationAudience = "verify-email"
+
+var (
+	ErrEmailTokenExpired = errors.New("verification link has expired")
+	ErrEmailTokenInvalid = errors.New("invalid verification link")
+)
+
+type emailClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// GenerateEmailVerificationToken signs a token that proves access to email
+// for the user, valid for EmailVerificationTTL.
+func GenerateEmailVerificationToken(userID int32, email string) (string, error) {
+	now := time.Now()
+	claims := &emailClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "url-shortener",
+			Subject:   strconv.Itoa(int(userID)),
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(EmailVerificationTTL)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(configs.GetJWTSecret()))
+}
+
+// ValidateEmailVerificationToken returns the user and email a token was
+// issued for.
+func ValidateEmailVerificationToken(tokenString string) (int32, string, error) {
+	claims := &emailClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(configs.GetJWTSecret()), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(emailVerificationAudience), jwt.WithExpirationRequired())
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return 0, "", ErrEmailTokenExpired
+	}
+	if err != nil {
+		return 0, "", ErrEmailTokenInvalid
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 32)
+	if err != nil || claims.Email == "" {
+		return 0, "", ErrEmailTokenInvalid
+	}
+	return int32(userID), claims.Email, nil
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signEmailClaims(t *testing.T, claims *emailClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestEmailVerificationTokenRoundTrip(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	token, err := GenerateEmailVerificationToken(42, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, email, err := ValidateEmailVerificationToken(token)
+	if err != nil || userID != 42 || email != "ada@example.com" {
+		t.Errorf("ValidateEmailVerificationToken = %d, %q, %v", userID, email, err)
+	}
+
+	t.Setenv("JWT_SECRET", "another-secret")
+	if _, _, err := ValidateEmailVerificationToken(token); !errors.Is(err, ErrEmailTokenInvalid) {
+		t.Errorf("token signed with another key: err = %v, want ErrEmailTokenInvalid", err)
+	}
+}
+
+func TestEmailVerificationTokenExpires(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	issued := time.Now().Add(-EmailVerificationTTL - time.Minute)
+	token := signEmailClaims(t, &emailClaims{
+		Email: "ada@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "42",
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(issued),
+			ExpiresAt: jwt.NewNumericDate(issued.Add(EmailVerificationTTL)),
+		},
+	})
+	if _, _, err := ValidateEmailVerificationToken(token); !errors.Is(err, ErrEmailTokenExpired) {
+		t.Errorf("err = %v, want ErrEmailTokenExpired", err)
+	}
+}
+
+func TestEmailVerificationTokenRejectsOtherTokens(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	expires := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	session, err := GenerateJWT(42, "ada", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"session token": session,
+		"wrong audience": signEmailClaims(t, &emailClaims{
+			Email:            "ada@example.com",
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "42", Audience: jwt.ClaimStrings{"reset-password"}, ExpiresAt: expires},
+		}),
+		"no expiry": signEmailClaims(t, &emailClaims{
+			Email:            "ada@example.com",
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "42", Audience: jwt.ClaimStrings{emailVerificationAudience}},
+		}),
+		"no email": signEmailClaims(t, &emailClaims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "42", Audience: jwt.ClaimStrings{emailVerificationAudience}, ExpiresAt: expires},
+		}),
+		"bad subject": signEmailClaims(t, &emailClaims{
+			Email:            "ada@example.com",
+			RegisteredClaims: jwt.RegisteredClaims{Subject: strconv.Itoa(1 << 40), Audience: jwt.ClaimStrings{emailVerificationAudience}, ExpiresAt: expires},
+		}),
+		"garbage": "not.a.token",
+	}
+	for name, token := range tests {
+		if _, _, err := ValidateEmailVerificationToken(token); !errors.Is(err, ErrEmailTokenInvalid) {
+			t.Errorf("%s: err = %v, want ErrEmailTokenInvalid", name, err)
+		}
+	}
+}
+
+func TestValidateTokenRejectsVerificationToken(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	session, err := GenerateJWT(42, "ada", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ValidateToken(session)
+	if err != nil || claims.UserID != 42 || claims.Username != "ada" || claims.SessionVersion != 3 {
+		t.Fatalf("ValidateToken(session) = %+v, %v", claims, err)
+	}
+
+	verification, err := GenerateEmailVerificationToken(42, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := ValidateToken(verification); err == nil {
+		t.Errorf("ValidateToken accepted a verification token as a session: %+v", claims)
+	}
+}
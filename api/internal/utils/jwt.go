@@ -2,22 +2,30 @@ package utils
 
 import (
 	"api/configs"
+	"api/internal/db"
+	"context"
+	"database/sql"
 	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var ErrSessionRevoked = errors.New("session has been signed out")
+
 type Claims struct {
 	UserID   int64  `json:"user_id"`
 	Username string `json:"username"`
+	// SessionVersion is users.session_version when the token was issued.
+	SessionVersion int32 `json:"session_version"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID int64, username string) (string, error) {
+func GenerateJWT(userID int64, username string, sessionVersion int32) (string, error) {
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:         userID,
+		Username:       username,
+		SessionVersion: sessionVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "url-shortener",
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -42,8 +50,20 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	if err != nil {
 		return nil, err
 	}
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Session tokens have no audience; others, such as email verification
+	// tokens, are not sessions.
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 	return &Claims{}, errors.New("invalid token")
 }
+
+// CheckSession reports ErrSessionRevoked if the session of claims was signed
+// out after it was issued, as happens when the password changes.
+func CheckSession(ctx context.Context, store *db.Queries, claims *Claims) error {
+	version, err := store.GetUserSessionVersion(ctx, int32(claims.UserID))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && version != claims.SessionVersion) {
+		return ErrSessionRevoked
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"api/internal/db"
+	"api/internal/dbtest"
+)
+
+func TestCheckSession(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	_, store := dbtest.Open(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, db.CreateUserParams{Username: "session", Email: "session@example.com", PasswordHash: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := func(version int32) *Claims {
+		t.Helper()
+		token, err := GenerateJWT(int64(user.ID), user.Username, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := ValidateToken(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return claims
+	}
+
+	old := session(user.SessionVersion)
+	if err := CheckSession(ctx, store, old); err != nil {
+		t.Fatalf("CheckSession(current session) = %v", err)
+	}
+
+	// Changing the password bumps session_version, which signs out the
+	// sessions issued before.
+	updated, err := store.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{ID: user.ID, PasswordHash: "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.SessionVersion != user.SessionVersion+1 {
+		t.Fatalf("session_version = %d, want %d", updated.SessionVersion, user.SessionVersion+1)
+	}
+	if err := CheckSession(ctx, store, old); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("CheckSession(old session) = %v, want ErrSessionRevoked", err)
+	}
+	if err := CheckSession(ctx, store, session(updated.SessionVersion)); err != nil {
+		t.Errorf("CheckSession(new session) = %v", err)
+	}
+
+	gone := session(0)
+	gone.UserID = int64(user.ID) + 1000
+	if err := CheckSession(ctx, store, gone); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("CheckSession(missing user) = %v, want ErrSessionRevoked", err)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"image"
@@ -11,26 +12,19 @@ import (
 	"image/png"
 	"math"
 	"net/http"
-	"net/url"
 	"strings"
+	"time"
+
+	"api/internal/safehttp"
 
 	"github.com/disintegration/imaging"
 	"github.com/skip2/go-qrcode"
 )
 
-func GenerateQRCode(url string, size int) ([]byte, error) {
-	print(url)
-	qr, err := qrcode.New(url, qrcode.Highest)
-	if err != nil {
-		return nil, err
-	}
-	qr.DisableBorder = false
-	qrImg := qr.Image(size)
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, qrImg); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+// GenerateQRCode renders a black on white code in format: png, jpeg, svg
+// or pdf.
+func GenerateQRCode(url string, size int, format string) ([]byte, error) {
+	return GenerateQRCodeWithLogos(url, "", size, format, color.Black, color.White)
 }
 
 func ParseHexColor(s string) (color.Color, error) {
@@ -54,48 +48,129 @@ func sscanf(hex string, format string, r, g, b *uint8) (int, error) {
 	return parsed, nil
 }
 
-// #TODO: Implement GenerateQRCodeWithLogos function
+// QROptions describe how a code is rendered.
+type QROptions struct {
+	Size       int
+	Format     string
+	Foreground color.Color
+	Background color.Color
+	LogoURL    string
+	// Logo is a PNG, JPEG or SVG file, such as an uploaded logo. It takes
+	// precedence over LogoURL.
+	Logo            []byte
+	ErrorCorrection qrcode.RecoveryLevel
+	// QuietZone is the blank margin in modules; see DefaultQuietZone.
+	QuietZone int
+	Style     QRStyle
+}
+
+var errorCorrectionLevels = map[string]qrcode.RecoveryLevel{
+	"low":     qrcode.Low,
+	"medium":  qrcode.Medium,
+	"high":    qrcode.High,
+	"highest": qrcode.Highest,
+}
+
+// ParseErrorCorrection maps low, medium, high or highest to a recovery
+// level. An empty level means highest, which leaves room for a logo.
+func ParseErrorCorrection(level string) (qrcode.RecoveryLevel, error) {
+	if level == "" {
+		return qrcode.Highest, nil
+	}
+	recovery, ok := errorCorrectionLevels[strings.ToLower(level)]
+	if !ok {
+		return 0, fmt.Errorf("unknown error correction level: %s", level)
+	}
+	return recovery, nil
+}
 
 func GenerateQRCodeWithLogos(url string, logoPath string, size int, format string, fg, bg color.Color) ([]byte, error) {
-	qr, err := qrcode.New(url, qrcode.Highest)
+	return RenderQRCode(url, QROptions{
+		Size:            size,
+		Format:          format,
+		Foreground:      fg,
+		Background:      bg,
+		LogoURL:         logoPath,
+		ErrorCorrection: qrcode.Highest,
+		QuietZone:       DefaultQuietZone,
+	})
+}
+
+// RenderQRCode encodes content as a QR code in opts.Format: png, jpeg, svg
+// or pdf.
+func RenderQRCode(content string, opts QROptions) ([]byte, error) {
+	data, _, err := renderQRCode(content, opts, false)
+	return data, err
+}
+
+// RenderCheckedQRCode renders like RenderQRCode and then reads the code
+// back from its pixels, reporting whether and how well it scans. SVG and
+// PDF codes are checked as drawn at opts.Size pixels.
+func RenderCheckedQRCode(content string, opts QROptions) ([]byte, QRScanReport, error) {
+	return renderQRCode(content, opts, true)
+}
+
+func renderQRCode(content string, opts QROptions, check bool) ([]byte, QRScanReport, error) {
+	qr, err := qrcode.New(content, opts.ErrorCorrection)
 	if err != nil {
-		return nil, err
+		return nil, QRScanReport{}, err
 	}
 
-	qr.ForegroundColor = fg
-	qr.BackgroundColor = bg
+	if err := opts.Style.Validate(); err != nil {
+		return nil, QRScanReport{}, err
+	}
+	format := strings.ToLower(opts.Format)
+	if opts.Style.Transparent && (format == "jpeg" || format == "jpg") {
+		return nil, QRScanReport{}, ErrTransparentJPEG
+	}
 
-	qr.DisableBorder = false
-	qrImg := qr.Image(size)
-	rgbaQR := image.NewRGBA(qrImg.Bounds())
+	// The layout adds the quiet zone around the bare bitmap.
+	qr.DisableBorder = true
+	layout := layoutQR(qr.Bitmap(), opts.Size, opts)
 
-	white := image.NewUniform(image.White)
-	draw.Draw(rgbaQR, qrImg.Bounds(), white, image.Point{}, draw.Src)
-	draw.Draw(rgbaQR, qrImg.Bounds(), qrImg, image.Point{}, draw.Over)
+	vector := format == "svg" || format == "pdf"
+	logoSize := int(layout.codeSize / 4)
+	if vector {
+		logoSize = max(logoSize, vectorLogoSize)
+	}
+	logoImg, err := loadLogo(opts, logoSize)
+	if err != nil {
+		return nil, QRScanReport{}, err
+	}
 
-	if logoPath != "" {
-		logoSize := size / 4
-		logoImg, err := downloadAndResizeImage(logoPath, logoSize)
-		if err != nil {
-			fmt.Println("Logo error:", err)
-			return nil, err
+	var data []byte
+	var raster *image.RGBA
+	if vector {
+		data, err = generateVectorQRCode(layout, logoImg, format)
+	} else {
+		if raster, err = renderQRRaster(layout, logoImg); err == nil {
+			data, err = encodeQRImage(raster, format)
 		}
-		// Center the logo
-		offset := image.Pt(
-			(size-logoImg.Bounds().Dx())/2,
-			(size-logoImg.Bounds().Dy())/2,
-		)
-		draw.Draw(rgbaQR, logoImg.Bounds().Add(offset), logoImg, image.Point{}, draw.Over)
+	}
+	if err != nil || !check {
+		return data, QRScanReport{}, err
 	}
 
+	if raster == nil {
+		if logoImg != nil {
+			logoImg = imaging.Resize(logoImg, int(layout.codeSize/4), 0, imaging.Lanczos)
+		}
+		if raster, err = renderQRRaster(layout, logoImg); err != nil {
+			return nil, QRScanReport{}, err
+		}
+	}
+	return data, checkQRCode(content, raster, layout, opts), nil
+}
+
+func encodeQRImage(img image.Image, format string) ([]byte, error) {
 	var buf bytes.Buffer
-	switch strings.ToLower(format) {
+	switch format {
 	case "jpeg", "jpg":
-		if err := jpeg.Encode(&buf, rgbaQR, &jpeg.Options{Quality: 90}); err != nil {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
 			return nil, err
 		}
 	case "png", "":
-		if err := png.Encode(&buf, rgbaQR); err != nil {
+		if err := png.Encode(&buf, img); err != nil {
 			return nil, err
 		}
 
@@ -105,29 +180,67 @@ func GenerateQRCodeWithLogos(url string, logoPath string, size int, format strin
 	return buf.Bytes(), nil
 }
 
-func downloadAndResizeImage(logoURL string, size int) (image.Image, error) {
-	parsed, err := url.Parse(logoURL)
-	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+// maxLogoBytes caps the size of a downloaded logo.
+const maxLogoBytes = 5 << 20
+
+var logoClient = safehttp.NewClient(safehttp.Options{Timeout: 10 * time.Second})
+
+// DownloadLogo fetches the image file at logoURL, refusing raster images
+// over 4096 pixels on a side.
+func DownloadLogo(logoURL string) ([]byte, error) {
+	req, err := safehttp.NewRequest(context.Background(), http.MethodGet, logoURL, nil)
+	if err != nil {
 		return nil, fmt.Errorf("invalid logo URL: %w", err)
 	}
-	if parsed.Scheme != "http" && parsed.Scheme != "https" {
-		return nil, fmt.Errorf("unsupported URL scheme: %s", parsed.Scheme)
-	}
 
-	resp, err := http.Get(logoURL)
+	resp, err := logoClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download logo: %w", err)
 	}
 	defer resp.Body.Close()
-
-	logoImg, _, err := image.Decode(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download logo: unexpected status %s", resp.Status)
+	}
+	if err := safehttp.CheckContentType(resp, "image/"); err != nil {
+		return nil, fmt.Errorf("failed to download logo: %w", err)
+	}
+	data, err := safehttp.ReadBody(resp, maxLogoBytes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+		return nil, fmt.Errorf("failed to download logo: %w", err)
+	}
+	if err := checkLogoPixels(data); err != nil {
+		return nil, fmt.Errorf("invalid logo: %w", err)
 	}
+	return data, nil
+}
+
+// loadLogo returns the logo of opts at size pixels square, or nil when
+// there is none.
+func loadLogo(opts QROptions, size int) (image.Image, error) {
+	if opts.Logo != nil {
+		logoImg, err := DecodeLogo(opts.Logo, size)
+		if err != nil {
+			return nil, err
+		}
+		return resizeLogo(logoImg, size), nil
+	}
+	if opts.LogoURL != "" {
+		data, err := DownloadLogo(opts.LogoURL)
+		if err != nil {
+			return nil, err
+		}
+		logoImg, err := DecodeLogo(data, size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+		return resizeLogo(logoImg, size), nil
+	}
+	return nil, nil
+}
 
+func resizeLogo(logoImg image.Image, size int) image.Image {
 	logoImg = imaging.Resize(logoImg, size, size, imaging.Lanczos)
-	logoImg = makeRounded(logoImg, float64(size)/6)
-	return logoImg, nil
+	return makeRounded(logoImg, float64(size)/6)
 }
 
 func makeRounded(img image.Image, radius float64) image.Image {
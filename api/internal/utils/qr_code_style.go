@@ -0,0 +1,397 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultQuietZone is the blank margin around a code, in modules, that
+// the standard asks for so scanners can find it.
+const DefaultQuietZone = 4
+
+// ErrTransparentJPEG is returned for a transparent background in JPEG,
+// which has no alpha channel.
+var ErrTransparentJPEG = errors.New("a transparent background needs png, svg or pdf")
+
+// QRStyle decorates a code beyond plain squares. The zero value is the
+// classic look.
+type QRStyle struct {
+	// Modules is the shape of the data modules: square, rounded or dot.
+	Modules string
+	// Eyes is the shape of the three finder patterns: square, rounded or
+	// circle.
+	Eyes string
+	// EyeColor colours the finder patterns; nil means the foreground.
+	EyeColor color.Color
+	// Gradient fills the data modules instead of the foreground.
+	Gradient    *QRGradient
+	Transparent bool
+	// FrameText puts the code in a frame with this call to action under it.
+	FrameText string
+	// FrameColor colours the frame; nil means the foreground. The text is
+	// drawn in the background colour.
+	FrameColor color.Color
+}
+
+// QRGradient blends From into To across the code.
+type QRGradient struct {
+	// Type is linear or radial. Radial gradients run from the centre out.
+	Type     string
+	From, To color.Color
+	// Angle is the direction of a linear gradient in degrees, clockwise
+	// from left to right.
+	Angle float64
+}
+
+var (
+	moduleShapes = map[string]bool{"": true, "square": true, "rounded": true, "dot": true}
+	eyeShapes    = map[string]bool{"": true, "square": true, "rounded": true, "circle": true}
+)
+
+// Validate reports unknown shapes and gradients.
+func (s QRStyle) Validate() error {
+	if !moduleShapes[s.Modules] {
+		return fmt.Errorf("unknown module shape: %s", s.Modules)
+	}
+	if !eyeShapes[s.Eyes] {
+		return fmt.Errorf("unknown eye shape: %s", s.Eyes)
+	}
+	if g := s.Gradient; g != nil {
+		if g.Type != "linear" && g.Type != "radial" {
+			return fmt.Errorf("unknown gradient: %s", g.Type)
+		}
+		if g.From == nil || g.To == nil {
+			return errors.New("a gradient needs two colours")
+		}
+	}
+	return nil
+}
+
+// plain reports whether s draws nothing but square modules, which the
+// go-qrcode image already does pixel exact.
+func (s QRStyle) plain() bool {
+	return (s.Modules == "" || s.Modules == "square") && (s.Eyes == "" || s.Eyes == "square") &&
+		s.EyeColor == nil && s.Gradient == nil && !s.Transparent && s.FrameText == ""
+}
+
+// qrMark is one shape of a code. Circles fill their box.
+type qrMark struct {
+	shape      string // rect, round or circle
+	x, y, w, h float64
+	radius     float64 // corner radius of round
+	stroke     float64 // when set, only the outline is drawn this wide
+}
+
+// qrFrame is the panel around a code with the call to action under it.
+type qrFrame struct {
+	text                string
+	color               color.Color
+	radius, innerRadius float64
+	textX, textY        float64 // centre of the text
+	fontSize            float64
+}
+
+// qrLayout is a code laid out in pixels, shared by every output format.
+type qrLayout struct {
+	width, height          float64
+	codeX, codeY, codeSize float64
+	module                 float64 // module width in pixels
+	modules, eyes          []qrMark
+	foreground, eyeColor   color.Color
+	background             color.Color
+	gradient               *QRGradient
+	transparent, crisp     bool
+	frame                  *qrFrame
+}
+
+// layoutQR places the modules of bitmap, which has no quiet zone, in a
+// size pixel square, and the frame under it when there is one. Modules
+// are whole pixels wide where they fit, so square modules stay crisp.
+func layoutQR(bitmap [][]bool, size int, opts QROptions) *qrLayout {
+	style := opts.Style
+	l := &qrLayout{
+		width:       float64(size),
+		height:      float64(size),
+		codeSize:    float64(size),
+		foreground:  opts.Foreground,
+		eyeColor:    opts.Foreground,
+		background:  opts.Background,
+		gradient:    style.Gradient,
+		transparent: style.Transparent,
+		crisp:       style.plain(),
+	}
+	if style.EyeColor != nil {
+		l.eyeColor = style.EyeColor
+	}
+
+	if style.FrameText != "" {
+		inset := math.Round(float64(size) * 0.05)
+		band := math.Round(float64(size) * 0.18)
+		frameColor := style.FrameColor
+		if frameColor == nil {
+			frameColor = opts.Foreground
+		}
+		l.height += band
+		l.codeX, l.codeY = inset, inset
+		l.codeSize -= 2 * inset
+		l.frame = &qrFrame{
+			text:        style.FrameText,
+			color:       frameColor,
+			radius:      inset * 1.5,
+			innerRadius: inset,
+			textX:       l.width / 2,
+			textY:       (l.width - inset + l.height) / 2,
+			fontSize:    frameFontSize(style.FrameText, band*0.5, l.width-4*inset),
+		}
+	}
+
+	n := len(bitmap)
+	quiet := float64(max(opts.QuietZone, 0))
+	m := l.codeSize / (float64(n) + 2*quiet)
+	if m >= 1 {
+		m = math.Floor(m)
+	}
+	l.module = m
+	// Whatever whole modules leave over is shared by both margins.
+	margin := math.Floor((l.codeSize - m*float64(n)) / 2)
+	originX := l.codeX + margin
+	originY := l.codeY + margin
+
+	eyes := [][2]int{{0, 0}, {n - 7, 0}, {0, n - 7}}
+	inEye := func(x, y int) bool {
+		for _, e := range eyes {
+			if x >= e[0] && x < e[0]+7 && y >= e[1] && y < e[1]+7 {
+				return true
+			}
+		}
+		return false
+	}
+
+	if style.Modules == "" || style.Modules == "square" {
+		masked := make([][]bool, n)
+		for y, row := range bitmap {
+			masked[y] = make([]bool, len(row))
+			for x, dark := range row {
+				masked[y][x] = dark && !inEye(x, y)
+			}
+		}
+		moduleRuns(masked, func(x, y, width int) {
+			l.modules = append(l.modules, qrMark{shape: "rect",
+				x: originX + float64(x)*m, y: originY + float64(y)*m, w: float64(width) * m, h: m})
+		})
+	} else {
+		for y, row := range bitmap {
+			for x, dark := range row {
+				if !dark || inEye(x, y) {
+					continue
+				}
+				mark := qrMark{x: originX + float64(x)*m, y: originY + float64(y)*m, w: m, h: m}
+				if style.Modules == "dot" {
+					mark.shape = "circle"
+					inset := m * 0.05
+					mark.x, mark.y, mark.w, mark.h = mark.x+inset, mark.y+inset, m-2*inset, m-2*inset
+				} else {
+					mark.shape, mark.radius = "round", m*0.35
+				}
+				l.modules = append(l.modules, mark)
+			}
+		}
+	}
+
+	for _, e := range eyes {
+		x, y := originX+float64(e[0])*m, originY+float64(e[1])*m
+		// The ring is stroked along its centre line, one module wide.
+		ring := qrMark{shape: "rect", x: x + m/2, y: y + m/2, w: 6 * m, h: 6 * m, stroke: m}
+		ball := qrMark{shape: "rect", x: x + 2*m, y: y + 2*m, w: 3 * m, h: 3 * m}
+		switch style.Eyes {
+		case "rounded":
+			ring.shape, ring.radius = "round", 1.5*m
+			ball.shape, ball.radius = "round", 0.75*m
+		case "circle":
+			ring.shape, ball.shape = "circle", "circle"
+		}
+		l.eyes = append(l.eyes, ring, ball)
+	}
+	return l
+}
+
+// logoBox is where a logo sits: a quarter of the code, in its centre.
+func (l *qrLayout) logoBox() (x, y, size float64) {
+	size = l.codeSize / 4
+	return l.codeX + (l.codeSize-size)/2, l.codeY + (l.codeSize-size)/2, size
+}
+
+// radialRadius makes a radial gradient reach To at the code's corners.
+const radialRadius = math.Sqrt2 / 2
+
+// gradientVector returns the ends of a linear gradient as fractions of the
+// code area, y pointing down.
+func (l *qrLayout) gradientVector() (x1, y1, x2, y2 float64) {
+	angle := l.gradient.Angle * math.Pi / 180
+	dx, dy := math.Cos(angle)/2, math.Sin(angle)/2
+	return 0.5 - dx, 0.5 - dy, 0.5 + dx, 0.5 + dy
+}
+
+var (
+	frameFontOnce sync.Once
+	frameFont     *opentype.Font
+	frameFontErr  error
+)
+
+func loadFrameFont() (*opentype.Font, error) {
+	frameFontOnce.Do(func() {
+		frameFont, frameFontErr = opentype.Parse(gobold.TTF)
+	})
+	return frameFont, frameFontErr
+}
+
+// frameFontSize shrinks size until text fits in width.
+func frameFontSize(text string, size, width float64) float64 {
+	f, err := loadFrameFont()
+	if err != nil {
+		return size
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: 100, DPI: 72})
+	if err != nil {
+		return size
+	}
+	defer face.Close()
+	advance := float64(font.MeasureString(face, text)) / 64 / 100
+	if advance*size > width {
+		return width / advance
+	}
+	return size
+}
+
+// renderQRRaster draws the layout with the logo and returns the image,
+// transparent wherever nothing is drawn.
+func renderQRRaster(l *qrLayout, logo image.Image) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, int(l.width), int(l.height)))
+	solid := func(c color.Color) func(x, y float64) color.RGBA {
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		return func(x, y float64) color.RGBA { return rgba }
+	}
+
+	if !l.transparent {
+		fillMarks(img, []qrMark{{shape: "rect", w: l.width, h: l.height}}, solid(l.background))
+	}
+	if f := l.frame; f != nil {
+		fillMarks(img, []qrMark{{shape: "round", w: l.width, h: l.height, radius: f.radius}}, solid(f.color))
+		fillMarks(img, []qrMark{{shape: "round", x: l.codeX, y: l.codeY, w: l.codeSize, h: l.codeSize, radius: f.innerRadius}}, solid(l.background))
+		if err := drawFrameText(img, l); err != nil {
+			return nil, err
+		}
+	}
+
+	if g := l.gradient; g != nil {
+		from := color.RGBAModel.Convert(g.From).(color.RGBA)
+		to := color.RGBAModel.Convert(g.To).(color.RGBA)
+		x1, y1, x2, y2 := l.gradientVector()
+		fillMarks(img, l.modules, func(x, y float64) color.RGBA {
+			fx, fy := (x-l.codeX)/l.codeSize, (y-l.codeY)/l.codeSize
+			var t float64
+			if g.Type == "radial" {
+				t = math.Hypot(fx-0.5, fy-0.5) / radialRadius
+			} else {
+				dx, dy := x2-x1, y2-y1
+				t = ((fx-x1)*dx + (fy-y1)*dy) / (dx*dx + dy*dy)
+			}
+			return mix(from, to, math.Min(math.Max(t, 0), 1))
+		})
+	} else {
+		fillMarks(img, l.modules, solid(l.foreground))
+	}
+	fillMarks(img, l.eyes, solid(l.eyeColor))
+
+	if logo != nil {
+		x, y, size := l.logoBox()
+		offset := image.Pt(int(x+(size-float64(logo.Bounds().Dx()))/2), int(y+(size-float64(logo.Bounds().Dy()))/2))
+		draw.Draw(img, logo.Bounds().Add(offset), logo, logo.Bounds().Min, draw.Over)
+	}
+	return img, nil
+}
+
+// fillMarks paints marks onto img with paint, anti-aliased. Coverage is
+// summed over all the marks before painting, so modules that touch leave
+// no seam between them.
+func fillMarks(img *image.RGBA, marks []qrMark, paint func(x, y float64) color.RGBA) {
+	bounds := img.Bounds()
+	coverage := make([]float64, bounds.Dx()*bounds.Dy())
+	for _, mark := range marks {
+		pad := mark.stroke/2 + 1
+		x0, y0 := max(int(mark.x-pad), 0), max(int(mark.y-pad), 0)
+		x1, y1 := min(int(mark.x+mark.w+pad)+1, bounds.Dx()), min(int(mark.y+mark.h+pad)+1, bounds.Dy())
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				i := y*bounds.Dx() + x
+				coverage[i] = math.Min(coverage[i]+mark.coverage(float64(x)+0.5, float64(y)+0.5), 1)
+			}
+		}
+	}
+
+	for i, a := range coverage {
+		if a == 0 {
+			continue
+		}
+		x, y := i%bounds.Dx(), i/bounds.Dx()
+		src := paint(float64(x)+0.5, float64(y)+0.5)
+		dst := img.RGBAAt(x, y)
+		// Both colours are alpha premultiplied.
+		keep := 1 - a*float64(src.A)/255
+		blend := func(s, d uint8) uint8 { return uint8(math.Round(float64(s)*a + float64(d)*keep)) }
+		img.SetRGBA(x, y, color.RGBA{blend(src.R, dst.R), blend(src.G, dst.G), blend(src.B, dst.B), blend(src.A, dst.A)})
+	}
+}
+
+// coverage estimates how much of the pixel centred on (px, py) the mark
+// covers, from its signed distance to the pixel centre.
+func (m qrMark) coverage(px, py float64) float64 {
+	radius := m.radius
+	if m.shape == "circle" {
+		radius = m.w / 2
+	}
+	qx := math.Abs(px-(m.x+m.w/2)) - (m.w/2 - radius)
+	qy := math.Abs(py-(m.y+m.h/2)) - (m.h/2 - radius)
+	d := math.Hypot(math.Max(qx, 0), math.Max(qy, 0)) + math.Min(math.Max(qx, qy), 0) - radius
+	if m.stroke > 0 {
+		d = math.Abs(d) - m.stroke/2
+	}
+	return math.Min(math.Max(0.5-d, 0), 1)
+}
+
+func mix(from, to color.RGBA, t float64) color.RGBA {
+	lerp := func(a, b uint8) uint8 { return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t)) }
+	return color.RGBA{lerp(from.R, to.R), lerp(from.G, to.G), lerp(from.B, to.B), lerp(from.A, to.A)}
+}
+
+func drawFrameText(img *image.RGBA, l *qrLayout) error {
+	f, err := loadFrameFont()
+	if err != nil {
+		return err
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: l.frame.fontSize, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	drawer := font.Drawer{Dst: img, Src: image.NewUniform(l.background), Face: face}
+	width := drawer.MeasureString(l.frame.text)
+	drawer.Dot = fixed.Point26_6{
+		X: fixed.Int26_6(l.frame.textX*64) - width/2,
+		Y: fixed.Int26_6(l.frame.textY*64) + (metrics.Ascent-metrics.Descent)/2,
+	}
+	drawer.DrawString(l.frame.text)
+	return nil
+}
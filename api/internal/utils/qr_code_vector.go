@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// vectorLogoSize is the minimum pixel size of a logo embedded in SVG and
+// PDF output, which is meant for print and scales beyond the requested size.
+const vectorLogoSize = 256
+
+var qrFileTypes = map[string][2]string{
+	"png":  {"image/png", "png"},
+	"jpeg": {"image/jpeg", "jpg"},
+	"jpg":  {"image/jpeg", "jpg"},
+	"svg":  {"image/svg+xml", "svg"},
+	"pdf":  {"application/pdf", "pdf"},
+}
+
+// QRFileType returns the content type and file extension of a QR code
+// format. An empty format means PNG.
+func QRFileType(format string) (contentType, extension string, err error) {
+	if format == "" {
+		format = "png"
+	}
+	fileType, ok := qrFileTypes[strings.ToLower(format)]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported format: %s. Use png, jpeg, svg or pdf", format)
+	}
+	return fileType[0], fileType[1], nil
+}
+
+// generateVectorQRCode renders the layout as SVG or PDF with the logo
+// embedded as a PNG in the centre.
+func generateVectorQRCode(layout *qrLayout, logo image.Image, format string) ([]byte, error) {
+	switch format {
+	case "svg":
+		return renderQRSVG(layout, logo)
+	case "pdf":
+		return renderQRPDF(layout, logo)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// moduleRuns calls fn for every horizontal run of dark modules.
+func moduleRuns(bitmap [][]bool, fn func(x, y, width int)) {
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fn(start, y, x-start)
+		}
+	}
+}
+
+func renderQRSVG(l *qrLayout, logo image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s"`,
+		num(l.width), num(l.height), num(l.width), num(l.height))
+	if l.crisp {
+		buf.WriteString(` shape-rendering="crispEdges"`)
+	}
+	buf.WriteString(">\n")
+
+	modulesFill := hexColor(l.foreground)
+	if g := l.gradient; g != nil {
+		modulesFill = "url(#modules)"
+		toPx := func(fx, fy float64) (string, string) {
+			return num(l.codeX + fx*l.codeSize), num(l.codeY + fy*l.codeSize)
+		}
+		stops := fmt.Sprintf(`<stop offset="0" stop-color="%s"/><stop offset="1" stop-color="%s"/>`, hexColor(g.From), hexColor(g.To))
+		if g.Type == "radial" {
+			cx, cy := toPx(0.5, 0.5)
+			fmt.Fprintf(&buf, `<defs><radialGradient id="modules" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s">%s</radialGradient></defs>`+"\n",
+				cx, cy, num(radialRadius*l.codeSize), stops)
+		} else {
+			x1, y1, x2, y2 := l.gradientVector()
+			sx, sy := toPx(x1, y1)
+			ex, ey := toPx(x2, y2)
+			fmt.Fprintf(&buf, `<defs><linearGradient id="modules" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s">%s</linearGradient></defs>`+"\n",
+				sx, sy, ex, ey, stops)
+		}
+	}
+
+	if !l.transparent {
+		fmt.Fprintf(&buf, `<rect width="%s" height="%s" fill="%s"/>`+"\n", num(l.width), num(l.height), hexColor(l.background))
+	}
+	if f := l.frame; f != nil {
+		fmt.Fprintf(&buf, `<rect width="%s" height="%s" rx="%s" fill="%s"/>`+"\n",
+			num(l.width), num(l.height), num(f.radius), hexColor(f.color))
+		fmt.Fprintf(&buf, `<rect x="%s" y="%s" width="%s" height="%s" rx="%s" fill="%s"/>`+"\n",
+			num(l.codeX), num(l.codeY), num(l.codeSize), num(l.codeSize), num(f.innerRadius), hexColor(l.background))
+		buf.WriteString(`<text x="` + num(f.textX) + `" y="` + num(f.textY) + `" font-family="Go, Helvetica, Arial, sans-serif" font-weight="bold" font-size="` +
+			num(f.fontSize) + `" text-anchor="middle" dominant-baseline="central" fill="` + hexColor(l.background) + `">`)
+		if err := xml.EscapeText(&buf, []byte(f.text)); err != nil {
+			return nil, err
+		}
+		buf.WriteString("</text>\n")
+	}
+
+	writeSVGMarks(&buf, l.modules, modulesFill)
+	writeSVGMarks(&buf, l.eyes, hexColor(l.eyeColor))
+
+	if logo != nil {
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, logo); err != nil {
+			return nil, err
+		}
+		x, y, size := l.logoBox()
+		fmt.Fprintf(&buf, `<image x="%s" y="%s" width="%s" height="%s" href="data:image/png;base64,%s"/>`+"\n",
+			num(x), num(y), num(size), num(size), base64.StdEncoding.EncodeToString(encoded.Bytes()))
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
+
+// writeSVGMarks writes marks in one group. Filled rectangles share a
+// single path to keep the file small.
+func writeSVGMarks(buf *bytes.Buffer, marks []qrMark, fill string) {
+	fmt.Fprintf(buf, `<g fill="%s">`+"\n", fill)
+	var path strings.Builder
+	for _, mark := range marks {
+		switch {
+		case mark.stroke > 0 && mark.shape == "circle":
+			fmt.Fprintf(buf, `<circle cx="%s" cy="%s" r="%s" fill="none" stroke="%s" stroke-width="%s"/>`+"\n",
+				num(mark.x+mark.w/2), num(mark.y+mark.h/2), num(mark.w/2), fill, num(mark.stroke))
+		case mark.stroke > 0:
+			fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s" rx="%s" fill="none" stroke="%s" stroke-width="%s"/>`+"\n",
+				num(mark.x), num(mark.y), num(mark.w), num(mark.h), num(mark.radius), fill, num(mark.stroke))
+		case mark.shape == "circle":
+			fmt.Fprintf(buf, `<circle cx="%s" cy="%s" r="%s"/>`+"\n", num(mark.x+mark.w/2), num(mark.y+mark.h/2), num(mark.w/2))
+		case mark.shape == "round":
+			fmt.Fprintf(buf, `<rect x="%s" y="%s" width="%s" height="%s" rx="%s"/>`+"\n",
+				num(mark.x), num(mark.y), num(mark.w), num(mark.h), num(mark.radius))
+		default:
+			fmt.Fprintf(&path, "M%s %sh%sv%sh-%sz", num(mark.x), num(mark.y), num(mark.w), num(mark.h), num(mark.w))
+		}
+	}
+	if path.Len() > 0 {
+		fmt.Fprintf(buf, `<path d="%s"/>`+"\n", path.String())
+	}
+	buf.WriteString("</g>\n")
+}
+
+// renderQRPDF draws the layout on a single page, one point per pixel.
+func renderQRPDF(l *qrLayout, logo image.Image) ([]byte, error) {
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: "pt",
+		Size:    gofpdf.SizeType{Wd: l.width, Ht: l.height},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	if !l.transparent {
+		pdf.SetFillColor(rgb(l.background))
+		pdf.Rect(0, 0, l.width, l.height, "F")
+	}
+	if f := l.frame; f != nil {
+		pdf.SetFillColor(rgb(f.color))
+		pdf.RoundedRect(0, 0, l.width, l.height, f.radius, "1234", "F")
+		pdf.SetFillColor(rgb(l.background))
+		pdf.RoundedRect(l.codeX, l.codeY, l.codeSize, l.codeSize, f.innerRadius, "1234", "F")
+
+		// The core fonts only cover Windows-1252.
+		text := pdf.UnicodeTranslatorFromDescriptor("")(f.text)
+		pdf.SetFont("Helvetica", "B", f.fontSize)
+		pdf.SetTextColor(rgb(l.background))
+		pdf.Text(f.textX-pdf.GetStringWidth(text)/2, f.textY+f.fontSize*0.35, text)
+	}
+
+	if g := l.gradient; g != nil {
+		// A gradient can only fill a clipping path, and clipping paths
+		// intersect, so each module gets its own.
+		r1, g1, b1 := rgb(g.From)
+		r2, g2, b2 := rgb(g.To)
+		for _, mark := range l.modules {
+			switch mark.shape {
+			case "circle":
+				pdf.ClipCircle(mark.x+mark.w/2, mark.y+mark.h/2, mark.w/2, false)
+			case "round":
+				pdf.ClipRoundedRect(mark.x, mark.y, mark.w, mark.h, mark.radius, false)
+			default:
+				pdf.ClipRect(mark.x, mark.y, mark.w, mark.h, false)
+			}
+			// PDF gradient vectors have y pointing up.
+			if g.Type == "radial" {
+				pdf.RadialGradient(l.codeX, l.codeY, l.codeSize, l.codeSize, r1, g1, b1, r2, g2, b2, 0.5, 0.5, 0.5, 0.5, radialRadius)
+			} else {
+				x1, y1, x2, y2 := l.gradientVector()
+				pdf.LinearGradient(l.codeX, l.codeY, l.codeSize, l.codeSize, r1, g1, b1, r2, g2, b2, x1, 1-y1, x2, 1-y2)
+			}
+			pdf.ClipEnd()
+		}
+	} else {
+		drawPDFMarks(pdf, l.modules, l.foreground)
+	}
+	drawPDFMarks(pdf, l.eyes, l.eyeColor)
+
+	if logo != nil {
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, logo); err != nil {
+			return nil, err
+		}
+		options := gofpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader("logo", options, &encoded)
+		x, y, size := l.logoBox()
+		pdf.ImageOptions("logo", x, y, size, size, false, options, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawPDFMarks(pdf *gofpdf.Fpdf, marks []qrMark, c color.Color) {
+	pdf.SetFillColor(rgb(c))
+	pdf.SetDrawColor(rgb(c))
+	for _, mark := range marks {
+		style := "F"
+		if mark.stroke > 0 {
+			style = "D"
+			pdf.SetLineWidth(mark.stroke)
+		}
+		switch mark.shape {
+		case "circle":
+			pdf.Circle(mark.x+mark.w/2, mark.y+mark.h/2, mark.w/2, style)
+		case "round":
+			pdf.RoundedRect(mark.x, mark.y, mark.w, mark.h, mark.radius, "1234", style)
+		default:
+			pdf.Rect(mark.x, mark.y, mark.w, mark.h, style)
+		}
+	}
+}
+
+func rgb(c color.Color) (int, int, int) {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return int(rgba.R), int(rgba.G), int(rgba.B)
+}
+
+func hexColor(c color.Color) string {
+	r, g, b := rgb(c)
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// num formats a coordinate with at most two decimals.
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
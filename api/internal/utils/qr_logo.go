@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+const (
+	// MaxLogoUploadBytes caps the size of an uploaded logo file.
+	MaxLogoUploadBytes = 2 << 20
+
+	minLogoDimension = 64
+	maxLogoDimension = 4096
+)
+
+var ErrUnsupportedLogo = errors.New("logo must be a PNG, JPEG or SVG image")
+
+// LogoInfo describes a validated logo file. SVG dimensions come from its
+// viewBox.
+type LogoInfo struct {
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+}
+
+// InspectLogo checks that data is a PNG, JPEG or SVG logo between 64 and
+// 4096 pixels on each side.
+func InspectLogo(data []byte) (LogoInfo, error) {
+	if len(data) > MaxLogoUploadBytes {
+		return LogoInfo{}, fmt.Errorf("logo is larger than %d MB", MaxLogoUploadBytes>>20)
+	}
+
+	var info LogoInfo
+	if isSVG(data) {
+		icon, err := oksvg.ReadIconStream(bytes.NewReader(data), oksvg.IgnoreErrorMode)
+		if err != nil {
+			return LogoInfo{}, fmt.Errorf("invalid SVG: %w", err)
+		}
+		info = LogoInfo{
+			ContentType: "image/svg+xml",
+			Extension:   "svg",
+			Width:       int(icon.ViewBox.W),
+			Height:      int(icon.ViewBox.H),
+		}
+	} else {
+		switch http.DetectContentType(data) {
+		case "image/png":
+			info = LogoInfo{ContentType: "image/png", Extension: "png"}
+		case "image/jpeg":
+			info = LogoInfo{ContentType: "image/jpeg", Extension: "jpg"}
+		default:
+			return LogoInfo{}, ErrUnsupportedLogo
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return LogoInfo{}, ErrUnsupportedLogo
+		}
+		info.Width, info.Height = config.Width, config.Height
+	}
+
+	if info.Width < minLogoDimension || info.Height < minLogoDimension ||
+		info.Width > maxLogoDimension || info.Height > maxLogoDimension {
+		return LogoInfo{}, fmt.Errorf("logo must be between %d and %d pixels on each side, got %dx%d",
+			minLogoDimension, maxLogoDimension, info.Width, info.Height)
+	}
+	return info, nil
+}
+
+// checkLogoPixels rejects raster logos over 4096 pixels on a side from
+// their header, before decoding them allocates the whole image. SVGs are
+// rasterised at the size asked for and need no check.
+func checkLogoPixels(data []byte) error {
+	if isSVG(data) {
+		return nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if config.Width > maxLogoDimension || config.Height > maxLogoDimension {
+		return fmt.Errorf("logo must be at most %d pixels on each side, got %dx%d",
+			maxLogoDimension, config.Width, config.Height)
+	}
+	return nil
+}
+
+// DecodeLogo decodes a logo file. SVGs are rasterised to fit in size
+// pixels square, keeping their aspect ratio.
+func DecodeLogo(data []byte, size int) (image.Image, error) {
+	if !isSVG(data) {
+		if err := checkLogoPixels(data); err != nil {
+			return nil, err
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		return img, err
+	}
+
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(data), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, err
+	}
+	if icon.ViewBox.W <= 0 || icon.ViewBox.H <= 0 {
+		return nil, errors.New("SVG has no size")
+	}
+	scale := float64(size) / max(icon.ViewBox.W, icon.ViewBox.H)
+	w, h := icon.ViewBox.W*scale, icon.ViewBox.H*scale
+	x, y := (float64(size)-w)/2, (float64(size)-h)/2
+	icon.SetTarget(x, y, w, h)
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	scanner := rasterx.NewScannerGV(size, size, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(size, size, scanner), 1)
+	return img, nil
+}
+
+func isSVG(data []byte) bool {
+	head := data[:min(len(data), 1024)]
+	return bytes.Contains(head, []byte("<svg")) &&
+		!bytes.HasPrefix(data, []byte("\x89PNG")) && !bytes.HasPrefix(data, []byte("\xff\xd8"))
+}
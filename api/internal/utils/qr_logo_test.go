@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// A small file can declare a huge image, so the size is checked from the
+// header before decoding.
+func TestDecodeLogoRejectsOversizedImages(t *testing.T) {
+	if _, err := DecodeLogo(encodePNG(t, maxLogoDimension+1, 1), 128); err == nil {
+		t.Error("DecodeLogo accepted a PNG wider than the limit")
+	}
+	if _, err := DecodeLogo(encodePNG(t, 1, maxLogoDimension+1), 128); err == nil {
+		t.Error("DecodeLogo accepted a PNG taller than the limit")
+	}
+	if _, err := DecodeLogo(encodePNG(t, 16, 16), 128); err != nil {
+		t.Errorf("DecodeLogo(small PNG) = %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// QR payload types besides plain URLs.
+const (
+	PayloadVCard  = "vcard"
+	PayloadMeCard = "mecard"
+	PayloadWiFi   = "wifi"
+	PayloadEmail  = "email"
+	PayloadSMS    = "sms"
+	PayloadTel    = "tel"
+	PayloadGeo    = "geo"
+)
+
+// Contact is a person or business for vCard and MeCard codes.
+type Contact struct {
+	FirstName    string
+	LastName     string
+	Organization string
+	Title        string
+	Phone        string
+	Email        string
+	URL          string
+	Address      string
+	Note         string
+}
+
+// VCard encodes c as a vCard 3.0, which phones offer to save as a contact.
+func VCard(c Contact) string {
+	var b strings.Builder
+	line := func(name, value string) {
+		if value != "" {
+			b.WriteString(name + ":" + value + "\r\n")
+		}
+	}
+	fullName := strings.TrimSpace(c.FirstName + " " + c.LastName)
+
+	line("BEGIN", "VCARD")
+	line("VERSION", "3.0")
+	line("N", vcardEscape(c.LastName)+";"+vcardEscape(c.FirstName)+";;;")
+	line("FN", vcardEscape(fullName))
+	line("ORG", vcardEscape(c.Organization))
+	line("TITLE", vcardEscape(c.Title))
+	line("TEL;TYPE=CELL", vcardEscape(c.Phone))
+	line("EMAIL", vcardEscape(c.Email))
+	line("URL", vcardEscape(c.URL))
+	if c.Address != "" {
+		line("ADR", ";;"+vcardEscape(c.Address)+";;;;")
+	}
+	line("NOTE", vcardEscape(c.Note))
+	line("END", "VCARD")
+	return b.String()
+}
+
+// MeCard encodes c in the MeCard format, which is shorter than a vCard and
+// so gives a smaller code, but holds fewer fields.
+func MeCard(c Contact) string {
+	var b strings.Builder
+	field := func(name, value string) {
+		if value != "" {
+			b.WriteString(name + ":" + mecardEscape(value) + ";")
+		}
+	}
+
+	b.WriteString("MECARD:")
+	// The comma between the names is a separator, so each is escaped alone.
+	name := mecardEscape(c.LastName)
+	if c.FirstName != "" {
+		if name != "" {
+			name += ","
+		}
+		name += mecardEscape(c.FirstName)
+	}
+	if name != "" {
+		b.WriteString("N:" + name + ";")
+	}
+	field("ORG", c.Organization)
+	field("TEL", c.Phone)
+	field("EMAIL", c.Email)
+	field("URL", c.URL)
+	field("ADR", c.Address)
+	field("NOTE", c.Note)
+	b.WriteString(";")
+	return b.String()
+}
+
+// vcardEscaper escapes text values. Line breaks of any kind become the \n
+// escape, so a value cannot start a property of its own.
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\r", `\n`, "\n", `\n`)
+
+func vcardEscape(s string) string {
+	return vcardEscaper.Replace(s)
+}
+
+// mecardEscaper escapes field values. MeCard has no escape for line
+// breaks and some readers split fields on them, so they become spaces.
+var mecardEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, ":", `\:`, `"`, `\"`, "\r\n", " ", "\r", " ", "\n", " ")
+
+func mecardEscape(s string) string {
+	return mecardEscaper.Replace(s)
+}
+
+// Wi-Fi security types.
+const (
+	WiFiWPA    = "WPA"
+	WiFiWEP    = "WEP"
+	WiFiNoPass = "nopass"
+)
+
+// WiFiNetwork is a network that phones join when they scan its code.
+type WiFiNetwork struct {
+	SSID     string
+	Password string
+	// Security is WPA (also for WPA2 and WPA3), WEP or nopass. Empty is WPA.
+	Security string
+	Hidden   bool
+}
+
+var hexKey = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+// WiFiPayload encodes n, checking that the password suits its security.
+func WiFiPayload(n WiFiNetwork) (string, error) {
+	security := n.Security
+	if security == "" {
+		security = WiFiWPA
+	}
+	switch security {
+	case WiFiWPA:
+		if l := len(n.Password); l < 8 || l > 63 {
+			return "", errors.New("a WPA password must be 8 to 63 characters")
+		}
+	case WiFiWEP:
+		switch l := len(n.Password); {
+		case l == 5 || l == 13:
+		case (l == 10 || l == 26) && hexKey.MatchString(n.Password):
+		default:
+			return "", errors.New("a WEP key must be 5 or 13 characters, or 10 or 26 hex digits")
+		}
+	case WiFiNoPass:
+		if n.Password != "" {
+			return "", errors.New("an open network takes no password")
+		}
+	default:
+		return "", fmt.Errorf("unknown Wi-Fi security %q", n.Security)
+	}
+
+	payload := "WIFI:T:" + security + ";S:" + mecardEscape(n.SSID) + ";"
+	if n.Password != "" {
+		payload += "P:" + mecardEscape(n.Password) + ";"
+	}
+	if n.Hidden {
+		payload += "H:true;"
+	}
+	return payload + ";", nil
+}
+
+// EmailMessage is a draft that the scanner's mail app opens.
+type EmailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// MailtoPayload encodes m as a mailto: URI.
+func MailtoPayload(m EmailMessage) string {
+	var query []string
+	if m.Subject != "" {
+		query = append(query, "subject="+uriEscape(m.Subject))
+	}
+	if m.Body != "" {
+		query = append(query, "body="+uriEscape(m.Body))
+	}
+	payload := "mailto:" + m.To
+	if len(query) > 0 {
+		payload += "?" + strings.Join(query, "&")
+	}
+	return payload
+}
+
+// SMSMessage is a text message that the scanner's messaging app opens.
+type SMSMessage struct {
+	Phone string
+	Body  string
+}
+
+// SMSPayload encodes m as an sms: URI.
+func SMSPayload(m SMSMessage) string {
+	if m.Body == "" {
+		return "sms:" + m.Phone
+	}
+	return "sms:" + m.Phone + "?body=" + uriEscape(m.Body)
+}
+
+// TelPayload encodes phone as a tel: URI.
+func TelPayload(phone string) string {
+	return "tel:" + phone
+}
+
+// GeoPayload encodes a location as a geo: URI, which opens a maps app.
+func GeoPayload(latitude, longitude float64) string {
+	return "geo:" + strconv.FormatFloat(latitude, 'f', -1, 64) + "," + strconv.FormatFloat(longitude, 'f', -1, 64)
+}
+
+// uriEscape escapes a query value with %20 for spaces, as mail and
+// messaging apps do not all read + as a space.
+func uriEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
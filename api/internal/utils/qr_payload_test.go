@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// A line break in a value must not start a property of its own, whichever
+// line ending it uses.
+func TestVCardEscapesLineBreaks(t *testing.T) {
+	for _, lineBreak := range []string{"\r\n", "\r", "\n"} {
+		card := VCard(Contact{FirstName: "Ada" + lineBreak + "TEL:+15550100", Note: "a;b,c\\d"})
+		for _, line := range strings.Split(strings.TrimSuffix(card, "\r\n"), "\r\n") {
+			if strings.ContainsAny(line, "\r\n") {
+				t.Errorf("%q: line %q holds a raw line break", lineBreak, line)
+			}
+			if strings.HasPrefix(line, "TEL") {
+				t.Errorf("%q: the name started a TEL property: %q", lineBreak, card)
+			}
+		}
+		if !strings.Contains(card, `FN:Ada\nTEL:+15550100`+"\r\n") {
+			t.Errorf("%q: FN not escaped as expected in %q", lineBreak, card)
+		}
+		if !strings.Contains(card, `NOTE:a\;b\,c\\d`+"\r\n") {
+			t.Errorf("%q: NOTE not escaped as expected in %q", lineBreak, card)
+		}
+	}
+}
+
+func TestMeCardEscapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		contact Contact
+		want    string
+	}{
+		{
+			"names",
+			Contact{FirstName: "Ada", LastName: "Lovelace"},
+			"MECARD:N:Lovelace,Ada;;",
+		},
+		{
+			"separators",
+			Contact{FirstName: "A;b", LastName: `C,d:e"f\g`},
+			`MECARD:N:C\,d\:e\"f\\g,A\;b;;`,
+		},
+		{
+			"carriage return",
+			Contact{FirstName: "Ada\rTEL:+15550100"},
+			`MECARD:N:Ada TEL\:+15550100;;`,
+		},
+		{
+			"line breaks",
+			Contact{FirstName: "Ada", Note: "one\r\ntwo\nthree"},
+			"MECARD:N:Ada;NOTE:one two three;;",
+		},
+	}
+	for _, tt := range tests {
+		if got := MeCard(tt.contact); got != tt.want {
+			t.Errorf("%s: MeCard() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWiFiPayloadEscapes(t *testing.T) {
+	got, err := WiFiPayload(WiFiNetwork{SSID: "Cafe;\rT:WEP", Password: `pa"ss:word`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `WIFI:T:WPA;S:Cafe\; T\:WEP;P:pa\"ss\:word;;`; got != want {
+		t.Errorf("WiFiPayload() = %q, want %q", got, want)
+	}
+}
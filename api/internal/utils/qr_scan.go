@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"api/internal/qrdecode"
+)
+
+const (
+	// MinQRContrast is the lowest contrast ratio, as WCAG measures it,
+	// of a design that is allowed; below it phone cameras often fail.
+	MinQRContrast = 3.0
+	// RecommendedQRContrast reads reliably in poor light and print.
+	RecommendedQRContrast = 4.5
+
+	minModulePixels = 3
+)
+
+// QRScanReport tells whether a rendered code reads back and how close it
+// is to not reading.
+type QRScanReport struct {
+	Readable bool `json:"readable"`
+	// Problem is why an unreadable code fails.
+	Problem       string  `json:"problem,omitempty"`
+	ContrastRatio float64 `json:"contrast_ratio"`
+	// ErrorBudgetUsed is the share of the error correction taken up by the
+	// logo and styling, from 0 to 1.
+	ErrorBudgetUsed float64  `json:"error_budget_used"`
+	ModulePixels    float64  `json:"module_pixels"`
+	Warnings        []string `json:"warnings,omitempty"`
+}
+
+// checkQRCode decodes img, the rendered layout, and compares it with the
+// content it should hold.
+func checkQRCode(content string, img *image.RGBA, l *qrLayout, opts QROptions) QRScanReport {
+	report := QRScanReport{
+		Readable:     true,
+		ModulePixels: math.Round(l.module*10) / 10,
+	}
+
+	// The contrast that matters is that of the lightest dark colour.
+	dark := []color.Color{l.eyeColor}
+	if g := l.gradient; g != nil {
+		dark = append(dark, g.From, g.To)
+	} else {
+		dark = append(dark, l.foreground)
+	}
+	report.ContrastRatio = math.Inf(1)
+	inverted := false
+	for _, c := range dark {
+		report.ContrastRatio = math.Min(report.ContrastRatio, ContrastRatio(c, l.background))
+		inverted = inverted || luminance(c) > luminance(l.background)
+	}
+	report.ContrastRatio = math.Round(report.ContrastRatio*100) / 100
+
+	if l.transparent {
+		// Judge a transparent code on its own background colour.
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.NewUniform(l.background), image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+		img = flat
+	}
+	result, err := qrdecode.Decode(img)
+	switch {
+	case err != nil:
+		report.Readable, report.Problem = false, "the code could not be read back: "+err.Error()
+	case result.Text != content:
+		report.Readable, report.Problem = false, "the code reads back as different content"
+	case report.ContrastRatio < MinQRContrast:
+		report.Readable = false
+		report.Problem = fmt.Sprintf("contrast ratio %.2f:1 is below the %.0f:1 minimum", report.ContrastRatio, MinQRContrast)
+	}
+	if err == nil {
+		report.ErrorBudgetUsed = math.Round(result.Damage*100) / 100
+	}
+
+	if report.Readable && report.ContrastRatio < RecommendedQRContrast {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("contrast ratio %.2f:1 is below the recommended %.1f:1", report.ContrastRatio, RecommendedQRContrast))
+	}
+	if inverted {
+		report.Warnings = append(report.Warnings, "light modules on a dark background are not read by every scanner")
+	}
+	if report.ErrorBudgetUsed > 0.5 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("the logo and styling use %.0f%% of the error correction, leaving little margin for print damage", report.ErrorBudgetUsed*100))
+	}
+	if opts.QuietZone < DefaultQuietZone {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("a quiet zone under %d modules may not scan on busy backgrounds", DefaultQuietZone))
+	}
+	if l.transparent {
+		report.Warnings = append(report.Warnings, "a transparent code needs a light, plain surface behind it")
+	}
+	if l.module < minModulePixels {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("modules are %.1f pixels wide; render larger to print", l.module))
+	}
+	return report
+}
+
+// ContrastRatio returns the WCAG contrast ratio of two colours, from 1 for
+// equal colours to 21 for black on white.
+func ContrastRatio(a, b color.Color) float64 {
+	la, lb := luminance(a), luminance(b)
+	return (math.Max(la, lb) + 0.05) / (math.Min(la, lb) + 0.05)
+}
+
+// luminance is the WCAG relative luminance of c.
+func luminance(c color.Color) float64 {
+	r, g, b := rgb(c)
+	linear := func(v int) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b)
+}
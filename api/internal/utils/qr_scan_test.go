@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Every design the style options allow must pass the scan check; round
+// eyes once made finder patterns measure short and codes fail to decode.
+func TestStyledQRCodesReadBack(t *testing.T) {
+	levels := []qrcode.RecoveryLevel{qrcode.Low, qrcode.Medium, qrcode.High, qrcode.Highest}
+	cases := []struct {
+		content string
+		size    int
+	}{
+		{"https://example.com/abc", 300},
+		// Version 17 to 27 codes, depending on the level, with modules of
+		// 3 to 4 pixels and of 10 to 13.
+		{"https://example.com/" + strings.Repeat("a", 220), 300},
+		{"https://example.com/" + strings.Repeat("a", 220), 1000},
+	}
+	for _, modules := range []string{"square", "rounded", "dot"} {
+		for _, eyes := range []string{"square", "rounded", "circle"} {
+			for _, level := range levels {
+				for _, c := range cases {
+					name := fmt.Sprintf("%s modules/%s eyes/level %d/%d chars at %dpx", modules, eyes, level, len(c.content), c.size)
+					t.Run(name, func(t *testing.T) {
+						t.Parallel()
+						_, report, err := RenderCheckedQRCode(c.content, QROptions{
+							Size:            c.size,
+							Format:          "png",
+							Foreground:      color.Black,
+							Background:      color.White,
+							ErrorCorrection: level,
+							QuietZone:       DefaultQuietZone,
+							Style:           QRStyle{Modules: modules, Eyes: eyes},
+						})
+						if err != nil {
+							t.Fatal(err)
+						}
+						if !report.Readable {
+							t.Errorf("unreadable: %s", report.Problem)
+						}
+					})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	ReferrerDirect = "direct"
+	ReferrerSearch = "search"
+	ReferrerSocial = "social"
+	ReferrerEmail  = "email"
+	ReferrerOther  = "other"
+)
+
+// referrerDomains maps known referrer hosts to a source category. Hosts are
+// matched on the exact name or any parent domain, so "mail.google.com" must
+// be listed to win over the generic "google" search keyword below.
+var referrerDomains = map[string]string{
+	"mail.google.com":    ReferrerEmail,
+	"outlook.live.com":   ReferrerEmail,
+	"outlook.office.com": ReferrerEmail,
+	"mail.yahoo.com":     ReferrerEmail,
+	"mail.proton.me":     ReferrerEmail,
+	"mail.zoho.com":      ReferrerEmail,
+	"app.fastmail.com":   ReferrerEmail,
+
+	"facebook.com":         ReferrerSocial,
+	"fb.com":               ReferrerSocial,
+	"instagram.com":        ReferrerSocial,
+	"twitter.com":          ReferrerSocial,
+	"x.com":                ReferrerSocial,
+	"t.co":                 ReferrerSocial,
+	"linkedin.com":         ReferrerSocial,
+	"lnkd.in":              ReferrerSocial,
+	"reddit.com":           ReferrerSocial,
+	"pinterest.com":        ReferrerSocial,
+	"tiktok.com":           ReferrerSocial,
+	"youtube.com":          ReferrerSocial,
+	"youtu.be":             ReferrerSocial,
+	"threads.net":          ReferrerSocial,
+	"bsky.app":             ReferrerSocial,
+	"mastodon.social":      ReferrerSocial,
+	"discord.com":          ReferrerSocial,
+	"slack.com":            ReferrerSocial,
+	"whatsapp.com":         ReferrerSocial,
+	"telegram.org":         ReferrerSocial,
+	"t.me":                 ReferrerSocial,
+	"quora.com":            ReferrerSocial,
+	"news.ycombinator.com": ReferrerSocial,
+
+	"duckduckgo.com":   ReferrerSearch,
+	"ecosia.org":       ReferrerSearch,
+	"search.brave.com": ReferrerSearch,
+	"startpage.com":    ReferrerSearch,
+}
+
+// searchEngines are matched against each label of the host so that country
+// domains (google.co.in, yandex.ru, ...) are classified without listing them.
+var searchEngines = map[string]bool{
+	"google": true,
+	"bing":   true,
+	"yahoo":  true,
+	"baidu":  true,
+	"yandex": true,
+	"naver":  true,
+}
+
+// utmSources maps common utm_source / utm_medium values to a category for
+// visits that arrive without a Referer header.
+var utmSources = map[string]string{
+	"email":      ReferrerEmail,
+	"e-mail":     ReferrerEmail,
+	"newsletter": ReferrerEmail,
+	"mailchimp":  ReferrerEmail,
+	"sendgrid":   ReferrerEmail,
+	"social":     ReferrerSocial,
+	"facebook":   ReferrerSocial,
+	"fb":         ReferrerSocial,
+	"instagram":  ReferrerSocial,
+	"ig":         ReferrerSocial,
+	"twitter":    ReferrerSocial,
+	"x":          ReferrerSocial,
+	"linkedin":   ReferrerSocial,
+	"reddit":     ReferrerSocial,
+	"tiktok":     ReferrerSocial,
+	"youtube":    ReferrerSocial,
+	"whatsapp":   ReferrerSocial,
+	"telegram":   ReferrerSocial,
+	"cpc":        ReferrerSearch,
+	"ppc":        ReferrerSearch,
+	"organic":    ReferrerSearch,
+	"search":     ReferrerSearch,
+}
+
+// ClassifyReferrer normalises a raw Referer header into its domain and a
+// source category. When the header is empty the utm_source (and then
+// utm_medium) query parameters are used as a fallback.
+func ClassifyReferrer(referrer, utmSource, utmMedium string) (domain string, source string) {
+	if host := referrerHost(referrer); host != "" {
+		return host, classifyHost(host)
+	}
+
+	utmSource = strings.ToLower(strings.TrimSpace(utmSource))
+	utmMedium = strings.ToLower(strings.TrimSpace(utmMedium))
+	if utmSource == "" && utmMedium == "" {
+		return "", ReferrerDirect
+	}
+
+	if category, ok := utmSources[utmSource]; ok {
+		return utmSource, category
+	}
+	if searchEngines[utmSource] {
+		return utmSource, ReferrerSearch
+	}
+	if strings.Contains(utmSource, ".") {
+		return utmSource, classifyHost(utmSource)
+	}
+	if category, ok := utmSources[utmMedium]; ok {
+		return utmSource, category
+	}
+	return utmSource, ReferrerOther
+}
+
+func referrerHost(referrer string) string {
+	referrer = strings.TrimSpace(referrer)
+	if referrer == "" {
+		return ""
+	}
+	if !strings.Contains(referrer, "://") {
+		referrer = "http://" + referrer
+	}
+	parsed, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	for _, prefix := range []string{"www.", "m.", "l.", "lm.", "mobile."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	return host
+}
+
+func classifyHost(host string) string {
+	for name := host; name != ""; {
+		if category, ok := referrerDomains[name]; ok {
+			return category
+		}
+		dot := strings.IndexByte(name, '.')
+		if dot < 0 {
+			break
+		}
+		name = name[dot+1:]
+	}
+
+	for _, label := range strings.Split(host, ".") {
+		if searchEngines[label] {
+			return ReferrerSearch
+		}
+	}
+	if strings.HasPrefix(host, "mail.") || strings.HasPrefix(host, "webmail.") {
+		return ReferrerEmail
+	}
+	return ReferrerOther
+}
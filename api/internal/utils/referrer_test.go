@@ -0,0 +1,52 @@
+package utils
+
+import "testing"
+
+func TestClassifyReferrer(t *testing.T) {
+	tests := []struct {
+		referrer, utmSource, utmMedium string
+		wantDomain, wantSource         string
+	}{
+		// Domain map, on the exact host or a parent domain.
+		{"https://www.facebook.com/", "", "", "facebook.com", ReferrerSocial},
+		{"https://l.facebook.com/l.php?u=x", "", "", "facebook.com", ReferrerSocial},
+		{"https://t.co/abc", "", "", "t.co", ReferrerSocial},
+		{"https://news.ycombinator.com/item?id=1", "", "", "news.ycombinator.com", ReferrerSocial},
+		{"https://old.reddit.com/r/golang", "", "", "old.reddit.com", ReferrerSocial},
+		{"https://mail.google.com/mail/u/0/", "", "", "mail.google.com", ReferrerEmail},
+		{"https://duckduckgo.com/", "", "", "duckduckgo.com", ReferrerSearch},
+		{"HTTPS://WWW.X.COM./home", "", "", "x.com", ReferrerSocial},
+		{"lnkd.in/abc", "", "", "lnkd.in", ReferrerSocial},
+
+		// Search engines by any label, so country domains need no listing.
+		{"https://www.google.com/", "", "", "google.com", ReferrerSearch},
+		{"https://www.google.co.in/", "", "", "google.co.in", ReferrerSearch},
+		{"https://yandex.ru/search", "", "", "yandex.ru", ReferrerSearch},
+		{"https://search.yahoo.co.jp/", "", "", "search.yahoo.co.jp", ReferrerSearch},
+
+		// Other hosts, with webmail recognised by name.
+		{"https://webmail.example.net/", "", "", "webmail.example.net", ReferrerEmail},
+		{"https://blog.example.com/post", "", "", "blog.example.com", ReferrerOther},
+
+		// A Referer header wins over UTM parameters.
+		{"https://www.bing.com/", "newsletter", "email", "bing.com", ReferrerSearch},
+
+		// UTM fallback without a Referer header.
+		{"", "", "", "", ReferrerDirect},
+		{"  ", " ", "", "", ReferrerDirect},
+		{"", "Newsletter", "", "newsletter", ReferrerEmail},
+		{"", "ig", "", "ig", ReferrerSocial},
+		{"", "google", "cpc", "google", ReferrerSearch},
+		{"", "news.ycombinator.com", "", "news.ycombinator.com", ReferrerSocial},
+		{"", "acme", "email", "acme", ReferrerEmail},
+		{"", "acme", "banner", "acme", ReferrerOther},
+		{"", "", "social", "", ReferrerSocial},
+	}
+	for _, tt := range tests {
+		domain, source := ClassifyReferrer(tt.referrer, tt.utmSource, tt.utmMedium)
+		if domain != tt.wantDomain || source != tt.wantSource {
+			t.Errorf("ClassifyReferrer(%q, %q, %q) = %q, %q, want %q, %q",
+				tt.referrer, tt.utmSource, tt.utmMedium, domain, source, tt.wantDomain, tt.wantSource)
+		}
+	}
+}
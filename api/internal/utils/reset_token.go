@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// PasswordResetTTL is how long a password reset link works.
+const PasswordResetTTL = time.Hour
+
+// NewPasswordResetToken returns a random token for a reset link and the hash
+// to store in its place.
+func NewPasswordResetToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashPasswordResetToken(token), nil
+}
+
+// HashPasswordResetToken hashes a token for lookup. The token is random
+// enough that a fast hash is safe.
+func HashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
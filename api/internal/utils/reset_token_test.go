@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestNewPasswordResetToken(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		token, hash, err := NewPasswordResetToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, err := base64.RawURLEncoding.DecodeString(token); err != nil || len(b) != 32 {
+			t.Fatalf("token %q is not 32 URL safe base64 bytes: %v", token, err)
+		}
+		if hash != HashPasswordResetToken(token) {
+			t.Fatalf("hash %s is not the hash of token %s", hash, token)
+		}
+		if seen[token] {
+			t.Fatalf("token %s repeated", token)
+		}
+		seen[token] = true
+	}
+}
+
+func TestHashPasswordResetToken(t *testing.T) {
+	// The SHA-256 of "abc", which fits the token_hash column as hex.
+	if got, want := HashPasswordResetToken("abc"), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got != want {
+		t.Errorf("HashPasswordResetToken(abc) = %s, want %s", got, want)
+	}
+	if HashPasswordResetToken("abc") == HashPasswordResetToken("abd") {
+		t.Error("different tokens hash the same")
+	}
+}
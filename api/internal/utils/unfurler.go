@@ -0,0 +1,48 @@
+package utils
+
+import "strings"
+
+// unfurlers are user agent fragments of the crawlers chat apps and social
+// networks use to build link previews.
+var unfurlers = []string{
+	"slackbot",
+	"slack-imgproxy",
+	"twitterbot",
+	"facebookexternalhit",
+	"facebot",
+	"linkedinbot",
+	"discordbot",
+	"telegrambot",
+	"skypeuripreview",
+	"microsoftpreview",
+	"redditbot",
+	"pinterestbot",
+	"embedly",
+	"mastodon",
+	"vkshare",
+	"iframely",
+	"bitlybot",
+}
+
+// unfurlerPrefixes start the user agents of crawlers from apps whose in-app
+// browsers also name the app, after a Mozilla/5.0 prefix the crawlers lack.
+var unfurlerPrefixes = []string{
+	"whatsapp/",
+	"pinterest/",
+}
+
+// IsUnfurler reports whether userAgent belongs to a link preview crawler.
+func IsUnfurler(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	for _, prefix := range unfurlerPrefixes {
+		if strings.HasPrefix(ua, prefix) {
+			return true
+		}
+	}
+	for _, bot := range unfurlers {
+		if strings.Contains(ua, bot) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package utils
+
+import "testing"
+
+func TestIsUnfurler(t *testing.T) {
+	tests := []struct {
+		name, ua string
+		want     bool
+	}{
+		{"slack", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", true},
+		{"twitter", "Twitterbot/1.0", true},
+		{"facebook", "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", true},
+		{"discord", "Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)", true},
+		{"whatsapp crawler", "WhatsApp/2.23.20.0 A", true},
+		{"pinterestbot", "Mozilla/5.0 (compatible; Pinterestbot/1.0; +http://www.pinterest.com/bot.html)", true},
+		{"pinterest crawler", "Pinterest/0.2 (+http://www.pinterest.com/bot.html)", true},
+
+		{"chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36", false},
+		{"whatsapp in-app browser", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 WhatsApp/24.6.77", false},
+		{"pinterest in-app browser ios", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [Pinterest/iOS]", false},
+		{"pinterest in-app browser android", "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36 Pinterest for Android/12.16.0", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		if got := IsUnfurler(tt.ua); got != tt.want {
+			t.Errorf("%s: IsUnfurler(%q) = %v, want %v", tt.name, tt.ua, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package utils
+
+// Visit sources tell how a visitor reached a short link, so QR scans and
+// API lookups can be told apart from clicks on the shared URL.
+const (
+	VisitSourceLink = "link"
+	VisitSourceQR   = "qr"
+	VisitSourceAPI  = "api"
+)
@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/internal/db"
+	"api/internal/safehttp"
+)
+
+const (
+	EventLinkCreated = "link.created"
+	EventLinkUpdated = "link.updated"
+	EventLinkDeleted = "link.deleted"
+	EventLinkClicked = "link.clicked"
+
+	// Sent by the health worker when a link's destination starts failing
+	// and when it answers again.
+	EventLinkUnhealthy = "link.unhealthy"
+	EventLinkRecovered = "link.recovered"
+
+	// Sent when a link's destination is found suspicious or malicious
+	// after it was created.
+	EventLinkFlagged = "link.flagged"
+
+	EventTest = "webhook.test"
+
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+
+	SignatureHeader = "X-Webhook-Signature"
+
+	// maxAttempts is the number of sends before a delivery is marked failed.
+	// With retryBase doubling each time the last retry is ~2 hours after
+	// the first attempt.
+	maxAttempts  = 8
+	retryBase    = time.Minute
+	maxBodyBytes = 2048
+	claimBatch   = 20
+)
+
+// Events lists the event types endpoints may subscribe to.
+var Events = []string{
+	EventLinkCreated,
+	EventLinkUpdated,
+	EventLinkDeleted,
+	EventLinkClicked,
+	EventLinkUnhealthy,
+	EventLinkRecovered,
+	EventLinkFlagged,
+}
+
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the JSON body POSTed to an endpoint.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Sign returns the hex HMAC-SHA256 of payload, sent in SignatureHeader.
+// Receivers verify it the same way verifyRazorpaySignature does.
+func Sign(payload []byte, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NewSecret returns a random signing secret for a new endpoint.
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Dispatcher queues events for the subscribed endpoints of a user and
+// delivers them in the background, retrying with exponential backoff.
+type Dispatcher struct {
+	store    *db.Queries
+	client   *http.Client
+	interval time.Duration
+	wake     chan struct{}
+}
+
+func NewDispatcher(store *db.Queries) *Dispatcher {
+	return &Dispatcher{
+		store: store,
+		client: safehttp.NewClient(safehttp.Options{
+			Timeout:      10 * time.Second,
+			MaxRedirects: -1,
+		}),
+		interval: 5 * time.Second,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Emit queues event for every active endpoint of userID subscribed to it.
+func (d *Dispatcher) Emit(ctx context.Context, userID int32, event string, data any) {
+	endpoints, err := d.store.ListWebhookEndpointsForEvent(ctx, db.ListWebhookEndpointsForEventParams{
+		UserID: userID,
+		Event:  event,
+	})
+	if err != nil {
+		log.Printf("webhook: listing endpoints for %s: %v", event, err)
+		return
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+
+	for _, endpoint := range endpoints {
+		if _, err := d.Enqueue(ctx, endpoint, event, data); err != nil {
+			log.Printf("webhook: queueing %s for endpoint %d: %v", event, endpoint.ID, err)
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue stores a pending delivery of event to endpoint.
+func (d *Dispatcher) Enqueue(ctx context.Context, endpoint db.WebhookEndpoint, event string, data any) (db.WebhookDelivery, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return db.WebhookDelivery{}, err
+	}
+	payload, err := json.Marshal(Event{
+		ID:        "evt_" + hex.EncodeToString(id),
+		Type:      event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return db.WebhookDelivery{}, err
+	}
+
+	return d.store.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+		EndpointID: endpoint.ID,
+		Event:      event,
+		Payload:    payload,
+	})
+}
+
+// Run delivers due deliveries until ctx is cancelled. Deliveries left
+// mid-send by a previous process are requeued on start.
+func (d *Dispatcher) Run(ctx context.Context) {
+	if err := d.store.RequeueSendingWebhookDeliveries(ctx); err != nil {
+		log.Printf("webhook: requeue failed: %v", err)
+	}
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.store.ClaimDueWebhookDeliveries(ctx, claimBatch)
+		if err != nil {
+			log.Printf("webhook: claim failed: %v", err)
+			return
+		}
+		for _, delivery := range deliveries {
+			endpoint, err := d.store.GetWebhookEndpointByID(ctx, delivery.EndpointID)
+			if err != nil {
+				log.Printf("webhook: endpoint %d: %v", delivery.EndpointID, err)
+				continue
+			}
+			d.Deliver(ctx, endpoint, delivery, true)
+		}
+		if len(deliveries) < claimBatch {
+			return
+		}
+	}
+}
+
+// Deliver sends delivery to endpoint once and records the attempt. When
+// retry is set an attempt that failed for a transient reason is
+// rescheduled until maxAttempts is reached; see retryable.
+func (d *Dispatcher) Deliver(ctx context.Context, endpoint db.WebhookEndpoint, delivery db.WebhookDelivery, retry bool) db.RecordWebhookAttemptParams {
+	attempt := d.send(ctx, endpoint, delivery)
+	attempt.ID = delivery.ID
+	attempt.NextAttemptAt = time.Now()
+
+	switch {
+	case attempt.Status == StatusDelivered:
+	case retry && retryable(attempt) && delivery.Attempts+1 < maxAttempts:
+		attempt.Status = StatusPending
+		attempt.NextAttemptAt = time.Now().Add(retryBase << delivery.Attempts)
+	default:
+		attempt.Status = StatusFailed
+	}
+
+	if err := d.store.RecordWebhookAttempt(ctx, attempt); err != nil {
+		log.Printf("webhook: recording delivery %d: %v", delivery.ID, err)
+	}
+	return attempt
+}
+
+// retryable reports whether a failed attempt may succeed later: the
+// endpoint could not be reached, had a server error, or asked to be
+// called again later. Other 4xx responses reject the event itself.
+func retryable(attempt db.RecordWebhookAttemptParams) bool {
+	if !attempt.ResponseCode.Valid {
+		return true
+	}
+	code := attempt.ResponseCode.Int32
+	return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
+
+func (d *Dispatcher) send(ctx context.Context, endpoint db.WebhookEndpoint, delivery db.WebhookDelivery) db.RecordWebhookAttemptParams {
+	var attempt db.RecordWebhookAttemptParams
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = sql.NullString{String: err.Error(), Valid: true}
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "url-shortner-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(int(delivery.ID)))
+	req.Header.Set(SignatureHeader, Sign(delivery.Payload, endpoint.Secret))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = sql.NullString{String: err.Error(), Valid: true}
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	body := strings.ReplaceAll(strings.ToValidUTF8(string(raw), ""), "\x00", "")
+	attempt.ResponseCode = sql.NullInt32{Int32: int32(resp.StatusCode), Valid: true}
+	attempt.ResponseBody = sql.NullString{String: body, Valid: body != ""}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		attempt.Status = StatusDelivered
+	} else {
+		attempt.Error = sql.NullString{String: fmt.Sprintf("unexpected status %s", resp.Status), Valid: true}
+	}
+	return attempt
+}
@@ -0,0 +1,163 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api/internal/db"
+)
+
+// recorder stands in for the database, keeping the arguments of every
+// statement Deliver runs.
+type recorder struct {
+	execs [][]any
+}
+
+func (r *recorder) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	r.execs = append(r.execs, args)
+	return nil, nil
+}
+
+func (r *recorder) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	panic("unexpected PrepareContext")
+}
+
+func (r *recorder) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	panic("unexpected QueryContext")
+}
+
+func (r *recorder) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	panic("unexpected QueryRowContext")
+}
+
+func newTestDispatcher(server *httptest.Server) (*Dispatcher, *recorder) {
+	rec := &recorder{}
+	d := NewDispatcher(db.New(rec))
+	// The default client refuses loopback addresses such as the test server's.
+	d.client = server.Client()
+	return d, rec
+}
+
+func TestDeliverSignsExactBody(t *testing.T) {
+	// Odd spacing and key order, which re-encoding the JSON would change.
+	payload := []byte(`{"type":"link.created",  "id":"evt_1","data":{"shortcode":"abc"}}`)
+	secret := "whsec_test"
+
+	var gotBody []byte
+	var gotHeader http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d, rec := newTestDispatcher(server)
+	attempt := d.Deliver(context.Background(),
+		db.WebhookEndpoint{ID: 1, Url: server.URL, Secret: secret},
+		db.WebhookDelivery{ID: 42, Event: EventLinkCreated, Payload: payload},
+		true,
+	)
+
+	if string(gotBody) != string(payload) {
+		t.Errorf("body = %s, want %s", gotBody, payload)
+	}
+	if got, want := gotHeader.Get(SignatureHeader), Sign(payload, secret); got != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+	}
+	// Sign must be a plain HMAC-SHA256, which receivers recompute themselves.
+	if got, want := Sign([]byte("The quick brown fox jumps over the lazy dog"), "key"),
+		"f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"; got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if got := gotHeader.Get("X-Webhook-Event"); got != EventLinkCreated {
+		t.Errorf("X-Webhook-Event = %q", got)
+	}
+	if got := gotHeader.Get("X-Webhook-Delivery"); got != "42" {
+		t.Errorf("X-Webhook-Delivery = %q", got)
+	}
+	if attempt.Status != StatusDelivered || attempt.ResponseCode.Int32 != http.StatusNoContent {
+		t.Errorf("attempt = %+v, want delivered with 204", attempt)
+	}
+	if len(rec.execs) != 1 || rec.execs[0][0] != int32(42) || rec.execs[0][1] != StatusDelivered {
+		t.Errorf("recorded %v, want delivery 42 delivered", rec.execs)
+	}
+}
+
+func TestDeliverRetriesTransientFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int32
+		retry    bool
+		want     string
+	}{
+		{"ok", http.StatusOK, 0, true, StatusDelivered},
+		{"server error", http.StatusInternalServerError, 0, true, StatusPending},
+		{"bad gateway", http.StatusBadGateway, 3, true, StatusPending},
+		{"unavailable", http.StatusServiceUnavailable, 0, true, StatusPending},
+		{"rate limited", http.StatusTooManyRequests, 0, true, StatusPending},
+		{"request timeout", http.StatusRequestTimeout, 0, true, StatusPending},
+		{"bad request", http.StatusBadRequest, 0, true, StatusFailed},
+		{"unauthorized", http.StatusUnauthorized, 0, true, StatusFailed},
+		{"not found", http.StatusNotFound, 0, true, StatusFailed},
+		{"gone", http.StatusGone, 0, true, StatusFailed},
+		{"server error without retry", http.StatusInternalServerError, 0, false, StatusFailed},
+		{"server error on last attempt", http.StatusInternalServerError, maxAttempts - 1, true, StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			d, rec := newTestDispatcher(server)
+			start := time.Now()
+			attempt := d.Deliver(context.Background(),
+				db.WebhookEndpoint{ID: 1, Url: server.URL, Secret: "s"},
+				db.WebhookDelivery{ID: 7, Event: EventLinkClicked, Payload: []byte(`{}`), Attempts: tt.attempts},
+				tt.retry,
+			)
+
+			if calls != 1 {
+				t.Errorf("endpoint called %d times, want 1", calls)
+			}
+			if attempt.Status != tt.want {
+				t.Errorf("status = %s, want %s", attempt.Status, tt.want)
+			}
+			if tt.want == StatusPending {
+				wait := attempt.NextAttemptAt.Sub(start)
+				if backoff := retryBase << tt.attempts; wait < backoff || wait > backoff+time.Minute {
+					t.Errorf("next attempt in %s, want %s", wait, backoff)
+				}
+			}
+			if len(rec.execs) != 1 || rec.execs[0][1] != tt.want {
+				t.Errorf("recorded %v, want status %s", rec.execs, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverRetriesUnreachableEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	d, _ := newTestDispatcher(server)
+	url := server.URL
+	server.Close()
+
+	attempt := d.Deliver(context.Background(),
+		db.WebhookEndpoint{ID: 1, Url: url, Secret: "s"},
+		db.WebhookDelivery{ID: 7, Event: EventLinkClicked, Payload: []byte(`{}`)},
+		true,
+	)
+	if attempt.Status != StatusPending || attempt.ResponseCode.Valid || !attempt.Error.Valid {
+		t.Errorf("attempt = %+v, want pending with an error and no response", attempt)
+	}
+}
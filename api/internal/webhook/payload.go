@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"time"
+
+	"api/configs"
+	"api/internal/db"
+)
+
+// Link is the data of the link.created, link.updated and link.deleted events.
+type Link struct {
+	ID          int32      `json:"id"`
+	Shortcode   string     `json:"shortcode"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	BackupURL   string     `json:"backup_url,omitempty"`
+	Title       string     `json:"title"`
+	CreatedAt   *time.Time `json:"created_at"`
+	ExpireAt    *time.Time `json:"expire_at"`
+}
+
+func NewLink(url db.Url) Link {
+	link := Link{
+		ID:          url.ID,
+		Shortcode:   url.ShortCode,
+		ShortURL:    configs.GetAPIURL() + "/s/" + url.ShortCode,
+		OriginalURL: url.OriginalUrl,
+		BackupURL:   url.BackupUrl.String,
+		Title:       url.Title.String,
+	}
+	if url.CreatedAt.Valid {
+		link.CreatedAt = &url.CreatedAt.Time
+	}
+	if url.ExpireAt.Valid {
+		link.ExpireAt = &url.ExpireAt.Time
+	}
+	return link
+}
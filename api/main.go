@@ -1,14 +1,24 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 
 	"api/configs"
 	"api/internal/db"
+	"api/internal/export"
+	"api/internal/health"
+	"api/internal/mail"
+	"api/internal/metadata"
+	"api/internal/rollup"
 	"api/internal/routes"
+	"api/internal/safety"
+	"api/internal/storage"
+	"api/internal/thumbnail"
 	"api/internal/utils"
+	"api/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -56,7 +66,33 @@ func main() {
 		log.Fatalf("Failed to register validator: %v", err)
 	}
 
-	r := routes.SetupRouter(store, conn)
+	go export.NewWorker(store, configs.GetExportDir()).Run(context.Background())
+	go rollup.NewWorker(store).Run(context.Background())
+
+	webhooks := webhook.NewDispatcher(store)
+	go webhooks.Run(context.Background())
+	go health.NewWorker(store, webhooks).Run(context.Background())
+
+	scanner, err := safety.LoadScanner()
+	if err != nil {
+		log.Fatalf("Failed to load URL safety checks: %v", err)
+	}
+	go safety.NewWorker(store, scanner, webhooks).Run(context.Background())
+
+	files, err := storage.Load()
+	if err != nil {
+		log.Fatalf("Failed to set up storage: %v", err)
+	}
+	fetcher := metadata.NewFetcher()
+	thumbnails := thumbnail.NewService(files, fetcher)
+	go thumbnail.NewWorker(store, thumbnails).Run(context.Background())
+
+	mailer, err := mail.Load()
+	if err != nil {
+		log.Fatalf("Failed to set up email: %v", err)
+	}
+
+	r := routes.SetupRouter(store, conn, webhooks, scanner, files, fetcher, thumbnails, mailer)
 
 	startServer(r)
 }